@@ -2,24 +2,59 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/adimail/colosseum/internal/auth"
+	"github.com/adimail/colosseum/internal/backplane"
+	"github.com/adimail/colosseum/internal/history"
+	"github.com/adimail/colosseum/internal/rating"
+	"github.com/adimail/colosseum/internal/roomstore"
 	"github.com/adimail/colosseum/internal/server"
 	"github.com/adimail/colosseum/internal/sheets"
+	"github.com/adimail/colosseum/internal/stats"
+	"github.com/joho/godotenv"
 )
 
 func main() {
-	sheetsService, err := sheets.New()
-	if err != nil {
-		slog.Warn("Could not initialize Google Sheets service. Game history will be unavailable.", "error", err)
+	if err := godotenv.Load(); err != nil {
+		slog.Warn("no .env file found")
+	}
+
+	store := openHistory(history.ConfigFromEnv())
+	if store != nil {
+		defer store.Close()
 	}
 
-	srv := server.NewServer(":8080", "./dist", sheetsService)
+	rooms := openRooms()
+	if rooms != nil {
+		defer rooms.Close()
+	}
+
+	ratings := openRatings()
+	if ratings != nil {
+		defer ratings.Close()
+	}
+
+	results := openResults()
+	if results != nil {
+		defer results.Close()
+	}
+
+	srv := server.NewServer(":8080", "./dist", store, ratings, openAccounts(), results, rooms)
+	useInviteSecret(srv)
+	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
+		srv.Hub.AllowOrigins(strings.Split(origins, ","))
+	}
+	if bp := openBackplane(srv, backplane.ConfigFromEnv()); bp != nil {
+		defer bp.Close()
+	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -44,3 +79,141 @@ func main() {
 	}
 	slog.Info("Server stopped")
 }
+
+// openHistory connects the configured game-history backend behind a durable
+// outbox. History is optional, so a backend that fails to open only disables
+// it.
+func openHistory(cfg history.Config) history.Store {
+	var backend history.Store
+	if cfg.Backend == "sheets" {
+		sheetsService, err := sheets.New()
+		if err != nil {
+			slog.Warn("Could not initialize Google Sheets service. Game history will be unavailable.", "error", err)
+			return nil
+		}
+		backend = sheetsService
+	} else {
+		store, err := history.Open(cfg)
+		if err != nil {
+			slog.Warn("Could not open game history. Game history will be unavailable.", "backend", cfg.Backend, "error", err)
+			return nil
+		}
+		if store == nil {
+			return nil
+		}
+		backend = store
+	}
+
+	outbox, err := history.NewOutbox(cfg.Outbox, backend)
+	if err != nil {
+		slog.Warn("Could not open history outbox. Game history will be unavailable.", "dir", cfg.Outbox, "error", err)
+		backend.Close()
+		return nil
+	}
+	slog.Info("Game history enabled", "backend", cfg.Backend, "outbox", cfg.Outbox)
+	return outbox
+}
+
+// openRooms opens the directory in ROOMS_DIR where open rooms are saved so
+// that games in progress survive a restart. Without it rooms only live as
+// long as the process.
+func openRooms() *roomstore.Store {
+	dir := os.Getenv("ROOMS_DIR")
+	if dir == "" {
+		dir = "rooms"
+	}
+	rooms, err := roomstore.Open(dir)
+	if err != nil {
+		slog.Warn("Could not open the room store. Games will not survive a restart.", "dir", dir, "error", err)
+		return nil
+	}
+	return rooms
+}
+
+// openBackplane connects the server to the others it shares rooms with.
+// Without a backplane the server runs on its own; one that is configured
+// but cannot be reached stops it from starting, since its rooms would be
+// invisible to the rest of the cluster.
+func openBackplane(srv *server.Server, cfg backplane.Config) backplane.Backplane {
+	bp, err := backplane.Open(cfg)
+	if err != nil {
+		slog.Error("Could not open the backplane", "backend", cfg.Backend, "error", err)
+		os.Exit(1)
+	}
+	if bp == nil {
+		return nil
+	}
+	if err := srv.Hub.UseBackplane(bp, cfg.Node); err != nil {
+		slog.Error("Could not join the backplane", "backend", cfg.Backend, "error", err)
+		os.Exit(1)
+	}
+	return bp
+}
+
+// useInviteSecret signs room invites with a key derived from INVITE_SECRET,
+// or SESSION_SECRET if that is not set, so that invites outlive a restart
+// and work on every replica. Without either invites stop working whenever
+// the server restarts.
+func useInviteSecret(srv *server.Server) {
+	secret := os.Getenv("INVITE_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SESSION_SECRET")
+	}
+	if secret == "" {
+		slog.Warn("Neither INVITE_SECRET nor SESSION_SECRET is set. Invites will not survive a restart.")
+		return
+	}
+	srv.Hub.UseInviteSecret([]byte(secret))
+}
+
+// openRatings loads player ratings from RATINGS_PATH. Without them games
+// are simply left unrated.
+func openRatings() *rating.Store {
+	path := os.Getenv("RATINGS_PATH")
+	if path == "" {
+		path = "ratings.json"
+	}
+	ratings, err := rating.Open(path)
+	if err != nil {
+		slog.Warn("Could not load ratings. Games will not be rated.", "path", path, "error", err)
+		return nil
+	}
+	return ratings
+}
+
+// openAccounts loads registered players from ACCOUNTS_PATH. Session cookies
+// are signed with SESSION_SECRET; without one a random key is used and
+// everyone is logged out whenever the server restarts.
+func openAccounts() *auth.Accounts {
+	path := os.Getenv("ACCOUNTS_PATH")
+	if path == "" {
+		path = "accounts.json"
+	}
+	secret := []byte(os.Getenv("SESSION_SECRET"))
+	if len(secret) == 0 {
+		slog.Warn("SESSION_SECRET is not set. Logins will not survive a restart.")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+	accounts, err := auth.Open(path, secret)
+	if err != nil {
+		slog.Warn("Could not load accounts. Everyone will play as a guest.", "path", path, "error", err)
+		return nil
+	}
+	return accounts
+}
+
+// openResults loads the finished-game results that the leaderboard and
+// player statistics are computed from.
+func openResults() *stats.Store {
+	path := os.Getenv("RESULTS_PATH")
+	if path == "" {
+		path = "results.jsonl"
+	}
+	results, err := stats.Open(path)
+	if err != nil {
+		slog.Warn("Could not load game results. Statistics will be unavailable.", "path", path, "error", err)
+		return nil
+	}
+	return results
+}
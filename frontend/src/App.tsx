@@ -3,11 +3,16 @@ import { Routes, Route, useNavigate } from "react-router-dom";
 import { useGameStore } from "./stores/useGameStore";
 import HomePage from "./pages/HomePage";
 import CreateRoomPage from "./pages/CreateRoomPage";
+import RankedQueuePage from "./pages/RankedQueuePage";
 import GameRoomPage from "./pages/GameRoomPage";
 import SpectatePage from "./pages/SpectatePage";
 import GamesPage from "./pages/GamesPage";
+import LeaderboardPage from "./pages/LeaderboardPage";
+import TournamentsPage from "./pages/TournamentsPage";
+import TournamentPage from "./pages/TournamentPage";
 import NotFoundPage from "./pages/NotFoundPage";
 import HelpPage from "./pages/HelpPage";
+import LoginPage from "./pages/LoginPage";
 import ReloadPrompt from "./components/ReloadPrompt";
 
 function Notification() {
@@ -27,8 +32,13 @@ function Notification() {
 export default function App() {
   const navigate = useNavigate();
   const connect = useGameStore((state) => state.connect);
+  const fetchUser = useGameStore((state) => state.fetchUser);
   const pokeSoundRef = useRef<HTMLAudioElement>(null);
 
+  useEffect(() => {
+    fetchUser();
+  }, [fetchUser]);
+
   useEffect(() => {
     connect(navigate);
 
@@ -52,8 +62,13 @@ export default function App() {
       <Routes>
         <Route path="/" element={<HomePage />} />
         <Route path="/create" element={<CreateRoomPage />} />
+        <Route path="/ranked" element={<RankedQueuePage />} />
         <Route path="/games" element={<GamesPage />} />
+        <Route path="/leaderboard" element={<LeaderboardPage />} />
+        <Route path="/tournaments" element={<TournamentsPage />} />
+        <Route path="/tournaments/:tournamentId" element={<TournamentPage />} />
         <Route path="/help" element={<HelpPage />} />
+        <Route path="/login" element={<LoginPage />} />
         <Route path="/room/:gameId" element={<GameRoomPage />} />
         <Route path="/spectate/:gameId" element={<SpectatePage />} />
         <Route path="*" element={<NotFoundPage />} />
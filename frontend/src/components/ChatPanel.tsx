@@ -0,0 +1,195 @@
+import { useEffect, useRef, useState } from "react";
+import {
+  Ban,
+  MessageSquare,
+  UserX,
+  VolumeX,
+  Volume2,
+  X,
+} from "lucide-react";
+import { useGameStore, ChatMessage } from "../stores/useGameStore";
+
+const MAX_LENGTH = 280;
+
+interface ChatPanelProps {
+  // myName is who the viewer chats as, so the owner cannot mute themself.
+  myName?: string;
+  isOwner?: boolean;
+  className?: string;
+}
+
+export default function ChatPanel({
+  myName,
+  isOwner = false,
+  className = "bottom-4",
+}: ChatPanelProps) {
+  const chat = useGameStore((state) => state.chat);
+  const role = useGameStore((state) => state.role);
+  const sendChat = useGameStore((state) => state.sendChat);
+  const setMuted = useGameStore((state) => state.setMuted);
+  const kick = useGameStore((state) => state.kick);
+  const [open, setOpen] = useState(false);
+  const [channel, setChannel] = useState<ChatMessage["channel"]>(
+    role === "spectator" ? "spectators" : "players",
+  );
+  const [text, setText] = useState("");
+  const [muted, setMutedNames] = useState<Set<string>>(new Set());
+  const [seen, setSeen] = useState(0);
+  const listRef = useRef<HTMLDivElement>(null);
+
+  const visible = chat.filter((m) => m.channel === channel);
+  const unread = open ? 0 : chat.length - seen;
+  // Players only ever see their own channel; spectators can read the
+  // players' too but only post in theirs.
+  const canPost = channel === (role === "spectator" ? "spectators" : "players");
+
+  useEffect(() => {
+    if (open) {
+      setSeen(chat.length);
+      listRef.current?.scrollTo({ top: listRef.current.scrollHeight });
+    }
+  }, [open, chat.length, channel]);
+
+  const handleSubmit = (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!text.trim()) return;
+    sendChat(text.trim(), channel);
+    setText("");
+  };
+
+  const toggleMute = (name: string) => {
+    const next = new Set(muted);
+    if (next.has(name)) {
+      next.delete(name);
+    } else {
+      next.add(name);
+    }
+    setMuted(name, next.has(name));
+    setMutedNames(next);
+  };
+
+  if (!open) {
+    return (
+      <button
+        onClick={() => setOpen(true)}
+        className={`fixed right-4 z-40 p-3 rounded-full border-2 border-stone-700 bg-black/80 text-stone-400 hover:text-amber-400 hover:border-amber-600 transition-colors ${className}`}
+        title="Chat"
+      >
+        <MessageSquare size={22} />
+        {unread > 0 && (
+          <span className="absolute -top-1 -right-1 bg-crimson text-white text-xs font-bold rounded-full h-5 min-w-5 px-1 flex items-center justify-center">
+            {unread}
+          </span>
+        )}
+      </button>
+    );
+  }
+
+  return (
+    <div
+      className={`fixed right-4 z-40 w-80 max-w-[calc(100vw-2rem)] h-96 flex flex-col bg-stone-950/95 border border-stone-700 shadow-2xl ${className}`}
+    >
+      <div className="flex items-center justify-between border-b border-stone-800 px-3 py-2">
+        <div className="flex gap-3 font-cinzel text-xs uppercase tracking-widest">
+          <button
+            onClick={() => setChannel("players")}
+            className={
+              channel === "players" ? "text-amber-400" : "text-stone-500"
+            }
+          >
+            Arena
+          </button>
+          {role === "spectator" && (
+            <button
+              onClick={() => setChannel("spectators")}
+              className={
+                channel === "spectators" ? "text-amber-400" : "text-stone-500"
+              }
+            >
+              Stands
+            </button>
+          )}
+        </div>
+        <button
+          onClick={() => setOpen(false)}
+          className="text-stone-500 hover:text-stone-300"
+        >
+          <X size={18} />
+        </button>
+      </div>
+
+      <div
+        ref={listRef}
+        className="flex-grow overflow-y-auto custom-scrollbar p-3 space-y-2 text-sm"
+      >
+        {visible.length === 0 ? (
+          <p className="text-stone-600 italic text-center font-cinzel">
+            The crowd is silent.
+          </p>
+        ) : (
+          visible.map((m, i) => (
+            <div key={`${m.timestamp}-${i}`} className="group break-words">
+              <span className="font-bold text-amber-500">{m.from}</span>
+              {isOwner && m.from !== myName && (
+                <span className="ml-1 inline-flex gap-1 align-middle opacity-0 group-hover:opacity-100">
+                  <button
+                    onClick={() => toggleMute(m.from)}
+                    className="text-stone-600 hover:text-crimson"
+                    title={muted.has(m.from) ? "Unmute" : "Mute"}
+                  >
+                    {muted.has(m.from) ? (
+                      <Volume2 size={12} />
+                    ) : (
+                      <VolumeX size={12} />
+                    )}
+                  </button>
+                  <button
+                    onClick={() => kick(m.from)}
+                    className="text-stone-600 hover:text-crimson"
+                    title="Kick"
+                  >
+                    <UserX size={12} />
+                  </button>
+                  <button
+                    onClick={() => {
+                      if (confirm(`Ban ${m.from} from this room?`)) {
+                        kick(m.from, true);
+                      }
+                    }}
+                    className="text-stone-600 hover:text-crimson"
+                    title="Ban"
+                  >
+                    <Ban size={12} />
+                  </button>
+                </span>
+              )}
+              <span className="text-stone-300">: {m.text}</span>
+            </div>
+          ))
+        )}
+      </div>
+
+      {canPost && (
+        <form
+          onSubmit={handleSubmit}
+          className="border-t border-stone-800 p-2 flex gap-2"
+        >
+          <input
+            value={text}
+            onChange={(e) => setText(e.target.value)}
+            maxLength={MAX_LENGTH}
+            placeholder="Say something..."
+            className="flex-1 bg-stone-900 border border-stone-700 focus:border-amber-600 outline-none px-2 py-1 text-stone-200"
+          />
+          <button
+            type="submit"
+            disabled={!text.trim()}
+            className="font-cinzel text-xs uppercase tracking-widest text-amber-500 disabled:opacity-40"
+          >
+            Send
+          </button>
+        </form>
+      )}
+    </div>
+  );
+}
@@ -1,5 +1,6 @@
 import { useState } from "react";
 import { useNavigate } from "react-router-dom";
+import { useGameStore } from "../../stores/useGameStore";
 import StoneInput from "../ui/StoneInput";
 import LegendaryButton from "../ui/LegendaryButton";
 
@@ -14,7 +15,10 @@ export default function PlayerNameForm({
   buttonText,
   variant = "gold",
 }: PlayerNameFormProps) {
-  const [name, setName] = useState("");
+  const user = useGameStore((state) => state.user);
+  const [guestName, setName] = useState("");
+  // Logged-in players always play under their username.
+  const name = user?.username ?? guestName;
   const navigate = useNavigate();
 
   const handleSubmit = (e: React.FormEvent) => {
@@ -34,6 +38,7 @@ export default function PlayerNameForm({
         className="text-xl"
         autoFocus
         required
+        readOnly={!!user}
       />
       <LegendaryButton
         type="submit"
@@ -1,12 +1,142 @@
+import { useState } from "react";
 import { useNavigate } from "react-router-dom";
-import { useGameStore } from "../stores/useGameStore";
+import {
+  BotOptions,
+  Clock,
+  Rules,
+  useGameStore,
+  Visibility,
+} from "../stores/useGameStore";
 import LegendaryCard from "../components/ui/LegendaryCard";
+import StoneInput from "../components/ui/StoneInput";
 import PlayerNameForm from "../components/forms/PlayerNameForm";
 
+const visibilities: { value: Visibility; label: string; hint: string }[] = [
+  { value: "public", label: "Public", hint: "Listed in the lobby." },
+  {
+    value: "unlisted",
+    label: "Unlisted",
+    hint: "Hidden from the lobby. Anyone with the code can enter.",
+  },
+  {
+    value: "private",
+    label: "Private",
+    hint: "Hidden from the lobby. Entry needs the password or an invite.",
+  },
+];
+
+type Mode = "duel" | "ffa" | "teams" | "bot";
+
+const modes: { value: Mode; label: string; hint: string }[] = [
+  { value: "duel", label: "Duel", hint: "One against one." },
+  {
+    value: "ffa",
+    label: "Melee",
+    hint: "Every player for themselves. The last code standing wins.",
+  },
+  {
+    value: "teams",
+    label: "Teams",
+    hint: "Two teams share a code and take turns guessing for it.",
+  },
+  { value: "bot", label: "Bot", hint: "Face a machine opponent." },
+];
+
+const range = (from: number, to: number) =>
+  Array.from({ length: to - from + 1 }, (_, i) => from + i);
+
+// Choice is a row of buttons picking one of a few values.
+function Choice<T extends string | number | boolean>({
+  label,
+  value,
+  options,
+  onChange,
+  hint,
+}: {
+  label: string;
+  value: T;
+  options: { value: T; label: string }[];
+  onChange: (value: T) => void;
+  hint?: string;
+}) {
+  return (
+    <div className="mb-5 w-full">
+      <label className="block text-stone-400 font-cinzel text-sm tracking-widest uppercase ml-1 mb-2">
+        {label}
+      </label>
+      <div className="flex gap-2">
+        {options.map((o) => (
+          <button
+            key={String(o.value)}
+            type="button"
+            onClick={() => onChange(o.value)}
+            className={`flex-1 px-3 py-2 font-cinzel text-xs uppercase tracking-widest border transition-colors ${
+              value === o.value
+                ? "border-amber-500 text-amber-400"
+                : "border-stone-700 text-stone-500 hover:text-amber-400"
+            }`}
+          >
+            {o.label}
+          </button>
+        ))}
+      </div>
+      {hint && <p className="text-stone-500 text-sm mt-2 ml-1">{hint}</p>}
+    </div>
+  );
+}
+
 export default function CreateRoomPage() {
   const createRoom = useGameStore((state) => state.createRoom);
   const gameState = useGameStore((state) => state.gameState);
+  const error = useGameStore((state) => state.error);
   const navigate = useNavigate();
+  const [visibility, setVisibility] = useState<Visibility>("public");
+  const [password, setPassword] = useState("");
+  const [mode, setMode] = useState<Mode>("duel");
+  const [players, setPlayers] = useState(3);
+  const [teamSize, setTeamSize] = useState(2);
+  const [difficulty, setDifficulty] =
+    useState<BotOptions["difficulty"]>("minimax");
+  const [length, setLength] = useState(4);
+  const [alphabet, setAlphabet] = useState<Rules["alphabet"]>("digits");
+  const [allowDuplicates, setAllowDuplicates] = useState(false);
+  const [hardMode, setHardMode] = useState(false);
+  const [winCondition, setWinCondition] =
+    useState<Rules["winCondition"]>("first_crack");
+  const [turnMode, setTurnMode] = useState<Rules["turnMode"]>("alternate");
+  const [bestOf, setBestOf] = useState(1);
+  const [clockMode, setClockMode] = useState<Clock["mode"]>("");
+  const [moveSeconds, setMoveSeconds] = useState(30);
+  const [onTimeout, setOnTimeout] =
+    useState<NonNullable<Clock["onTimeout"]>>("forfeit_turn");
+  const [bankSeconds, setBankSeconds] = useState(300);
+  const [incrementSeconds, setIncrementSeconds] = useState(5);
+
+  const freeForAll = mode === "ffa";
+  // A melee is always alternate turns, first crack and a single game, and
+  // simultaneous turns always end on the first crack.
+  const simultaneous = !freeForAll && turnMode === "simultaneous";
+
+  const buildRules = (): Partial<Rules> => {
+    const clock: Clock =
+      clockMode === "per_move"
+        ? { mode: clockMode, moveSeconds, onTimeout }
+        : clockMode === "fischer"
+          ? { mode: clockMode, bankSeconds, incrementSeconds }
+          : { mode: "" };
+    return {
+      length,
+      alphabet,
+      allowDuplicates,
+      hardMode,
+      winCondition: freeForAll || simultaneous ? "first_crack" : winCondition,
+      turnMode: freeForAll ? "alternate" : turnMode,
+      bestOf: freeForAll ? 1 : bestOf,
+      players: freeForAll ? players : 2,
+      teamSize: mode === "teams" ? teamSize : 1,
+      clock,
+    };
+  };
 
   if (gameState?.roomCode) {
     navigate(`/room/${gameState.roomCode}`);
@@ -23,8 +153,207 @@ export default function CreateRoomPage() {
     >
       <div className="w-full max-w-lg">
         <LegendaryCard title="Create Room">
+          {error && <p className="text-crimson mb-4">{error}</p>}
+          <div className="mb-5 w-full">
+            <label className="block text-stone-400 font-cinzel text-sm tracking-widest uppercase ml-1 mb-2">
+              Gates
+            </label>
+            <div className="flex gap-2">
+              {visibilities.map((v) => (
+                <button
+                  key={v.value}
+                  type="button"
+                  onClick={() => setVisibility(v.value)}
+                  className={`flex-1 px-3 py-2 font-cinzel text-xs uppercase tracking-widest border transition-colors ${
+                    visibility === v.value
+                      ? "border-amber-500 text-amber-400"
+                      : "border-stone-700 text-stone-500 hover:text-amber-400"
+                  }`}
+                >
+                  {v.label}
+                </button>
+              ))}
+            </div>
+            <p className="text-stone-500 text-sm mt-2 ml-1">
+              {visibilities.find((v) => v.value === visibility)?.hint}
+            </p>
+          </div>
+          <Choice
+            label="Mode"
+            value={mode}
+            options={modes}
+            onChange={setMode}
+            hint={modes.find((m) => m.value === mode)?.hint}
+          />
+          {mode === "ffa" && (
+            <Choice
+              label="Players"
+              value={players}
+              options={range(3, 8).map((n) => ({ value: n, label: `${n}` }))}
+              onChange={setPlayers}
+            />
+          )}
+          {mode === "teams" && (
+            <Choice
+              label="Team Size"
+              value={teamSize}
+              options={range(2, 4).map((n) => ({ value: n, label: `${n}` }))}
+              onChange={setTeamSize}
+            />
+          )}
+          {mode === "bot" && (
+            <Choice
+              label="Opponent"
+              value={difficulty}
+              options={[
+                { value: "weak", label: "Novice" },
+                { value: "random", label: "Gambler" },
+                { value: "minimax", label: "Master" },
+              ]}
+              onChange={setDifficulty}
+            />
+          )}
+          <Choice
+            label="Code Length"
+            value={length}
+            options={range(3, 8).map((n) => ({ value: n, label: `${n}` }))}
+            onChange={setLength}
+          />
+          <Choice
+            label="Symbols"
+            value={alphabet}
+            options={[
+              { value: "digits", label: "Digits" },
+              { value: "hex", label: "Hex" },
+              { value: "letters", label: "Letters" },
+            ]}
+            onChange={setAlphabet}
+          />
+          <div className="flex gap-4">
+            <Choice
+              label="Repeats"
+              value={allowDuplicates}
+              options={[
+                { value: false, label: "No" },
+                { value: true, label: "Yes" },
+              ]}
+              onChange={setAllowDuplicates}
+            />
+            <Choice
+              label="Hard Mode"
+              value={hardMode}
+              options={[
+                { value: false, label: "Off" },
+                { value: true, label: "On" },
+              ]}
+              onChange={setHardMode}
+            />
+          </div>
+          {!freeForAll && (
+            <>
+              <Choice
+                label="Turns"
+                value={turnMode}
+                options={[
+                  { value: "alternate", label: "Alternate" },
+                  { value: "simultaneous", label: "Together" },
+                ]}
+                onChange={setTurnMode}
+              />
+              {!simultaneous && (
+                <Choice
+                  label="Victory"
+                  value={winCondition}
+                  options={[
+                    { value: "first_crack", label: "First Crack" },
+                    { value: "equal_turns", label: "Equal Turns" },
+                  ]}
+                  onChange={setWinCondition}
+                />
+              )}
+              <Choice
+                label="Best Of"
+                value={bestOf}
+                options={[1, 3, 5, 7].map((n) => ({ value: n, label: `${n}` }))}
+                onChange={setBestOf}
+              />
+            </>
+          )}
+          <Choice
+            label="Clock"
+            value={clockMode}
+            options={[
+              { value: "", label: "None" },
+              { value: "per_move", label: "Per Move" },
+              { value: "fischer", label: "Fischer" },
+            ]}
+            onChange={setClockMode}
+          />
+          {clockMode === "per_move" && (
+            <>
+              <Choice
+                label="Seconds Per Move"
+                value={moveSeconds}
+                options={[15, 30, 60, 120].map((n) => ({
+                  value: n,
+                  label: `${n}`,
+                }))}
+                onChange={setMoveSeconds}
+              />
+              <Choice
+                label="On Timeout"
+                value={onTimeout}
+                options={[
+                  { value: "forfeit_turn", label: "Lose Turn" },
+                  { value: "forfeit_game", label: "Lose Game" },
+                ]}
+                onChange={setOnTimeout}
+              />
+            </>
+          )}
+          {clockMode === "fischer" && (
+            <>
+              <Choice
+                label="Minutes Each"
+                value={bankSeconds}
+                options={[60, 180, 300, 600].map((n) => ({
+                  value: n,
+                  label: `${n / 60}`,
+                }))}
+                onChange={setBankSeconds}
+              />
+              <Choice
+                label="Seconds Added Per Move"
+                value={incrementSeconds}
+                options={[0, 2, 5, 10].map((n) => ({
+                  value: n,
+                  label: `${n}`,
+                }))}
+                onChange={setIncrementSeconds}
+              />
+            </>
+          )}
+          {visibility === "private" && (
+            <StoneInput
+              label="Password"
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              maxLength={72}
+              autoComplete="new-password"
+              containerClassName="mb-5"
+            />
+          )}
           <PlayerNameForm
-            onSubmit={createRoom}
+            onSubmit={(name) =>
+              createRoom(
+                name,
+                buildRules(),
+                mode === "bot" ? { difficulty } : undefined,
+                visibility,
+                visibility === "private" ? password : undefined,
+              )
+            }
             buttonText="Establish Arena"
             variant="crimson"
           />
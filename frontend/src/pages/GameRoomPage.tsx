@@ -1,14 +1,32 @@
 import { useEffect, useState, useRef } from "react";
-import { useParams, useNavigate } from "react-router-dom";
-import { useGameStore } from "../stores/useGameStore";
-import { BellRing, Copy, LogOut, Shield, Sword, Loader } from "lucide-react";
+import { useParams, useNavigate, useSearchParams } from "react-router-dom";
+import { Guess, PlayerState, useGameStore } from "../stores/useGameStore";
+import {
+  BellRing,
+  Copy,
+  Crosshair,
+  Crown,
+  Eye,
+  EyeOff,
+  Link2,
+  LogOut,
+  Shield,
+  Skull,
+  Sword,
+  Loader,
+  WifiOff,
+} from "lucide-react";
 import LegendaryCard from "../components/ui/LegendaryCard";
 import LegendaryButton from "../components/ui/LegendaryButton";
 import PlayerNameForm from "../components/forms/PlayerNameForm";
+import StoneInput from "../components/ui/StoneInput";
+import ChatPanel from "../components/ChatPanel";
 
 export default function GameRoomPage() {
   const { gameId } = useParams();
   const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const inviteToken = searchParams.get("invite") ?? undefined;
   const {
     gameState,
     joinRoom,
@@ -16,10 +34,16 @@ export default function GameRoomPage() {
     setSecret,
     submitGuess,
     playerId,
+    member,
     restartGame,
     error: globalError,
     clearError,
     pokeOpponent,
+    invite,
+    createInvite,
+    spectatorsLocked,
+    lockSpectators,
+    transferOwnership,
   } = useGameStore();
   const [input, setInput] = useState("");
   const [inputError, setInputError] = useState<string | null>(null);
@@ -31,7 +55,12 @@ export default function GameRoomPage() {
   const [roomInfo, setRoomInfo] = useState<{
     exists: boolean;
     ownerName: string;
+    visibility?: string;
   }>({ exists: false, ownerName: "" });
+  const [password, setPassword] = useState("");
+  const [inviteCopied, setInviteCopied] = useState(false);
+  // targetId is the opponent picked to strike at in a free-for-all.
+  const [targetId, setTargetId] = useState<string | null>(null);
 
   useEffect(() => {
     if (globalError) {
@@ -62,6 +91,7 @@ export default function GameRoomPage() {
           setRoomInfo({
             exists: true,
             ownerName: data.ownerName,
+            visibility: data.visibility,
           });
           setFetchStatus("success");
         } else {
@@ -77,7 +107,17 @@ export default function GameRoomPage() {
     if (scrollRef.current) {
       scrollRef.current.scrollTop = scrollRef.current.scrollHeight;
     }
-  }, [gameState?.p1.guesses, gameState?.p2.guesses]);
+  }, [gameState?.players, targetId]);
+
+  useEffect(() => {
+    if (!invite || invite.roomCode !== gameState?.roomCode) return;
+    navigator.clipboard.writeText(
+      `${window.location.origin}/room/${invite.roomCode}?invite=${invite.token}`,
+    );
+    setInviteCopied(true);
+    const timer = setTimeout(() => setInviteCopied(false), 3000);
+    return () => clearTimeout(timer);
+  }, [invite, gameState?.roomCode]);
 
   const handlePoke = () => {
     if (!canPoke) return;
@@ -174,8 +214,22 @@ export default function GameRoomPage() {
       >
         <div className="w-full max-w-md">
           <LegendaryCard title={`Join ${roomInfo.ownerName}'s Room`}>
+            {inputError && <p className="text-crimson mb-4">{inputError}</p>}
+            {roomInfo.visibility === "private" && !inviteToken && (
+              <StoneInput
+                label="Password"
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                autoComplete="off"
+                containerClassName="mb-5"
+              />
+            )}
             <PlayerNameForm
-              onSubmit={(name) => gameId && joinRoom(name, gameId)}
+              onSubmit={(name) =>
+                gameId &&
+                joinRoom(name, gameId, { password, invite: inviteToken })
+              }
               buttonText="Enter Arena"
             />
           </LegendaryCard>
@@ -184,22 +238,61 @@ export default function GameRoomPage() {
     );
   }
 
-  const isPlayer1 = playerId === "p1";
-  const myState = isPlayer1 ? gameState.p1 : gameState.p2;
-  const oppState = isPlayer1 ? gameState.p2 : gameState.p1;
-  const isMyTurn = gameState.turn === playerId;
+  const isOwner = playerId === gameState.ownerId;
+  const myState =
+    gameState.players.find((p) => p.id === playerId) ?? gameState.players[0];
+  const opponents = gameState.players.filter((p) => p.id !== myState.id);
+  const freeForAll = gameState.players.length > 2;
+  const teamGame = gameState.rules.teamSize > 1;
+  const standing = opponents.filter((p) => !p.eliminated);
+  const target = freeForAll
+    ? (standing.find((p) => p.id === targetId) ?? standing[0])
+    : opponents[0];
+  const nameOf = (id?: string) =>
+    gameState.players.find((p) => p.id === id)?.name || "Opponent";
+
+  const simultaneous = gameState.rules.turnMode === "simultaneous";
+  // sideToMove is whether this seat may guess; in team games only the
+  // member whose turn it is does the guessing for it.
+  const sideToMove =
+    !myState.eliminated &&
+    (simultaneous ? !myState.pending : gameState.turn === myState.id);
+  const guesser = myState.members?.[myState.guesser ?? 0];
+  const isMyTurn =
+    sideToMove && (!teamGame || (myState.guesser ?? 0) === member);
+  const myName = teamGame
+    ? (myState.members?.[member ?? 0] ?? myState.name)
+    : myState.name;
+
+  // holdingUp is whether the game is waiting on opponent p.
+  const holdingUp = (p: PlayerState) =>
+    !!p.name &&
+    ((gameState.status === "active" &&
+      !p.eliminated &&
+      (simultaneous ? !p.pending : gameState.turn === p.id)) ||
+      (gameState.status === "setup" && myState.isReady && !p.isReady));
 
-  const showPokeButton =
-    oppState.name &&
-    ((gameState.status === "active" && !isMyTurn) ||
-      (gameState.status === "setup" && myState.isReady && !oppState.isReady));
+  const {
+    length: codeLength,
+    symbols,
+    alphabet,
+    allowDuplicates,
+  } = gameState.rules;
+
+  const normalizeInput = (value: string): string => {
+    const upper = alphabet === "custom" ? value : value.toUpperCase();
+    return Array.from(upper)
+      .filter((c) => symbols.includes(c))
+      .join("");
+  };
 
   const validateInput = (value: string): string | null => {
-    if (value.length !== 4) {
-      return "Must be 4 digits.";
+    const chars = Array.from(value);
+    if (chars.length !== codeLength) {
+      return `Must be ${codeLength} symbols.`;
     }
-    if (new Set(value).size !== 4) {
-      return "Digits must be unique.";
+    if (!allowDuplicates && new Set(chars).size !== codeLength) {
+      return "Symbols must be unique.";
     }
     return null;
   };
@@ -218,7 +311,7 @@ export default function GameRoomPage() {
     if (!myState.secret) {
       setSecret(input);
     } else if (gameState.status === "active") {
-      submitGuess(input);
+      submitGuess(input, freeForAll ? target?.id : undefined);
     }
     setInput("");
   };
@@ -232,39 +325,59 @@ export default function GameRoomPage() {
 
   const getStatusMessage = () => {
     if (gameState.status === "completed") return "Battle Concluded";
+    if (myState.eliminated) {
+      return "Your code has been cracked. Watch the others fight on.";
+    }
     if (!myState.secret) {
-      return "Carve your secret code into the stone.";
+      return teamGame
+        ? "Carve your team's secret code into the stone."
+        : "Carve your secret code into the stone.";
     }
-    if (gameState.status === "setup") {
-      return `Waiting for ${oppState.name || "opponent"} to prepare...`;
+    if (gameState.status === "setup" || gameState.status === "waiting") {
+      const unready = opponents.filter((p) => !p.isReady && p.name);
+      return `Waiting for ${unready.map((p) => p.name).join(", ") || "opponents"} to prepare...`;
     }
     if (gameState.status === "active") {
+      if (gameState.pausedAt) {
+        const away = gameState.players.find((p) => p.disconnected);
+        return `Paused while ${away?.name || "a player"} reconnects...`;
+      }
       if (isMyTurn) {
-        return "It is your turn to strike!";
+        return freeForAll
+          ? `Strike at ${target?.name}, or pick another foe.`
+          : "It is your turn to strike!";
       }
-      return `${oppState.name || "Opponent"} is strategizing...`;
+      if (sideToMove) {
+        return `It is ${guesser}'s turn to guess for your team.`;
+      }
+      if (simultaneous) {
+        return "Your strike is locked in. Awaiting the others...";
+      }
+      return `${nameOf(gameState.turn)} is strategizing...`;
     }
     return "";
   };
 
   const renderRematchControls = () => {
-    if (!myState.isReady && !oppState.isReady) {
+    const ready = opponents.filter((p) => p.isReady);
+    if (!myState.isReady && ready.length === 0) {
       return (
         <LegendaryButton onClick={restartGame}>Challenge Again</LegendaryButton>
       );
     }
-    if (myState.isReady && !oppState.isReady) {
+    if (myState.isReady && ready.length < opponents.length) {
       return (
         <LegendaryButton disabled className="opacity-70">
-          Awaiting Opponent...
+          Awaiting {opponents.length > 1 ? "Opponents" : "Opponent"}...
         </LegendaryButton>
       );
     }
-    if (!myState.isReady && oppState.isReady) {
+    if (!myState.isReady) {
       return (
         <div className="flex flex-col gap-4 w-full">
           <p className="text-stone-400 italic text-center">
-            {oppState.name} demands a rematch!
+            {ready.map((p) => p.name).join(", ")}{" "}
+            {ready.length > 1 ? "demand" : "demands"} a rematch!
           </p>
           <LegendaryButton onClick={restartGame}>
             Accept Challenge
@@ -279,6 +392,17 @@ export default function GameRoomPage() {
     );
   };
 
+  const verdict =
+    gameState.winner === myState.id
+      ? { title: "VICTORY", line: "The gods smile upon you." }
+      : gameState.winner === "draw"
+        ? { title: "STALEMATE", line: "Neither side yields the field." }
+        : { title: "DEFEAT", line: "You have fallen in battle." };
+
+  const myGuesses = freeForAll
+    ? myState.guesses.filter((g) => g.target === target?.id)
+    : myState.guesses;
+
   return (
     <div
       className="min-h-screen bg-image-overlay text-parchment font-roman flex flex-col overflow-hidden"
@@ -303,6 +427,30 @@ export default function GameRoomPage() {
           </div>
         </div>
         <div className="flex items-center gap-2 md:gap-4">
+          {isOwner && (
+            <button
+              onClick={() => lockSpectators(!spectatorsLocked)}
+              className={`p-2 transition-colors ${
+                spectatorsLocked
+                  ? "text-crimson"
+                  : "text-stone-400 hover:text-amber-400"
+              }`}
+              title={spectatorsLocked ? "Open the Stands" : "Close the Stands"}
+            >
+              {spectatorsLocked ? <EyeOff size={20} /> : <Eye size={20} />}
+            </button>
+          )}
+          <button
+            onClick={() => createInvite()}
+            className={`p-2 transition-colors ${
+              inviteCopied
+                ? "text-amber-400"
+                : "text-stone-400 hover:text-amber-400"
+            }`}
+            title={inviteCopied ? "Invite link copied" : "Copy Invite Link"}
+          >
+            <Link2 size={20} />
+          </button>
           <button
             onClick={() => {
               navigator.clipboard.writeText(window.location.href);
@@ -327,12 +475,10 @@ export default function GameRoomPage() {
           <div className="absolute inset-0 z-50 flex items-center justify-center bg-black/80 backdrop-blur-sm p-4">
             <LegendaryCard className="text-center max-w-lg w-full">
               <h2 className="text-4xl md:text-5xl font-cinzel font-black text-gold-gradient mb-2 drop-shadow-lg">
-                {gameState.winner === playerId ? "VICTORY" : "DEFEAT"}
+                {verdict.title}
               </h2>
               <p className="text-xl text-stone-300 font-roman italic mb-8">
-                {gameState.winner === playerId
-                  ? "The gods smile upon you."
-                  : "You have fallen in battle."}
+                {verdict.line}
               </p>
 
               <div className="flex flex-col items-center gap-4">
@@ -381,6 +527,9 @@ export default function GameRoomPage() {
                   <div className="flex items-center justify-between mb-6 border-b border-stone-800 pb-4">
                     <h3 className="text-xl font-cinzel font-bold text-amber-500 flex items-center gap-2">
                       <Shield size={20} /> {myState.name}
+                      {myState.eliminated && (
+                        <Skull size={16} className="text-crimson" />
+                      )}
                     </h3>
                     {isMyTurn && gameState.status === "active" && (
                       <span className="px-3 py-1 bg-amber-900/30 border border-amber-700/50 text-amber-500 text-xs font-cinzel uppercase tracking-widest animate-pulse">
@@ -389,6 +538,10 @@ export default function GameRoomPage() {
                     )}
                   </div>
 
+                  {teamGame && myState.members && (
+                    <TeamRoster side={myState} active={sideToMove} />
+                  )}
+
                   <div className="mb-6">
                     <div className="text-center mb-2 text-stone-500 font-cinzel text-xs uppercase tracking-widest">
                       Secret Code
@@ -406,105 +559,147 @@ export default function GameRoomPage() {
 
                   <div className="flex-grow overflow-hidden flex flex-col">
                     <div className="text-stone-500 font-cinzel text-xs uppercase tracking-widest mb-2 flex justify-between px-2">
-                      <span>History</span>
+                      <span>
+                        {freeForAll && target
+                          ? `History vs ${target.name}`
+                          : "History"}
+                      </span>
                       <span>B / C</span>
                     </div>
                     <div
                       className="flex-grow overflow-y-auto custom-scrollbar space-y-1 bg-black/20 p-2 border border-stone-800/50"
                       ref={scrollRef}
                     >
-                      {myState.guesses.map((g, i) => (
-                        <div
-                          key={i}
-                          className="flex justify-between items-center p-3 bg-stone-800/30 border-b border-stone-800 last:border-0"
-                        >
-                          <span className="font-nums text-xl tracking-widest text-stone-300">
-                            {g.code}
-                          </span>
-                          <div className="flex gap-3 font-cinzel font-bold">
-                            <span className="text-amber-500 w-8 text-right">
-                              {g.bulls}B
-                            </span>
-                            <span className="text-stone-400 w-8 text-right">
-                              {g.cows}C
-                            </span>
-                          </div>
-                        </div>
-                      ))}
-                      {myState.guesses.length === 0 && (
-                        <div className="text-center text-stone-700 italic py-10">
-                          No attempts made yet.
-                        </div>
-                      )}
+                      <GuessList
+                        guesses={myGuesses}
+                        empty="No attempts made yet."
+                      />
                     </div>
                   </div>
                 </div>
               </div>
             </div>
 
-            <div className="flex flex-col h-full">
-              <div className="card-legendary flex-grow flex flex-col p-1 border-stone-700">
-                <div className="bg-stone-900/90 flex-grow p-4 md:p-6 flex flex-col border border-stone-800">
-                  <div className="flex items-center justify-between mb-6 border-b border-stone-800 pb-4">
-                    <h3 className="text-xl font-cinzel font-bold text-stone-400 flex items-center gap-2">
-                      <Sword size={20} /> {oppState.name || "Opponent"}
-                    </h3>
-                    {showPokeButton && (
-                      <button
-                        onClick={handlePoke}
-                        disabled={!canPoke}
-                        className="flex items-center gap-2 text-xs font-cinzel uppercase tracking-widest text-stone-500 hover:text-amber-500 transition-colors disabled:opacity-30 disabled:cursor-not-allowed"
+            <div className="flex flex-col h-full gap-4">
+              {opponents.map((opp) => (
+                <div
+                  key={opp.id}
+                  className={`card-legendary flex-grow flex flex-col p-1 ${
+                    freeForAll && target?.id === opp.id
+                      ? "border-amber-700"
+                      : "border-stone-700"
+                  }`}
+                >
+                  <div className="bg-stone-900/90 flex-grow p-4 md:p-6 flex flex-col border border-stone-800">
+                    <div className="flex items-center justify-between mb-6 border-b border-stone-800 pb-4">
+                      <h3
+                        className={`text-xl font-cinzel font-bold flex items-center gap-2 ${
+                          opp.eliminated
+                            ? "text-stone-600 line-through"
+                            : "text-stone-400"
+                        }`}
                       >
-                        <BellRing size={14} />
-                        {canPoke ? "Provoke" : "Cooldown"}
-                      </button>
+                        <Sword size={20} /> {opp.name || "Opponent"}
+                        {opp.eliminated && (
+                          <Skull size={16} className="text-crimson" />
+                        )}
+                        {opp.disconnected && (
+                          <WifiOff
+                            size={16}
+                            className="text-stone-500"
+                            aria-label="Reconnecting"
+                          />
+                        )}
+                        {isOwner && opp.name && (
+                          <button
+                            onClick={() => {
+                              if (
+                                confirm(`Hand the room over to ${opp.name}?`)
+                              ) {
+                                transferOwnership(opp.name);
+                              }
+                            }}
+                            className="text-stone-600 hover:text-amber-500 transition-colors"
+                            title="Transfer Ownership"
+                          >
+                            <Crown size={16} />
+                          </button>
+                        )}
+                      </h3>
+                      <div className="flex items-center gap-3">
+                        {freeForAll &&
+                          !opp.eliminated &&
+                          gameState.status === "active" && (
+                            <button
+                              onClick={() => setTargetId(opp.id)}
+                              className={`flex items-center gap-2 text-xs font-cinzel uppercase tracking-widest transition-colors ${
+                                target?.id === opp.id
+                                  ? "text-amber-500"
+                                  : "text-stone-500 hover:text-amber-500"
+                              }`}
+                              title="Strike at this foe"
+                            >
+                              <Crosshair size={14} />
+                              {target?.id === opp.id ? "Target" : "Aim"}
+                            </button>
+                          )}
+                        {holdingUp(opp) && (
+                          <button
+                            onClick={handlePoke}
+                            disabled={!canPoke}
+                            className="flex items-center gap-2 text-xs font-cinzel uppercase tracking-widest text-stone-500 hover:text-amber-500 transition-colors disabled:opacity-30 disabled:cursor-not-allowed"
+                          >
+                            <BellRing size={14} />
+                            {canPoke ? "Provoke" : "Cooldown"}
+                          </button>
+                        )}
+                      </div>
+                    </div>
+
+                    {teamGame && opp.members && (
+                      <TeamRoster
+                        side={opp}
+                        active={
+                          gameState.status === "active" &&
+                          gameState.turn === opp.id
+                        }
+                      />
                     )}
-                  </div>
 
-                  <div className="mb-6">
-                    <div className="text-center mb-2 text-stone-500 font-cinzel text-xs uppercase tracking-widest">
-                      Enemy Secret
-                    </div>
-                    <div className="text-xl md:text-3xl font-nums tracking-[0.5em] text-center md:py-5 py-3 bg-black/40 border-2 border-stone-800 text-stone-600 shadow-inner">
-                      {gameState.status === "completed"
-                        ? oppState.secret
-                        : "????"}
+                    <div className="mb-6">
+                      <div className="text-center mb-2 text-stone-500 font-cinzel text-xs uppercase tracking-widest">
+                        Enemy Secret
+                      </div>
+                      <div className="text-xl md:text-3xl font-nums tracking-[0.5em] text-center md:py-5 py-3 bg-black/40 border-2 border-stone-800 text-stone-600 shadow-inner">
+                        {gameState.status === "completed" && opp.secret
+                          ? opp.secret
+                          : "????"}
+                      </div>
                     </div>
-                  </div>
 
-                  <div className="flex-grow overflow-hidden flex flex-col">
-                    <div className="text-stone-500 font-cinzel text-xs uppercase tracking-widest mb-2 flex justify-between px-2">
-                      <span>Enemy Strikes</span>
-                      <span>B / C</span>
-                    </div>
-                    <div className="flex-grow overflow-y-auto custom-scrollbar space-y-1 bg-black/20 p-2 border border-stone-800/50">
-                      {oppState.guesses.map((g, i) => (
-                        <div
-                          key={i}
-                          className="flex justify-between items-center p-3 bg-stone-800/30 border-b border-stone-800 last:border-0"
-                        >
-                          <span className="font-nums text-xl tracking-widest text-stone-300">
-                            {g.code}
-                          </span>
-                          <div className="flex gap-3 font-cinzel font-bold">
-                            <span className="text-amber-500 w-8 text-right">
-                              {g.bulls}B
-                            </span>
-                            <span className="text-stone-400 w-8 text-right">
-                              {g.cows}C
-                            </span>
-                          </div>
-                        </div>
-                      ))}
-                      {oppState.guesses.length === 0 && (
-                        <div className="text-center text-stone-700 italic py-10">
-                          The enemy has not struck yet.
-                        </div>
-                      )}
+                    <div className="flex-grow overflow-hidden flex flex-col">
+                      <div className="text-stone-500 font-cinzel text-xs uppercase tracking-widest mb-2 flex justify-between px-2">
+                        <span>Enemy Strikes</span>
+                        <span>B / C</span>
+                      </div>
+                      <div className="flex-grow overflow-y-auto custom-scrollbar space-y-1 bg-black/20 p-2 border border-stone-800/50">
+                        <GuessList
+                          guesses={opp.guesses}
+                          empty="The enemy has not struck yet."
+                          targetName={
+                            freeForAll
+                              ? (g) =>
+                                  g.target === myState.id
+                                    ? "you"
+                                    : nameOf(g.target)
+                              : undefined
+                          }
+                        />
+                      </div>
                     </div>
                   </div>
                 </div>
-              </div>
+              ))}
             </div>
           </div>
         )}
@@ -529,9 +724,9 @@ export default function GameRoomPage() {
               <div className="relative flex-1">
                 <input
                   type="text"
-                  maxLength={4}
+                  maxLength={codeLength}
                   value={input}
-                  onChange={(e) => setInput(e.target.value.replace(/\D/g, ""))}
+                  onChange={(e) => setInput(normalizeInput(e.target.value))}
                   placeholder={!myState.secret ? "SET CODE" : "GUESS"}
                   className={`w-full bg-stone-900 border-2 md:p-4 p-3 text-center font-nums md:text-2xl tracking-[0.5em] text-amber-100 shadow-[inset_0_2px_10px_rgba(0,0,0,0.5)] outline-none transition-colors ${
                     inputError
@@ -552,7 +747,7 @@ export default function GameRoomPage() {
               <LegendaryButton
                 type="submit"
                 disabled={
-                  input.length !== 4 ||
+                  Array.from(input).length !== codeLength ||
                   (!!myState.secret &&
                     (gameState.status === "setup" || !isMyTurn))
                 }
@@ -564,6 +759,66 @@ export default function GameRoomPage() {
           </div>
         </div>
       )}
+      <ChatPanel myName={myName} isOwner={isOwner} className="bottom-44" />
+    </div>
+  );
+}
+
+// GuessList shows a seat's guesses in the order they were made.
+// In a free-for-all targetName labels whom each guess was aimed at.
+function GuessList({
+  guesses,
+  empty,
+  targetName,
+}: {
+  guesses: Guess[];
+  empty: string;
+  targetName?: (g: Guess) => string;
+}) {
+  if (guesses.length === 0) {
+    return <div className="text-center text-stone-700 italic py-10">{empty}</div>;
+  }
+  return (
+    <>
+      {guesses.map((g, i) => (
+        <div
+          key={i}
+          className="flex justify-between items-center p-3 bg-stone-800/30 border-b border-stone-800 last:border-0"
+        >
+          <span className="font-nums text-xl tracking-widest text-stone-300">
+            {g.code}
+            {targetName && (
+              <span className="ml-3 font-cinzel text-xs tracking-normal text-stone-500">
+                at {targetName(g)}
+              </span>
+            )}
+          </span>
+          <div className="flex gap-3 font-cinzel font-bold">
+            <span className="text-amber-500 w-8 text-right">{g.bulls}B</span>
+            <span className="text-stone-400 w-8 text-right">{g.cows}C</span>
+          </div>
+        </div>
+      ))}
+    </>
+  );
+}
+
+// TeamRoster lists a team's members, marking whoever guesses next for it.
+function TeamRoster({ side, active }: { side: PlayerState; active: boolean }) {
+  return (
+    <div className="flex flex-wrap gap-2 mb-6">
+      {side.members?.map((name, i) => (
+        <span
+          key={name}
+          className={`px-2 py-1 text-xs font-cinzel uppercase tracking-widest border ${
+            active && i === (side.guesser ?? 0)
+              ? "border-amber-700/50 text-amber-500"
+              : "border-stone-800 text-stone-500"
+          }`}
+        >
+          {name}
+        </span>
+      ))}
     </div>
   );
 }
@@ -3,6 +3,8 @@ import { RefreshCw, Trophy } from "lucide-react";
 import BackToLobby from "../components/BackToLobby";
 
 interface GameHistory {
+  id?: string;
+  roomCode?: string;
   timestamp: string;
   p1Name: string;
   p2Name: string;
@@ -4,14 +4,7 @@ import { RefreshCw, Swords, Scroll, Trophy, Users } from "lucide-react";
 import RoomCodeForm from "../components/forms/RoomCodeForm";
 import LegendaryCard from "../components/ui/LegendaryCard";
 import LegendaryButton from "../components/ui/LegendaryButton";
-
-interface RoomInfo {
-  roomCode: string;
-  ownerName: string;
-  playerCount: number;
-  status: string;
-  createdAt: string;
-}
+import { useGameStore } from "../stores/useGameStore";
 
 interface GameHistory {
   timestamp: string;
@@ -21,9 +14,12 @@ interface GameHistory {
 }
 
 export default function HomePage() {
-  const [rooms, setRooms] = useState<RoomInfo[]>([]);
+  const user = useGameStore((state) => state.user);
+  const logout = useGameStore((state) => state.logout);
+  const lobby = useGameStore((state) => state.lobby);
+  const watchLobby = useGameStore((state) => state.watchLobby);
+  const unwatchLobby = useGameStore((state) => state.unwatchLobby);
   const [history, setHistory] = useState<GameHistory[]>([]);
-  const [loading, setLoading] = useState(false);
   const [historyLoading, setHistoryLoading] = useState(false);
   const [serverStatus, setServerStatus] = useState<
     "checking" | "online" | "offline"
@@ -61,15 +57,6 @@ export default function HomePage() {
     };
   }, [serverStatus]);
 
-  const fetchRooms = () => {
-    setLoading(true);
-    fetch("/api/rooms")
-      .then((res) => res.json())
-      .then((data) => setRooms(data))
-      .catch((err) => console.error(err))
-      .finally(() => setLoading(false));
-  };
-
   const fetchHistory = () => {
     setHistoryLoading(true);
     fetch("/api/games")
@@ -86,11 +73,17 @@ export default function HomePage() {
 
   useEffect(() => {
     if (serverStatus === "online") {
-      fetchRooms();
       fetchHistory();
     }
   }, [serverStatus]);
 
+  useEffect(() => {
+    watchLobby();
+    return () => unwatchLobby();
+  }, [watchLobby, unwatchLobby]);
+
+  const rooms = lobby || [];
+
   const StatusScreen = ({
     title,
     message,
@@ -160,6 +153,25 @@ export default function HomePage() {
             >
               <Scroll size={16} /> Rules of Engagement
             </Link>
+            <span className="hidden md:inline text-yellow-900">•</span>
+            {user ? (
+              <span className="flex items-center gap-2">
+                {user.username}
+                <button
+                  onClick={logout}
+                  className="hover:text-amber-400 transition-colors uppercase"
+                >
+                  (Log Out)
+                </button>
+              </span>
+            ) : (
+              <Link
+                to="/login"
+                className="hover:text-amber-400 transition-colors"
+              >
+                Log In
+              </Link>
+            )}
           </div>
         </header>
 
@@ -179,6 +191,15 @@ export default function HomePage() {
                   </LegendaryButton>
                 </Link>
 
+                <Link to="/ranked" className="block group">
+                  <LegendaryButton
+                    variant="gold"
+                    className="md:text-lg md:py-4 px-0 w-full"
+                  >
+                    Ranked Match
+                  </LegendaryButton>
+                </Link>
+
                 <div className="relative">
                   <div className="absolute inset-0 flex items-center">
                     <div className="w-full border-t border-stone-800"></div>
@@ -199,19 +220,10 @@ export default function HomePage() {
                 <h2 className="text-xl md:text-2xl font-cinzel text-stone-300 flex items-center gap-3">
                   <Users className="text-amber-600" /> Public Lobby
                 </h2>
-                <button
-                  onClick={fetchRooms}
-                  disabled={loading}
-                  className="text-stone-500 hover:text-amber-400 transition-colors p-2 hover:bg-stone-800 rounded-full"
-                >
-                  <RefreshCw
-                    className={`h-6 w-6 ${loading ? "animate-spin" : ""}`}
-                  />
-                </button>
               </div>
 
               <div className="space-y-4 max-h-[400px] overflow-y-auto custom-scrollbar pr-2">
-                {loading && rooms.length === 0 ? (
+                {lobby === null ? (
                   <div className="text-center py-12 text-stone-600 italic font-cinzel">
                     Scouting for battles...
                   </div>
@@ -268,6 +280,18 @@ export default function HomePage() {
                   <Trophy className="text-yellow-500" /> Hall of Fame
                 </h2>
                 <div className="flex items-center gap-3">
+                  <Link
+                    to="/leaderboard"
+                    className="text-xs font-cinzel text-stone-500 hover:text-amber-400 uppercase tracking-widest"
+                  >
+                    Rankings
+                  </Link>
+                  <Link
+                    to="/tournaments"
+                    className="text-xs font-cinzel text-stone-500 hover:text-amber-400 uppercase tracking-widest"
+                  >
+                    Tournaments
+                  </Link>
                   <Link
                     to="/games"
                     className="text-xs font-cinzel text-stone-500 hover:text-amber-400 uppercase tracking-widest"
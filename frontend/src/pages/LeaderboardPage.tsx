@@ -0,0 +1,209 @@
+import { useEffect, useState } from "react";
+import { RefreshCw, Trophy } from "lucide-react";
+import BackToLobby from "../components/BackToLobby";
+
+type TimeWindow = "week" | "month" | "all";
+
+interface PlayerStats {
+  name: string;
+  games: number;
+  wins: number;
+  losses: number;
+  draws: number;
+  winRate: number;
+  avgGuesses: number;
+  fastestCrackMs?: number;
+  streak: number;
+  rating?: number;
+}
+
+interface LeaderboardPage {
+  total: number;
+  players: PlayerStats[];
+}
+
+const PAGE_SIZE = 20;
+
+const windows: { value: TimeWindow; label: string }[] = [
+  { value: "week", label: "This Week" },
+  { value: "month", label: "This Month" },
+  { value: "all", label: "All Time" },
+];
+
+const formatStreak = (streak: number) =>
+  streak > 0 ? `W${streak}` : streak < 0 ? `L${-streak}` : "-";
+
+export default function LeaderboardPage() {
+  const [timeWindow, setTimeWindow] = useState<TimeWindow>("week");
+  const [offset, setOffset] = useState(0);
+  const [page, setPage] = useState<LeaderboardPage>({ total: 0, players: [] });
+  const [loading, setLoading] = useState(false);
+
+  const fetchLeaderboard = () => {
+    setLoading(true);
+    fetch(
+      `/api/leaderboard?window=${timeWindow}&limit=${PAGE_SIZE}&offset=${offset}`,
+    )
+      .then((res) => {
+        if (!res.ok) {
+          return { total: 0, players: [] };
+        }
+        return res.json();
+      })
+      .then((data) => setPage(data))
+      .catch((err) => console.error(err))
+      .finally(() => setLoading(false));
+  };
+
+  useEffect(() => {
+    fetchLeaderboard();
+  }, [timeWindow, offset]);
+
+  return (
+    <div
+      className="min-h-screen bg-image-overlay text-parchment font-roman"
+      style={{
+        backgroundImage:
+          "url(https://images.unsplash.com/photo-1552832230-c0197dd311b5?w=1600&q=80&auto=format)",
+      }}
+    >
+      <div className="pillar-side left-0 border-r border-stone-800"></div>
+      <div className="pillar-side right-0 border-l border-stone-800"></div>
+
+      <div className="p-4 md:p-8 max-w-5xl mx-auto relative z-10">
+        <BackToLobby />
+
+        <header className="mb-12 text-center mt-12">
+          <h1 className="text-4xl md:text-5xl font-cinzel font-bold text-gold-gradient mb-4 drop-shadow-lg">
+            Champions of the Arena
+          </h1>
+          <p className="text-stone-400 font-cinzel tracking-widest uppercase text-sm">
+            Glory Ranked by Victory
+          </p>
+        </header>
+
+        <div className="card-legendary p-8 md:p-12">
+          <div className="flex justify-between items-center mb-8 border-b border-stone-800 pb-4 gap-4">
+            <div className="flex gap-2">
+              {windows.map((w) => (
+                <button
+                  key={w.value}
+                  onClick={() => {
+                    setTimeWindow(w.value);
+                    setOffset(0);
+                  }}
+                  className={`px-3 py-1 font-cinzel text-xs uppercase tracking-widest border transition-colors ${
+                    timeWindow === w.value
+                      ? "border-amber-500 text-amber-400"
+                      : "border-stone-700 text-stone-500 hover:text-amber-400"
+                  }`}
+                >
+                  {w.label}
+                </button>
+              ))}
+            </div>
+            <button
+              onClick={fetchLeaderboard}
+              disabled={loading}
+              className="text-stone-500 hover:text-amber-400 disabled:opacity-50 transition-colors p-2 hover:bg-stone-800 rounded-full"
+            >
+              <RefreshCw
+                className={`h-6 w-6 ${loading ? "animate-spin" : ""}`}
+              />
+            </button>
+          </div>
+
+          <div className="custom-scrollbar pr-2 overflow-x-auto">
+            {loading && page.players.length === 0 ? (
+              <p className="text-stone-500 italic text-center py-12 font-cinzel">
+                Counting the laurels...
+              </p>
+            ) : page.players.length === 0 ? (
+              <p className="text-stone-500 italic text-center py-12 font-cinzel">
+                No champions yet.
+              </p>
+            ) : (
+              <table className="w-full text-left border-collapse">
+                <thead className="text-stone-500 font-cinzel text-xs uppercase tracking-widest">
+                  <tr>
+                    <th className="p-3 border-b border-stone-800">#</th>
+                    <th className="p-3 border-b border-stone-800">
+                      <Trophy className="inline h-4 w-4 text-amber-500" />{" "}
+                      Gladiator
+                    </th>
+                    <th className="p-3 border-b border-stone-800">W-L-D</th>
+                    <th className="p-3 border-b border-stone-800">Win %</th>
+                    <th className="p-3 border-b border-stone-800">
+                      Avg Guesses
+                    </th>
+                    <th className="p-3 border-b border-stone-800">Fastest</th>
+                    <th className="p-3 border-b border-stone-800">Streak</th>
+                    <th className="p-3 border-b border-stone-800">Rating</th>
+                  </tr>
+                </thead>
+                <tbody className="text-base">
+                  {page.players.map((p, index) => (
+                    <tr
+                      key={p.name}
+                      className="hover:bg-white/5 transition-colors border-b border-stone-800/50 last:border-0"
+                    >
+                      <td className="p-3 text-stone-500 font-mono text-sm">
+                        {offset + index + 1}
+                      </td>
+                      <td className="p-3 font-bold text-amber-500 font-cinzel">
+                        {p.name}
+                      </td>
+                      <td className="p-3 text-stone-300 font-mono text-sm">
+                        {p.wins}-{p.losses}-{p.draws}
+                      </td>
+                      <td className="p-3 text-stone-300">
+                        {Math.round(p.winRate * 100)}%
+                      </td>
+                      <td className="p-3 text-stone-300">
+                        {p.avgGuesses ? p.avgGuesses.toFixed(1) : "-"}
+                      </td>
+                      <td className="p-3 text-stone-300">
+                        {p.fastestCrackMs
+                          ? `${(p.fastestCrackMs / 1000).toFixed(1)}s`
+                          : "-"}
+                      </td>
+                      <td className="p-3 text-stone-300 font-mono text-sm">
+                        {formatStreak(p.streak)}
+                      </td>
+                      <td className="p-3 text-stone-300">
+                        {p.rating ? Math.round(p.rating) : "-"}
+                      </td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            )}
+          </div>
+
+          {page.total > PAGE_SIZE && (
+            <div className="flex justify-between items-center mt-6 font-cinzel text-sm text-stone-500">
+              <button
+                onClick={() => setOffset(Math.max(offset - PAGE_SIZE, 0))}
+                disabled={offset === 0}
+                className="hover:text-amber-400 disabled:opacity-30 uppercase tracking-widest"
+              >
+                Previous
+              </button>
+              <span>
+                {offset + 1}-{Math.min(offset + PAGE_SIZE, page.total)} of{" "}
+                {page.total}
+              </span>
+              <button
+                onClick={() => setOffset(offset + PAGE_SIZE)}
+                disabled={offset + PAGE_SIZE >= page.total}
+                className="hover:text-amber-400 disabled:opacity-30 uppercase tracking-widest"
+              >
+                Next
+              </button>
+            </div>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
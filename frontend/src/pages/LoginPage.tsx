@@ -0,0 +1,94 @@
+import { useState } from "react";
+import { useNavigate } from "react-router-dom";
+import { useGameStore } from "../stores/useGameStore";
+import LegendaryCard from "../components/ui/LegendaryCard";
+import LegendaryButton from "../components/ui/LegendaryButton";
+import StoneInput from "../components/ui/StoneInput";
+
+export default function LoginPage() {
+  const login = useGameStore((state) => state.login);
+  const register = useGameStore((state) => state.register);
+  const navigate = useNavigate();
+  const [mode, setMode] = useState<"login" | "register">("login");
+  const [username, setUsername] = useState("");
+  const [password, setPassword] = useState("");
+  const [error, setError] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setSubmitting(true);
+    const submit = mode === "login" ? login : register;
+    const err = await submit(username.trim(), password);
+    setSubmitting(false);
+    if (err) {
+      setError(err);
+      return;
+    }
+    navigate("/");
+  };
+
+  return (
+    <div
+      className="min-h-screen bg-image-overlay text-parchment font-roman flex items-center justify-center p-4"
+      style={{
+        backgroundImage:
+          "url(https://images.unsplash.com/photo-1714259184249-b3f85962cfda?q=80&w=2672&auto=format&fit=crop)",
+      }}
+    >
+      <div className="w-full max-w-lg">
+        <LegendaryCard title={mode === "login" ? "Log In" : "Enlist"}>
+          <form onSubmit={handleSubmit} className="space-y-5 w-full">
+            {error && <p className="text-crimson">{error}</p>}
+            <StoneInput
+              label="Username"
+              value={username}
+              onChange={(e) => setUsername(e.target.value)}
+              autoComplete="username"
+              autoFocus
+              required
+            />
+            <StoneInput
+              label="Password"
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              autoComplete={
+                mode === "login" ? "current-password" : "new-password"
+              }
+              required
+            />
+            <LegendaryButton
+              type="submit"
+              variant="crimson"
+              className="w-full"
+              disabled={submitting || !username.trim() || !password}
+            >
+              {mode === "login" ? "Log In" : "Create Account"}
+            </LegendaryButton>
+            <button
+              type="button"
+              className="w-full text-stone-500 hover:text-amber-400 font-cinzel text-sm uppercase tracking-widest"
+              onClick={() => {
+                setMode(mode === "login" ? "register" : "login");
+                setError(null);
+              }}
+            >
+              {mode === "login"
+                ? "New here? Create an account"
+                : "Already enlisted? Log in"}
+            </button>
+            <LegendaryButton
+              type="button"
+              variant="gold"
+              className="w-full"
+              onClick={() => navigate("/")}
+            >
+              Return to Lobby
+            </LegendaryButton>
+          </form>
+        </LegendaryCard>
+      </div>
+    </div>
+  );
+}
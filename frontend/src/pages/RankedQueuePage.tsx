@@ -0,0 +1,72 @@
+import { useEffect, useState } from "react";
+import { useNavigate } from "react-router-dom";
+import { useGameStore } from "../stores/useGameStore";
+import LegendaryCard from "../components/ui/LegendaryCard";
+import LegendaryButton from "../components/ui/LegendaryButton";
+import PlayerNameForm from "../components/forms/PlayerNameForm";
+
+export default function RankedQueuePage() {
+  const queue = useGameStore((state) => state.queue);
+  const error = useGameStore((state) => state.error);
+  const joinQueue = useGameStore((state) => state.joinQueue);
+  const leaveQueue = useGameStore((state) => state.leaveQueue);
+  const navigate = useNavigate();
+  const [waited, setWaited] = useState(0);
+
+  useEffect(() => {
+    if (!queue) {
+      setWaited(0);
+      return;
+    }
+    const intervalId = setInterval(() => setWaited((w) => w + 1), 1000);
+    return () => clearInterval(intervalId);
+  }, [queue]);
+
+  return (
+    <div
+      className="min-h-screen bg-image-overlay text-parchment font-roman flex items-center justify-center p-4"
+      style={{
+        backgroundImage:
+          "url(https://images.unsplash.com/photo-1714259184249-b3f85962cfda?q=80&w=2672&auto=format&fit=crop)",
+      }}
+    >
+      <div className="w-full max-w-lg">
+        <LegendaryCard title="Ranked Match">
+          {error && <p className="text-crimson mb-4">{error}</p>}
+          {queue ? (
+            <div className="space-y-5 text-center">
+              <p className="text-2xl font-cinzel text-amber-100">
+                Seeking a worthy opponent...
+              </p>
+              <p className="text-stone-400">
+                Your rating:{" "}
+                <span className="text-amber-400 font-bold">
+                  {Math.round(queue.rating)}
+                </span>
+              </p>
+              <p className="text-stone-500 text-sm font-mono">
+                Waiting {waited}s
+              </p>
+              <LegendaryButton
+                variant="gold"
+                className="w-full"
+                onClick={() => {
+                  leaveQueue();
+                  navigate("/");
+                }}
+              >
+                Leave Queue
+              </LegendaryButton>
+            </div>
+          ) : (
+            <PlayerNameForm
+              onSubmit={joinQueue}
+              buttonText="Find Opponent"
+              variant="crimson"
+            />
+          )}
+        </LegendaryCard>
+      </div>
+    </div>
+  );
+}
@@ -1,23 +1,87 @@
-import { useEffect } from "react";
-import { useParams, useNavigate } from "react-router-dom";
+import { useEffect, useState } from "react";
+import { useParams, useNavigate, useSearchParams } from "react-router-dom";
 import { useGameStore } from "../stores/useGameStore";
 import { Shield, Sword, Eye } from "lucide-react";
 import BackToLobby from "../components/BackToLobby";
+import ChatPanel from "../components/ChatPanel";
+import LegendaryCard from "../components/ui/LegendaryCard";
+import LegendaryButton from "../components/ui/LegendaryButton";
+import StoneInput from "../components/ui/StoneInput";
 
 export default function SpectatePage() {
   const { gameId } = useParams();
   const navigate = useNavigate();
-  const { gameState, spectateRoom, leaveRoom, error, clearError } =
-    useGameStore();
+  const [searchParams] = useSearchParams();
+  const invite = searchParams.get("invite") ?? undefined;
+  const {
+    gameState,
+    spectateRoom,
+    leaveRoom,
+    error,
+    clearError,
+    passwordRequired,
+  } = useGameStore();
+  const [password, setPassword] = useState("");
 
   useEffect(() => {
     if (gameId) {
-      spectateRoom(gameId);
+      spectateRoom(gameId, { invite });
     }
     return () => {
       clearError();
     };
-  }, [gameId, spectateRoom, clearError]);
+  }, [gameId, invite, spectateRoom, clearError]);
+
+  if (!gameState && gameId && passwordRequired === gameId) {
+    return (
+      <div
+        className="min-h-screen bg-image-overlay text-parchment font-roman flex items-center justify-center p-4"
+        style={{
+          backgroundImage:
+            "url(https://images.unsplash.com/photo-1714259184249-b3f85962cfda?q=80&w=2672&auto=format&fit=crop)",
+        }}
+      >
+        <div className="w-full max-w-md">
+          <LegendaryCard title="Private Arena">
+            <form
+              onSubmit={(e) => {
+                e.preventDefault();
+                spectateRoom(gameId, { password });
+              }}
+              className="space-y-5 w-full"
+            >
+              {error && <p className="text-crimson">{error}</p>}
+              <StoneInput
+                label="Password"
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                autoComplete="off"
+                autoFocus
+                required
+              />
+              <LegendaryButton
+                type="submit"
+                variant="crimson"
+                className="w-full"
+                disabled={!password}
+              >
+                Take a Seat in the Stands
+              </LegendaryButton>
+              <LegendaryButton
+                type="button"
+                variant="gold"
+                className="w-full"
+                onClick={() => navigate("/")}
+              >
+                Return to Lobby
+              </LegendaryButton>
+            </form>
+          </LegendaryCard>
+        </div>
+      </div>
+    );
+  }
 
   if (error) {
     return (
@@ -177,6 +241,7 @@ export default function SpectatePage() {
           </div>
         </div>
       </main>
+      <ChatPanel />
     </div>
   );
 }
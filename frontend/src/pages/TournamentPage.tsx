@@ -0,0 +1,211 @@
+import { useEffect } from "react";
+import { Link, useParams } from "react-router-dom";
+import { Crown } from "lucide-react";
+import {
+  useGameStore,
+  formatLabels,
+  TournamentMatch,
+} from "../stores/useGameStore";
+import BackToLobby from "../components/BackToLobby";
+
+const bracketLabels: Record<string, string> = {
+  winners: "Winners Bracket",
+  losers: "Losers Bracket",
+  final: "Grand Final",
+};
+
+// groupRounds splits the matches into brackets, each a list of rounds in
+// order.
+const groupRounds = (matches: TournamentMatch[]) => {
+  const brackets = new Map<string, Map<number, TournamentMatch[]>>();
+  for (const m of matches) {
+    const bracket = m.bracket ?? "";
+    if (!brackets.has(bracket)) brackets.set(bracket, new Map());
+    const rounds = brackets.get(bracket)!;
+    if (!rounds.has(m.round)) rounds.set(m.round, []);
+    rounds.get(m.round)!.push(m);
+  }
+  return [...brackets.entries()].map(([bracket, rounds]) => ({
+    bracket,
+    rounds: [...rounds.entries()].sort(([a], [b]) => a - b),
+  }));
+};
+
+function MatchCard({ match }: { match: TournamentMatch }) {
+  const slot = (name: string) => {
+    if (!name) {
+      return (
+        <span className="text-stone-600 italic">
+          {match.status === "pending" ? "TBD" : "Bye"}
+        </span>
+      );
+    }
+    return (
+      <span
+        className={
+          match.winner === name
+            ? "text-amber-400 font-bold"
+            : match.loser === name
+              ? "text-stone-500 line-through"
+              : "text-stone-300"
+        }
+      >
+        {name}
+      </span>
+    );
+  };
+
+  return (
+    <div
+      className={`border p-3 w-48 bg-black/30 ${
+        match.status === "ready" ? "border-amber-700" : "border-stone-800"
+      } ${match.status === "skipped" ? "opacity-40" : ""}`}
+    >
+      <div className="flex justify-between text-xs font-mono text-stone-600 mb-2">
+        <span>{match.id}</span>
+        <span>{match.draw ? "draw" : match.status}</span>
+      </div>
+      <div className="flex flex-col gap-1 font-cinzel text-sm">
+        {slot(match.players[0])}
+        {slot(match.players[1])}
+      </div>
+      {match.roomCode && match.status === "ready" && (
+        <div className="flex gap-3 mt-2 text-xs font-cinzel uppercase tracking-widest">
+          <Link
+            to={`/room/${match.roomCode}`}
+            className="text-amber-500 hover:underline"
+          >
+            Fight
+          </Link>
+          <Link
+            to={`/spectate/${match.roomCode}`}
+            className="text-stone-500 hover:text-amber-400"
+          >
+            Watch
+          </Link>
+        </div>
+      )}
+    </div>
+  );
+}
+
+export default function TournamentPage() {
+  const { tournamentId } = useParams<{ tournamentId: string }>();
+  const socket = useGameStore((state) => state.socket);
+  const tournament = useGameStore((state) => state.tournament);
+  const error = useGameStore((state) => state.error);
+  const watchTournament = useGameStore((state) => state.watchTournament);
+  const unwatchTournament = useGameStore((state) => state.unwatchTournament);
+
+  useEffect(() => {
+    if (!tournamentId) return;
+    watchTournament(tournamentId);
+    return () => unwatchTournament();
+  }, [socket, tournamentId]);
+
+  const current = tournament?.id === tournamentId ? tournament : null;
+
+  return (
+    <div
+      className="min-h-screen bg-image-overlay text-parchment font-roman"
+      style={{
+        backgroundImage:
+          "url(https://images.unsplash.com/photo-1552832230-c0197dd311b5?w=1600&q=80&auto=format)",
+      }}
+    >
+      <div className="pillar-side left-0 border-r border-stone-800"></div>
+      <div className="pillar-side right-0 border-l border-stone-800"></div>
+
+      <div className="p-4 md:p-8 max-w-6xl mx-auto relative z-10">
+        <BackToLobby />
+
+        {!current ? (
+          <p className="text-stone-500 italic text-center py-24 font-cinzel">
+            {error ?? "Unrolling the bracket..."}
+          </p>
+        ) : (
+          <>
+            <header className="mb-12 text-center mt-12">
+              <h1 className="text-4xl md:text-5xl font-cinzel font-bold text-gold-gradient mb-4 drop-shadow-lg">
+                {current.name}
+              </h1>
+              <p className="text-stone-400 font-cinzel tracking-widest uppercase text-sm">
+                {formatLabels[current.format]} · called by{" "}
+                {current.organizer}
+              </p>
+              {current.champion && (
+                <p className="mt-6 text-2xl font-cinzel text-amber-400 flex items-center justify-center gap-3">
+                  <Crown className="h-7 w-7" /> {current.champion}
+                </p>
+              )}
+            </header>
+
+            {current.standings && (
+              <div className="card-legendary p-8 mb-8 overflow-x-auto">
+                <table className="w-full text-left border-collapse">
+                  <thead className="text-stone-500 font-cinzel text-xs uppercase tracking-widest">
+                    <tr>
+                      <th className="p-3 border-b border-stone-800">#</th>
+                      <th className="p-3 border-b border-stone-800">
+                        Gladiator
+                      </th>
+                      <th className="p-3 border-b border-stone-800">
+                        Played
+                      </th>
+                      <th className="p-3 border-b border-stone-800">W-L-D</th>
+                      <th className="p-3 border-b border-stone-800">
+                        Points
+                      </th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {current.standings.map((s, index) => (
+                      <tr
+                        key={s.name}
+                        className="border-b border-stone-800/50 last:border-0"
+                      >
+                        <td className="p-3 text-stone-500 font-mono text-sm">
+                          {index + 1}
+                        </td>
+                        <td className="p-3 font-bold text-amber-500 font-cinzel">
+                          {s.name}
+                        </td>
+                        <td className="p-3 text-stone-300">{s.played}</td>
+                        <td className="p-3 text-stone-300 font-mono text-sm">
+                          {s.wins}-{s.losses}-{s.draws}
+                        </td>
+                        <td className="p-3 text-stone-300">{s.points}</td>
+                      </tr>
+                    ))}
+                  </tbody>
+                </table>
+              </div>
+            )}
+
+            {groupRounds(current.matches).map(({ bracket, rounds }) => (
+              <div key={bracket} className="card-legendary p-8 mb-8">
+                {bracketLabels[bracket] && (
+                  <h2 className="text-xl font-cinzel text-stone-300 mb-6">
+                    {bracketLabels[bracket]}
+                  </h2>
+                )}
+                <div className="flex gap-6 overflow-x-auto custom-scrollbar pb-2">
+                  {rounds.map(([round, matches]) => (
+                    <div key={round} className="flex flex-col gap-4">
+                      <p className="text-stone-500 font-cinzel text-xs uppercase tracking-widest">
+                        Round {round}
+                      </p>
+                      {matches.map((m) => (
+                        <MatchCard key={m.id} match={m} />
+                      ))}
+                    </div>
+                  ))}
+                </div>
+              </div>
+            ))}
+          </>
+        )}
+      </div>
+    </div>
+  );
+}
@@ -0,0 +1,205 @@
+import { useEffect, useState } from "react";
+import { Link, useNavigate } from "react-router-dom";
+import { RefreshCw, Swords } from "lucide-react";
+import {
+  useGameStore,
+  formatLabels,
+  Tournament,
+} from "../stores/useGameStore";
+import BackToLobby from "../components/BackToLobby";
+import LegendaryButton from "../components/ui/LegendaryButton";
+import StoneInput from "../components/ui/StoneInput";
+
+type Format = Tournament["format"];
+
+interface TournamentSummary {
+  id: string;
+  name: string;
+  organizer: string;
+  format: Format;
+  players: number;
+  status: string;
+  champion?: string;
+  createdAt: string;
+}
+
+export default function TournamentsPage() {
+  const user = useGameStore((state) => state.user);
+  const navigate = useNavigate();
+  const [tournaments, setTournaments] = useState<TournamentSummary[]>([]);
+  const [loading, setLoading] = useState(false);
+  const [name, setName] = useState("");
+  const [format, setFormat] = useState<Format>("single_elimination");
+  const [roster, setRoster] = useState("");
+  const [error, setError] = useState<string | null>(null);
+  const [submitting, setSubmitting] = useState(false);
+
+  const fetchTournaments = () => {
+    setLoading(true);
+    fetch("/api/tournaments")
+      .then((res) => (res.ok ? res.json() : []))
+      .then((data) => setTournaments(data))
+      .catch((err) => console.error(err))
+      .finally(() => setLoading(false));
+  };
+
+  useEffect(() => {
+    fetchTournaments();
+  }, []);
+
+  const handleCreate = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setSubmitting(true);
+    const res = await fetch("/api/tournaments", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({
+        name: name.trim(),
+        format,
+        roster: roster
+          .split(/[\n,]/)
+          .map((p) => p.trim())
+          .filter(Boolean),
+      }),
+    });
+    setSubmitting(false);
+    if (!res.ok) {
+      setError((await res.text()).trim());
+      return;
+    }
+    const t: Tournament = await res.json();
+    navigate(`/tournaments/${t.id}`);
+  };
+
+  return (
+    <div
+      className="min-h-screen bg-image-overlay text-parchment font-roman"
+      style={{
+        backgroundImage:
+          "url(https://images.unsplash.com/photo-1552832230-c0197dd311b5?w=1600&q=80&auto=format)",
+      }}
+    >
+      <div className="pillar-side left-0 border-r border-stone-800"></div>
+      <div className="pillar-side right-0 border-l border-stone-800"></div>
+
+      <div className="p-4 md:p-8 max-w-5xl mx-auto relative z-10">
+        <BackToLobby />
+
+        <header className="mb-12 text-center mt-12">
+          <h1 className="text-4xl md:text-5xl font-cinzel font-bold text-gold-gradient mb-4 drop-shadow-lg">
+            Tournaments
+          </h1>
+          <p className="text-stone-400 font-cinzel tracking-widest uppercase text-sm">
+            Brackets of Blood and Glory
+          </p>
+        </header>
+
+        <div className="card-legendary p-8 md:p-12 mb-8">
+          <div className="flex justify-between items-center mb-8 border-b border-stone-800 pb-4">
+            <h2 className="text-xl font-cinzel text-stone-300 flex items-center gap-3">
+              <Swords className="text-amber-500" /> Games of the Season
+            </h2>
+            <button
+              onClick={fetchTournaments}
+              disabled={loading}
+              className="text-stone-500 hover:text-amber-400 disabled:opacity-50 transition-colors p-2 hover:bg-stone-800 rounded-full"
+            >
+              <RefreshCw
+                className={`h-6 w-6 ${loading ? "animate-spin" : ""}`}
+              />
+            </button>
+          </div>
+
+          {tournaments.length === 0 ? (
+            <p className="text-stone-500 italic text-center py-12 font-cinzel">
+              No tournaments have been called.
+            </p>
+          ) : (
+            <div className="space-y-3">
+              {tournaments.map((t) => (
+                <Link
+                  key={t.id}
+                  to={`/tournaments/${t.id}`}
+                  className="flex justify-between items-center p-4 border border-stone-800 hover:border-amber-700 hover:bg-white/5 transition-colors"
+                >
+                  <div>
+                    <p className="font-cinzel font-bold text-amber-500">
+                      {t.name}
+                    </p>
+                    <p className="text-stone-500 text-sm">
+                      {formatLabels[t.format]} · {t.players} gladiators ·
+                      called by {t.organizer}
+                    </p>
+                  </div>
+                  <span className="font-cinzel text-xs uppercase tracking-widest text-stone-400">
+                    {t.champion ? `Champion: ${t.champion}` : t.status}
+                  </span>
+                </Link>
+              ))}
+            </div>
+          )}
+        </div>
+
+        <div className="card-legendary p-8 md:p-12">
+          <h2 className="text-xl font-cinzel text-stone-300 mb-6">
+            Call a Tournament
+          </h2>
+          {user ? (
+            <form onSubmit={handleCreate} className="space-y-5">
+              {error && <p className="text-crimson">{error}</p>}
+              <StoneInput
+                label="Name"
+                value={name}
+                onChange={(e) => setName(e.target.value)}
+                maxLength={40}
+                required
+              />
+              <div>
+                <label className="block text-stone-400 font-cinzel text-sm tracking-widest uppercase ml-1 mb-2">
+                  Format
+                </label>
+                <select
+                  className="input-stone"
+                  value={format}
+                  onChange={(e) => setFormat(e.target.value as Format)}
+                >
+                  {Object.entries(formatLabels).map(([value, label]) => (
+                    <option key={value} value={value}>
+                      {label}
+                    </option>
+                  ))}
+                </select>
+              </div>
+              <div>
+                <label className="block text-stone-400 font-cinzel text-sm tracking-widest uppercase ml-1 mb-2">
+                  Gladiators, one per line in seed order
+                </label>
+                <textarea
+                  className="input-stone min-h-40"
+                  value={roster}
+                  onChange={(e) => setRoster(e.target.value)}
+                  required
+                />
+              </div>
+              <LegendaryButton
+                type="submit"
+                variant="crimson"
+                className="w-full"
+                disabled={submitting || !name.trim() || !roster.trim()}
+              >
+                Draw the Bracket
+              </LegendaryButton>
+            </form>
+          ) : (
+            <p className="text-stone-500">
+              <Link to="/login" className="text-amber-500 hover:underline">
+                Log in
+              </Link>{" "}
+              to call a tournament.
+            </p>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -1,20 +1,108 @@
 import { create } from "zustand";
 import { NavigateFunction } from "react-router-dom";
 
-interface Guess {
+export interface Guess {
   code: string;
   bulls: number;
   cows: number;
   timestamp: number;
+  target?: string;
+  by?: string;
 }
 
-interface PlayerState {
+export interface PlayerState {
   id: string;
   name: string;
   secret: string;
   guesses: Guess[];
   isWinner: boolean;
   isReady: boolean;
+  solved: boolean;
+  timeLeftMs?: number;
+  pending?: Guess;
+  eliminated?: boolean;
+  members?: string[];
+  guesser?: number;
+  disconnected?: boolean;
+}
+
+export interface Rules {
+  length: number;
+  alphabet: "digits" | "hex" | "letters" | "custom";
+  symbols: string;
+  winCondition: "first_crack" | "equal_turns";
+  allowDuplicates: boolean;
+  hardMode: boolean;
+  clock: Clock;
+  turnMode: "alternate" | "simultaneous";
+  tiebreak?: "draw" | "time";
+  bestOf: number;
+  players: number;
+  teamSize: number;
+  spectatorView: "open" | "hidden";
+}
+
+export interface TeamChatMessage {
+  from: string;
+  text: string;
+  timestamp: number;
+}
+
+export interface ChatMessage {
+  channel: "players" | "spectators";
+  from: string;
+  text: string;
+  timestamp: number;
+}
+
+export type Visibility = "public" | "unlisted" | "private";
+
+// RoomAccess gets a newcomer into a private room: its password, or an
+// invite from someone already in it.
+export interface RoomAccess {
+  password?: string;
+  invite?: string;
+}
+
+export interface Invite {
+  roomCode: string;
+  token: string;
+  expiresAt: number;
+}
+
+export interface Series {
+  bestOf: number;
+  game: number;
+  wins: Record<string, number>;
+  draws: number;
+  firstMover: string;
+  results: {
+    game: number;
+    firstMover: string;
+    winner: string;
+    endReason: string;
+  }[];
+  winner?: string;
+}
+
+export interface RoundResult {
+  round: number;
+  p1?: Guess;
+  p2?: Guess;
+  winner?: string;
+}
+
+export interface Clock {
+  mode: "" | "per_move" | "fischer";
+  moveSeconds?: number;
+  bankSeconds?: number;
+  incrementSeconds?: number;
+  onTimeout?: "forfeit_turn" | "forfeit_game";
+}
+
+export interface BotOptions {
+  difficulty: "weak" | "random" | "minimax";
+  thinkMs?: number;
 }
 
 interface GameState {
@@ -22,38 +110,238 @@ interface GameState {
   status: string;
   turn: string;
   ownerId: string;
+  players: PlayerState[];
+  // p1 and p2 alias the first two seats for the one-on-one views.
   p1: PlayerState;
   p2: PlayerState;
   spectators: number;
   winner?: string;
+  endReason?: string;
+  rules: Rules;
+  gameId?: string;
+  startedAt?: number;
+  endedAt?: number;
+  turnStartedAt?: number;
+  turnDeadline?: number;
+  turnRemainingMs?: number;
+  pausedAt?: number;
+  round?: number;
+  lastRound?: RoundResult;
+  series?: Series;
+}
+
+export interface GuessAnalysis {
+  code: string;
+  bulls: number;
+  cows: number;
+  candidatesBefore: number;
+  candidatesAfter: number;
+  infoBits: number;
+  worstCase?: number;
+  optimalGuess?: string;
+  optimalWorstCase?: number;
+  exact: boolean;
+}
+
+export interface Analysis {
+  roomCode: string;
+  rules: Rules;
+  players: {
+    player: string;
+    name: string;
+    target: string;
+    guesses: GuessAnalysis[];
+    totalBits: number;
+  }[];
+}
+
+export interface GameEvent {
+  seq: number;
+  type: "joined" | "secret_set" | "guess" | "result";
+  at: number;
+  player?: string;
+  name?: string;
+  guess?: Guess;
+  state?: Omit<GameState, "p1" | "p2">;
+}
+
+export interface ReplayStatus {
+  gameId: string;
+  events: number;
+  seq: number;
+  speed: number;
+  done: boolean;
+}
+
+export interface QueueStatus {
+  rating: number;
+  window: number;
+  waiting: number;
+}
+
+export interface RatingChange {
+  name: string;
+  before: number;
+  after: number;
+}
+
+export interface User {
+  id: string;
+  username: string;
+}
+
+export interface TournamentMatch {
+  id: string;
+  bracket?: "winners" | "losers" | "final";
+  round: number;
+  players: [string, string];
+  status: "pending" | "ready" | "done" | "skipped";
+  winner?: string;
+  loser?: string;
+  draw?: boolean;
+  roomCode?: string;
+}
+
+export interface Standing {
+  name: string;
+  played: number;
+  wins: number;
+  losses: number;
+  draws: number;
+  points: number;
+}
+
+export interface Tournament {
+  id: string;
+  name: string;
+  organizer: string;
+  format: "single_elimination" | "double_elimination" | "round_robin";
+  rules: Rules;
+  roster: string[];
+  status: string;
+  champion?: string;
+  createdAt: string;
+  matches: TournamentMatch[];
+  standings?: Standing[];
+}
+
+export const formatLabels: Record<Tournament["format"], string> = {
+  single_elimination: "Single Elimination",
+  double_elimination: "Double Elimination",
+  round_robin: "Round Robin",
+};
+
+export interface LobbyRoom {
+  roomCode: string;
+  ownerName: string;
+  playerCount: number;
+  maxPlayers: number;
+  status: string;
+  createdAt: string;
 }
 
+const byNewest = (a: LobbyRoom, b: LobbyRoom) =>
+  b.createdAt.localeCompare(a.createdAt);
+
 interface GameStore {
   socket: WebSocket | null;
   gameState: GameState | null;
   playerId: string | null;
+  member: number | null;
   role: "player" | "spectator" | null;
   error: string | null;
   notification: string | null;
+  analysis: Analysis | null;
+  lastRound: RoundResult | null;
+  teamChat: TeamChatMessage[];
+  chat: ChatMessage[];
+  passwordRequired: string | null;
+  invite: Invite | null;
+  spectatorsLocked: boolean;
+  replay: ReplayStatus | null;
+  queue: QueueStatus | null;
+  ratingChanges: RatingChange[] | null;
+  user: User | null;
+  tournament: Tournament | null;
+  // lobby is null until the first snapshot arrives.
+  lobby: LobbyRoom[] | null;
+  watchingLobby: boolean;
   connect: (navigate: NavigateFunction) => void;
-  createRoom: (name: string) => void;
-  joinRoom: (name: string, code: string) => void;
-  spectateRoom: (code: string) => void;
+  createRoom: (
+    name: string,
+    rules?: Partial<Rules>,
+    bot?: BotOptions,
+    visibility?: Visibility,
+    password?: string,
+  ) => void;
+  joinRoom: (name: string, code: string, access?: RoomAccess) => void;
+  spectateRoom: (code: string, access?: RoomAccess) => void;
+  createInvite: (ttlMinutes?: number) => void;
+  kick: (name: string, ban?: boolean) => void;
+  lockSpectators: (locked: boolean) => void;
+  transferOwnership: (name: string) => void;
   leaveRoom: () => void;
   setSecret: (secret: string) => void;
-  submitGuess: (guess: string) => void;
+  submitGuess: (guess: string, target?: string) => void;
   restartGame: () => void;
   pokeOpponent: () => void;
+  sendTeamChat: (text: string) => void;
+  sendChat: (text: string, channel?: ChatMessage["channel"]) => void;
+  setMuted: (name: string, muted: boolean) => void;
+  watchReplay: (gameId: string, speed?: number) => void;
+  setReplaySpeed: (speed: number) => void;
+  stopReplay: () => void;
+  joinQueue: (name: string) => void;
+  leaveQueue: () => void;
+  watchTournament: (id: string) => void;
+  unwatchTournament: () => void;
+  watchLobby: () => void;
+  unwatchLobby: () => void;
+  fetchUser: () => Promise<void>;
+  login: (username: string, password: string) => Promise<string | null>;
+  register: (username: string, password: string) => Promise<string | null>;
+  logout: () => Promise<void>;
   clearError: () => void;
 }
 
+// The session lets a dropped player take their seat back after reconnecting.
+const SESSION_KEY = "colosseum-session";
+
+interface Session {
+  roomCode: string;
+  token: string;
+}
+
+const loadSession = (): Session | null => {
+  try {
+    return JSON.parse(localStorage.getItem(SESSION_KEY) || "null");
+  } catch {
+    return null;
+  }
+};
+
 export const useGameStore = create<GameStore>((set, get) => ({
   socket: null,
   gameState: null,
   playerId: null,
+  member: null,
   role: null,
   error: null,
   notification: null,
+  analysis: null,
+  lastRound: null,
+  teamChat: [],
+  chat: [],
+  passwordRequired: null,
+  invite: null,
+  spectatorsLocked: false,
+  replay: null,
+  queue: null,
+  ratingChanges: null,
+  user: null,
+  tournament: null,
+  lobby: null,
+  watchingLobby: false,
 
   connect: (navigate) => {
     if (get().socket) return;
@@ -65,6 +353,13 @@ export const useGameStore = create<GameStore>((set, get) => ({
 
       socket.onopen = () => {
         console.log("Connected to WebSocket");
+        const session = loadSession();
+        if (session) {
+          socket.send(JSON.stringify({ type: "resume", payload: session }));
+        }
+        if (get().watchingLobby) {
+          socket.send(JSON.stringify({ type: "subscribe_lobby" }));
+        }
       };
 
       socket.onmessage = (event) => {
@@ -80,12 +375,34 @@ export const useGameStore = create<GameStore>((set, get) => ({
         switch (msg.type) {
           case "state":
             set({
-              gameState: msg.payload,
+              gameState: {
+                ...msg.payload,
+                p1: msg.payload.players[0],
+                p2: msg.payload.players[1],
+              },
               playerId: msg.playerId || null,
+              member: msg.member ?? null,
               role: msg.role || null,
+              spectatorsLocked: !!msg.spectatorsLocked,
               error: null,
             });
             break;
+          case "kicked":
+            localStorage.removeItem(SESSION_KEY);
+            set({
+              gameState: null,
+              playerId: null,
+              role: null,
+              chat: [],
+              invite: null,
+            });
+            setNotificationWithTimeout(
+              msg.payload.banned
+                ? "You have been banned from the room."
+                : "You were kicked from the room.",
+            );
+            navigate("/");
+            break;
           case "error":
             set({ error: msg.payload });
             break;
@@ -101,12 +418,120 @@ export const useGameStore = create<GameStore>((set, get) => ({
           case "notification":
             setNotificationWithTimeout(msg.payload.message);
             break;
+          case "analysis":
+            set({ analysis: msg.payload });
+            break;
+          case "round_resolved":
+            set({ lastRound: msg.payload });
+            break;
+          case "team_chat":
+            set({ teamChat: [...get().teamChat, msg.payload].slice(-100) });
+            break;
+          case "chat":
+            set({ chat: [...get().chat, msg.payload].slice(-100) });
+            break;
+          case "chat_history":
+            set({ chat: msg.payload });
+            break;
+          case "password_required":
+            set({ passwordRequired: msg.payload });
+            break;
+          case "invite":
+            set({ invite: msg.payload });
+            break;
+          case "session":
+            localStorage.setItem(
+              SESSION_KEY,
+              JSON.stringify({
+                roomCode: msg.payload.roomCode,
+                token: msg.payload.token,
+              }),
+            );
+            break;
+          case "session_expired":
+            localStorage.removeItem(SESSION_KEY);
+            break;
+          case "queue":
+            set({ queue: msg.payload });
+            break;
+          case "queue_left":
+            set({ queue: null });
+            break;
+          case "match_found":
+            set({ queue: null, ratingChanges: null });
+            navigate(`/room/${msg.payload.roomCode}`);
+            break;
+          case "ratings":
+            set({ ratingChanges: msg.payload });
+            break;
+          case "tournament":
+            set({ tournament: msg.payload });
+            break;
+          case "lobby":
+            set({ lobby: msg.payload });
+            break;
+          case "room_created":
+          case "room_updated": {
+            const room: LobbyRoom = msg.payload;
+            const rooms = (get().lobby || []).filter(
+              (r) => r.roomCode !== room.roomCode,
+            );
+            set({ lobby: [...rooms, room].sort(byNewest) });
+            break;
+          }
+          case "room_closed":
+            set({
+              lobby: (get().lobby || []).filter(
+                (r) => r.roomCode !== msg.payload.roomCode,
+              ),
+            });
+            break;
+          case "replay_start":
+            set({
+              replay: {
+                gameId: msg.payload.game.id,
+                events: msg.payload.events,
+                seq: 0,
+                speed: msg.payload.speed,
+                done: false,
+              },
+              gameState: null,
+              playerId: null,
+              role: "spectator",
+            });
+            break;
+          case "replay_event": {
+            const event: GameEvent = msg.payload;
+            const replay = get().replay;
+            set({
+              replay: replay && { ...replay, seq: event.seq },
+              ...(event.state && {
+                gameState: {
+                  ...event.state,
+                  p1: event.state.players[0],
+                  p2: event.state.players[1],
+                },
+              }),
+            });
+            break;
+          }
+          case "replay_end": {
+            const replay = get().replay;
+            set({ replay: replay && { ...replay, done: true } });
+            break;
+          }
         }
       };
 
       socket.onclose = () => {
         console.log("Disconnected, attempting to reconnect in 3 seconds...");
-        set({ socket: null, gameState: null, playerId: null, role: null });
+        set({
+          socket: null,
+          gameState: null,
+          playerId: null,
+          role: null,
+          queue: null,
+        });
         setTimeout(() => {
           connectWebSocket();
         }, 3000);
@@ -125,44 +550,82 @@ export const useGameStore = create<GameStore>((set, get) => ({
 
   clearError: () => set({ error: null }),
 
-  createRoom: (name) => {
+  createRoom: (name, rules, bot, visibility, password) => {
     const socket = get().socket;
     if (socket) {
       socket.send(
         JSON.stringify({
           type: "create_room",
-          payload: { name },
+          payload: { name, rules, bot, visibility, password },
         }),
       );
     }
   },
 
-  joinRoom: (name, code) => {
+  joinRoom: (name, code, access) => {
     get().clearError();
+    set({ passwordRequired: null });
     const socket = get().socket;
     if (socket) {
       socket.send(
         JSON.stringify({
           type: "join_room",
-          payload: { name, code },
+          payload: { name, code, ...access },
         }),
       );
     }
   },
 
-  spectateRoom: (code) => {
+  spectateRoom: (code, access) => {
     get().clearError();
+    set({ passwordRequired: null });
     const socket = get().socket;
     if (socket) {
       socket.send(
         JSON.stringify({
           type: "spectate",
-          payload: { code },
+          payload: { code, ...access },
         }),
       );
     }
   },
 
+  kick: (name, ban = false) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: ban ? "ban" : "kick", payload: { name } }),
+      );
+    }
+  },
+
+  lockSpectators: (locked) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: "lock_spectators", payload: { locked } }),
+      );
+    }
+  },
+
+  transferOwnership: (name) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: "transfer_ownership", payload: { name } }),
+      );
+    }
+  },
+
+  createInvite: (ttlMinutes) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: "create_invite", payload: { ttlMinutes } }),
+      );
+    }
+  },
+
   leaveRoom: () => {
     const socket = get().socket;
     const gameState = get().gameState;
@@ -173,7 +636,14 @@ export const useGameStore = create<GameStore>((set, get) => ({
           payload: { room_id: gameState.roomCode },
         }),
       );
-      set({ gameState: null, playerId: null, role: null });
+      localStorage.removeItem(SESSION_KEY);
+      set({
+        gameState: null,
+        playerId: null,
+        role: null,
+        chat: [],
+        invite: null,
+      });
     }
   },
 
@@ -189,13 +659,13 @@ export const useGameStore = create<GameStore>((set, get) => ({
     }
   },
 
-  submitGuess: (guess) => {
+  submitGuess: (guess, target) => {
     const socket = get().socket;
     if (socket) {
       socket.send(
         JSON.stringify({
           type: "submit_guess",
-          payload: { data: guess },
+          payload: { data: guess, target },
         }),
       );
     }
@@ -224,4 +694,162 @@ export const useGameStore = create<GameStore>((set, get) => ({
       );
     }
   },
+
+  sendTeamChat: (text) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({
+          type: "team_chat",
+          payload: { data: text },
+        }),
+      );
+    }
+  },
+
+  sendChat: (text, channel) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: "chat", payload: { text, channel } }),
+      );
+    }
+  },
+
+  setMuted: (name, muted) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: muted ? "mute" : "unmute", payload: { name } }),
+      );
+    }
+  },
+
+  watchReplay: (gameId, speed = 1) => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(
+        JSON.stringify({ type: "replay", payload: { gameId, speed } }),
+      );
+    }
+  },
+
+  setReplaySpeed: (speed) => {
+    const socket = get().socket;
+    const replay = get().replay;
+    if (socket && replay) {
+      socket.send(
+        JSON.stringify({ type: "replay_speed", payload: { speed } }),
+      );
+      set({ replay: { ...replay, speed } });
+    }
+  },
+
+  stopReplay: () => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(JSON.stringify({ type: "stop_replay" }));
+    }
+    set({ replay: null, gameState: null, role: null });
+  },
+
+  joinQueue: (name) => {
+    get().clearError();
+    const socket = get().socket;
+    if (socket) {
+      socket.send(JSON.stringify({ type: "join_queue", payload: { name } }));
+    }
+  },
+
+  leaveQueue: () => {
+    const socket = get().socket;
+    if (socket) {
+      socket.send(JSON.stringify({ type: "leave_queue" }));
+    }
+    set({ queue: null });
+  },
+
+  watchTournament: (id) => {
+    const socket = get().socket;
+    if (!socket) return;
+    const subscribe = () =>
+      socket.send(
+        JSON.stringify({ type: "subscribe_tournament", payload: { id } }),
+      );
+    if (socket.readyState === WebSocket.OPEN) {
+      subscribe();
+    } else {
+      socket.addEventListener("open", subscribe, { once: true });
+    }
+  },
+
+  unwatchTournament: () => {
+    const socket = get().socket;
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify({ type: "unsubscribe_tournament" }));
+    }
+    set({ tournament: null });
+  },
+
+  // The subscription is picked up again on reconnect, see onopen.
+  watchLobby: () => {
+    set({ watchingLobby: true });
+    const socket = get().socket;
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify({ type: "subscribe_lobby" }));
+    }
+  },
+
+  unwatchLobby: () => {
+    const socket = get().socket;
+    if (socket && socket.readyState === WebSocket.OPEN) {
+      socket.send(JSON.stringify({ type: "unsubscribe_lobby" }));
+    }
+    set({ watchingLobby: false, lobby: null });
+  },
+
+  fetchUser: async () => {
+    try {
+      const res = await fetch("/api/auth/me");
+      set({ user: res.ok ? await res.json() : null });
+    } catch {
+      set({ user: null });
+    }
+  },
+
+  // The socket only learns who it belongs to when it connects, so it is
+  // reopened whenever the user logs in or out.
+  login: async (username, password) => {
+    const res = await fetch("/api/auth/login", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ username, password }),
+    });
+    if (!res.ok) {
+      return (await res.text()).trim();
+    }
+    set({ user: await res.json() });
+    get().socket?.close();
+    return null;
+  },
+
+  register: async (username, password) => {
+    const res = await fetch("/api/auth/register", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ username, password }),
+    });
+    if (!res.ok) {
+      return (await res.text()).trim();
+    }
+    set({ user: await res.json() });
+    get().socket?.close();
+    return null;
+  },
+
+  logout: async () => {
+    await fetch("/api/auth/logout", { method: "POST" });
+    set({ user: null });
+    get().socket?.close();
+  },
 }));
@@ -0,0 +1,354 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	CookieName        = "colosseum_session"
+	sessionTTL        = 30 * 24 * time.Hour
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+
+	// After freeLogins wrong passwords in a row an account makes every
+	// further login wait loginBackoff, doubling with each failure up to
+	// maxLoginBackoff. Failures are forgotten on success, or once none has
+	// been seen for failureMemory.
+	freeLogins      = 5
+	loginBackoff    = time.Second
+	maxLoginBackoff = 15 * time.Minute
+	failureMemory   = time.Hour
+	// maxTrackedLogins bounds how many usernames failures are kept for.
+	maxTrackedLogins = 10000
+)
+
+var (
+	ErrUsernameTaken   = errors.New("that username is already taken")
+	ErrInvalidLogin    = errors.New("invalid username or password")
+	ErrInvalidUsername = errors.New("usernames are 3 to 20 letters, digits, '-' or '_'")
+	ErrInvalidPassword = fmt.Errorf("passwords are %d to %d characters", minPasswordLength, maxPasswordLength)
+)
+
+// BackoffError is returned by Login while an account is waiting out its
+// failed logins.
+type BackoffError struct {
+	RetryAfter time.Duration
+}
+
+func (e *BackoffError) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// dummyHash is compared against when a login names no account, so that
+// unknown usernames take as long to reject as wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("colosseum"), bcrypt.DefaultCost)
+
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"passwordHash,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Public is the user without their password hash.
+func (u User) Public() User {
+	u.PasswordHash = ""
+	return u
+}
+
+// Accounts keeps registered users in a JSON file and signs the session
+// cookies that prove who a request comes from.
+type Accounts struct {
+	path   string
+	secret []byte
+	mu     sync.Mutex
+	users  map[string]*User
+	byName map[string]*User
+	// failures counts recent failed logins by lowercased username, whether
+	// or not an account has it.
+	failures map[string]*loginFailures
+}
+
+type loginFailures struct {
+	count int
+	last  time.Time
+}
+
+// wait is how much longer a login must wait after these failures.
+func (f *loginFailures) wait(now time.Time) time.Duration {
+	if f == nil || f.count < freeLogins {
+		return 0
+	}
+	backoff := maxLoginBackoff
+	if shift := f.count - freeLogins; shift < 20 {
+		backoff = min(loginBackoff<<shift, maxLoginBackoff)
+	}
+	return f.last.Add(backoff).Sub(now)
+}
+
+func Open(path string, secret []byte) (*Accounts, error) {
+	a := &Accounts{
+		path:     path,
+		secret:   secret,
+		users:    make(map[string]*User),
+		byName:   make(map[string]*User),
+		failures: make(map[string]*loginFailures),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read accounts: %v", err)
+	}
+
+	var list []*User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unable to parse accounts: %v", err)
+	}
+	for _, u := range list {
+		a.users[u.ID] = u
+		a.byName[strings.ToLower(u.Username)] = u
+	}
+	return a, nil
+}
+
+func (a *Accounts) Register(username, password string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, taken := a.byName[strings.ToLower(username)]; taken {
+		return nil, ErrUsernameTaken
+	}
+	u := &User{
+		ID:           newUserID(),
+		Username:     username,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now(),
+	}
+	a.users[u.ID] = u
+	a.byName[strings.ToLower(username)] = u
+	if err := a.save(); err != nil {
+		delete(a.users, u.ID)
+		delete(a.byName, strings.ToLower(username))
+		return nil, err
+	}
+	pub := u.Public()
+	return &pub, nil
+}
+
+// Login checks a username and password. An account with too many failed
+// logins in a row returns a *BackoffError until its wait is over, without
+// the password being looked at.
+func (a *Accounts) Login(username, password string) (*User, error) {
+	name := strings.ToLower(strings.TrimSpace(username))
+	now := time.Now()
+	a.mu.Lock()
+	if wait := a.failures[name].wait(now); wait > 0 {
+		a.mu.Unlock()
+		return nil, &BackoffError{RetryAfter: wait}
+	}
+	u, ok := a.byName[name]
+	a.mu.Unlock()
+
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		a.loginFailed(name, now)
+		return nil, ErrInvalidLogin
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		a.loginFailed(name, now)
+		return nil, ErrInvalidLogin
+	}
+	a.mu.Lock()
+	delete(a.failures, name)
+	a.mu.Unlock()
+	pub := u.Public()
+	return &pub, nil
+}
+
+// loginFailed counts a failed login for name.
+func (a *Accounts) loginFailed(name string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f := a.failures[name]
+	if f == nil || now.Sub(f.last) > failureMemory {
+		if len(a.failures) >= maxTrackedLogins {
+			a.forgetFailures(now)
+		}
+		f = &loginFailures{}
+		a.failures[name] = f
+	}
+	f.count++
+	f.last = now
+}
+
+// forgetFailures drops failures that are no longer remembered. The caller
+// must hold a.mu.
+func (a *Accounts) forgetFailures(now time.Time) {
+	for name, f := range a.failures {
+		if now.Sub(f.last) > failureMemory {
+			delete(a.failures, name)
+		}
+	}
+}
+
+func (a *Accounts) User(id string) (*User, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.users[id]
+	if !ok {
+		return nil, false
+	}
+	pub := u.Public()
+	return &pub, true
+}
+
+// Registered reports whether name belongs to an account, so guests can be
+// kept from playing under it.
+func (a *Accounts) Registered(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.byName[strings.ToLower(strings.TrimSpace(name))]
+	return ok
+}
+
+// UserID returns the ID of the account name belongs to, or "" if none does.
+func (a *Accounts) UserID(name string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if u, ok := a.byName[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return u.ID
+	}
+	return ""
+}
+
+// SetSession logs u in on w with a signed cookie.
+func (a *Accounts) SetSession(w http.ResponseWriter, r *http.Request, u *User) {
+	expires := time.Now().Add(sessionTTL)
+	payload := u.ID + "." + strconv.FormatInt(expires.Unix(), 10)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    payload + "." + a.sign(payload),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (a *Accounts) ClearSession(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SessionUser returns the user whose valid session cookie r carries.
+func (a *Accounts) SessionUser(r *http.Request) (*User, bool) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return nil, false
+	}
+	payload, sig, ok := cutLast(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(a.sign(payload))) {
+		return nil, false
+	}
+	id, exp, ok := strings.Cut(payload, ".")
+	if !ok {
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return nil, false
+	}
+	return a.User(id)
+}
+
+func (a *Accounts) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+func isSecure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func newUserID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// save writes the accounts through a temporary file so a crash never leaves
+// the file half written. The caller must hold a.mu.
+func (a *Accounts) save() error {
+	list := make([]*User, 0, len(a.users))
+	for _, u := range a.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), ".accounts-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), a.path)
+}
@@ -0,0 +1,66 @@
+// Package backplane lets several servers share one set of rooms. Every room
+// is owned by the server that opened it; the backplane records who owns
+// what and carries messages between servers so that the others can reach
+// rooms they do not hold.
+package backplane
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ClaimTTL is how long a claim on a room lasts unless its owner renews it,
+// so that the rooms of a server that died are eventually let go.
+const ClaimTTL = 15 * time.Minute
+
+type Backplane interface {
+	// Claim makes node the owner of room, or renews its claim. It reports
+	// false if another node already owns the room.
+	Claim(room, node string) (bool, error)
+	// Owner returns the node that owns room, or "" if none does.
+	Owner(room string) (string, error)
+	// Release gives up node's claim on room.
+	Release(room, node string) error
+	Publish(channel string, msg []byte) error
+	// Subscribe delivers what is published on channel until the backplane
+	// is closed.
+	Subscribe(channel string) (<-chan []byte, error)
+	Close() error
+}
+
+type Config struct {
+	Backend string
+	URL     string
+	Node    string
+}
+
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Backend: strings.ToLower(os.Getenv("BACKPLANE")),
+		URL:     os.Getenv("REDIS_URL"),
+		Node:    os.Getenv("NODE_ID"),
+	}
+	if cfg.Node == "" {
+		cfg.Node, _ = os.Hostname()
+	}
+	return cfg
+}
+
+// Open connects the configured backplane. A server running on its own needs
+// none, so "" and "none" return nil.
+func Open(cfg Config) (Backplane, error) {
+	switch cfg.Backend {
+	case "", "none":
+		return nil, nil
+	case "memory":
+		return NewMemory(), nil
+	case "redis":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("the redis backplane needs REDIS_URL")
+		}
+		return DialRedis(cfg.URL)
+	}
+	return nil, fmt.Errorf("unknown backplane %q", cfg.Backend)
+}
@@ -0,0 +1,100 @@
+package backplane
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how far a subscriber may fall behind before messages
+// to it are dropped, as a broker would drop a slow subscriber.
+const subscriberBuffer = 1024
+
+type claim struct {
+	node    string
+	expires time.Time
+}
+
+// Memory is a backplane for hubs that share one process.
+type Memory struct {
+	mu     sync.Mutex
+	claims map[string]claim
+	subs   map[string][]chan []byte
+	closed bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		claims: make(map[string]claim),
+		subs:   make(map[string][]chan []byte),
+	}
+}
+
+func (m *Memory) Claim(room, node string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if c, ok := m.claims[room]; ok && c.node != node && now.Before(c.expires) {
+		return false, nil
+	}
+	m.claims[room] = claim{node: node, expires: now.Add(ClaimTTL)}
+	return true, nil
+}
+
+func (m *Memory) Owner(room string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.claims[room]
+	if !ok || time.Now().After(c.expires) {
+		return "", nil
+	}
+	return c.node, nil
+}
+
+func (m *Memory) Release(room, node string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.claims[room]; ok && c.node == node {
+		delete(m.claims, room)
+	}
+	return nil
+}
+
+func (m *Memory) Publish(channel string, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.subs[channel] {
+		select {
+		case sub <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(channel string) (<-chan []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub := make(chan []byte, subscriberBuffer)
+	if m.closed {
+		close(sub)
+		return sub, nil
+	}
+	m.subs[channel] = append(m.subs[channel], sub)
+	return sub, nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for _, subs := range m.subs {
+		for _, sub := range subs {
+			close(sub)
+		}
+	}
+	m.subs = nil
+	return nil
+}
@@ -0,0 +1,336 @@
+package backplane
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisPrefix      = "colosseum:"
+	redisDialTimeout = 5 * time.Second
+	redisTimeout     = 2 * time.Second
+	redisRetryDelay  = time.Second
+)
+
+// Redis is a backplane kept in a Redis server. Claims are keys that expire
+// after ClaimTTL, and messages go over Redis pub/sub, which delivers them at
+// most once: anything published while a subscriber is reconnecting is lost.
+type Redis struct {
+	addr     string
+	user     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+
+	subMu    sync.Mutex
+	subConns map[net.Conn]bool
+	done     chan struct{}
+	closed   bool
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// DialRedis connects to the server at a redis://[user:password@]host:port/db
+// URL.
+func DialRedis(rawURL string) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %v", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid redis URL: unsupported scheme %q", u.Scheme)
+	}
+	r := &Redis{
+		addr:     u.Host,
+		subConns: make(map[net.Conn]bool),
+		done:     make(chan struct{}),
+	}
+	if !strings.Contains(r.addr, ":") {
+		r.addr += ":6379"
+	}
+	if u.User != nil {
+		r.user = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	if _, err := r.do("PING"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Claims are checked and changed by scripts, which Redis runs atomically, so
+// that a claim that expires between the check and the change is never
+// renewed or released on behalf of a node that no longer holds it.
+const (
+	claimScript = `local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1`
+	releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+func (r *Redis) Claim(room, node string) (bool, error) {
+	ttl := strconv.FormatInt(ClaimTTL.Milliseconds(), 10)
+	reply, err := r.do("EVAL", claimScript, "1", redisPrefix+"room:"+room, node, ttl)
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+func (r *Redis) Owner(room string) (string, error) {
+	reply, err := r.do("GET", redisPrefix+"room:"+room)
+	if err != nil {
+		return "", err
+	}
+	owner, _ := reply.(string)
+	return owner, nil
+}
+
+func (r *Redis) Release(room, node string) error {
+	_, err := r.do("EVAL", releaseScript, "1", redisPrefix+"room:"+room, node)
+	return err
+}
+
+func (r *Redis) Publish(channel string, msg []byte) error {
+	_, err := r.do("PUBLISH", redisPrefix+channel, string(msg))
+	return err
+}
+
+func (r *Redis) Subscribe(channel string) (<-chan []byte, error) {
+	conn, br, err := r.subscribe(channel)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan []byte, subscriberBuffer)
+	go r.listen(channel, conn, br, out)
+	return out, nil
+}
+
+func (r *Redis) Close() error {
+	r.subMu.Lock()
+	if r.closed {
+		r.subMu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	for conn := range r.subConns {
+		conn.Close()
+	}
+	r.subMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	return nil
+}
+
+// do runs a command on the shared connection, dialling again once if the
+// connection it had turned out to be broken. A command that times out is
+// not retried, so that a stalled server holds its callers up for no longer
+// than redisTimeout.
+func (r *Redis) do(args ...string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		reused := r.conn != nil
+		if !reused {
+			conn, br, err := r.dial()
+			if err != nil {
+				return nil, err
+			}
+			r.conn, r.r = conn, br
+		}
+		reply, err := roundTrip(r.conn, r.r, args...)
+		var redisErr redisError
+		if err == nil || errors.As(err, &redisErr) {
+			return reply, err
+		}
+		r.conn.Close()
+		r.conn = nil
+		if !reused || attempt > 0 || errors.Is(err, os.ErrDeadlineExceeded) {
+			return nil, err
+		}
+	}
+}
+
+func (r *Redis) dial() (net.Conn, *bufio.Reader, error) {
+	conn, err := net.DialTimeout("tcp", r.addr, redisDialTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to reach redis at %s: %v", r.addr, err)
+	}
+	br := bufio.NewReader(conn)
+	if r.password != "" {
+		args := []string{"AUTH", r.password}
+		if r.user != "" {
+			args = []string{"AUTH", r.user, r.password}
+		}
+		if _, err := roundTrip(conn, br, args...); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := roundTrip(conn, br, "SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	return conn, br, nil
+}
+
+// subscribe opens a connection of its own for channel, since a subscribed
+// connection can run no other commands.
+func (r *Redis) subscribe(channel string) (net.Conn, *bufio.Reader, error) {
+	conn, br, err := r.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := roundTrip(conn, br, "SUBSCRIBE", redisPrefix+channel); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	// A subscription waits for messages as long as it has to.
+	conn.SetDeadline(time.Time{})
+
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+	if r.closed {
+		conn.Close()
+		return nil, nil, net.ErrClosed
+	}
+	r.subConns[conn] = true
+	return conn, br, nil
+}
+
+// listen passes on what arrives for channel, subscribing again whenever the
+// connection drops, until the backplane is closed.
+func (r *Redis) listen(channel string, conn net.Conn, br *bufio.Reader, out chan<- []byte) {
+	defer close(out)
+	for {
+		for {
+			reply, err := readReply(br)
+			if err != nil {
+				break
+			}
+			parts, ok := reply.([]interface{})
+			if !ok || len(parts) != 3 || parts[0] != "message" {
+				continue
+			}
+			payload, _ := parts[2].(string)
+			select {
+			case out <- []byte(payload):
+			case <-r.done:
+				return
+			}
+		}
+
+		r.subMu.Lock()
+		delete(r.subConns, conn)
+		r.subMu.Unlock()
+		conn.Close()
+
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-time.After(redisRetryDelay):
+			}
+			var err error
+			if conn, br, err = r.subscribe(channel); err == nil {
+				break
+			}
+			slog.Warn("backplane subscription lost, retrying", "channel", channel, "error", err)
+		}
+	}
+}
+
+// roundTrip sends one command and reads its reply, giving up with
+// os.ErrDeadlineExceeded if the two together take longer than redisTimeout.
+func roundTrip(conn net.Conn, br *bufio.Reader, args ...string) (interface{}, error) {
+	if err := conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(br)
+}
+
+// readReply reads one RESP value: simple and bulk strings come back as
+// strings, a null as nil, integers as int64 and arrays as []interface{}.
+func readReply(br *bufio.Reader) (interface{}, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(br); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/solver"
+)
+
+type Difficulty string
+
+const (
+	// Weak guesses something consistent with its feedback only half the time,
+	// unless hard mode forces it to.
+	Weak Difficulty = "weak"
+	// Random always guesses a random code that could still be the secret.
+	Random Difficulty = "random"
+	// Minimax picks the guess that minimises the worst-case number of
+	// remaining candidates, as in Knuth's Mastermind algorithm.
+	Minimax Difficulty = "minimax"
+)
+
+const (
+	DefaultThinkDelay = 1200 * time.Millisecond
+	MaxThinkDelay     = 10 * time.Second
+
+	// maxRetries bounds the attempts to draw a code the bot has not already
+	// played.
+	maxRetries = 1000
+)
+
+type Bot struct {
+	Difficulty Difficulty
+	ThinkDelay time.Duration
+
+	mu  sync.Mutex
+	rng *mrand.Rand
+}
+
+func New(difficulty Difficulty, thinkDelay time.Duration) (*Bot, error) {
+	if difficulty == "" {
+		difficulty = Random
+	}
+	switch difficulty {
+	case Weak, Random, Minimax:
+	default:
+		return nil, fmt.Errorf("unknown bot difficulty %q", difficulty)
+	}
+
+	if thinkDelay < 0 {
+		thinkDelay = 0
+	}
+	if thinkDelay > MaxThinkDelay {
+		thinkDelay = MaxThinkDelay
+	}
+
+	return &Bot{
+		Difficulty: difficulty,
+		ThinkDelay: thinkDelay,
+		rng:        mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func (b *Bot) Name() string {
+	switch b.Difficulty {
+	case Weak:
+		return "Bot (Weak)"
+	case Minimax:
+		return "Bot (Minimax)"
+	default:
+		return "Bot (Random)"
+	}
+}
+
+func (b *Bot) ChooseSecret(rules game.Rules) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return rules.RandomCode(b.rng)
+}
+
+// NextGuess picks the bot's next guess given the feedback it has received so
+// far. guesses may be in any order.
+func (b *Bot) NextGuess(rules game.Rules, guesses []game.Guess) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.Difficulty {
+	case Weak:
+		if b.rng.Intn(2) == 0 && !rules.HardMode {
+			return b.randomUnplayed(rules, guesses)
+		}
+		return b.randomConsistent(rules, guesses)
+	case Minimax:
+		return b.minimax(rules, guesses)
+	default:
+		return b.randomConsistent(rules, guesses)
+	}
+}
+
+func (b *Bot) randomUnplayed(rules game.Rules, guesses []game.Guess) string {
+	played := make(map[string]bool, len(guesses))
+	for _, g := range guesses {
+		played[g.Code] = true
+	}
+	code := rules.RandomCode(b.rng)
+	for i := 0; i < maxRetries && played[code]; i++ {
+		code = rules.RandomCode(b.rng)
+	}
+	return code
+}
+
+func (b *Bot) randomConsistent(rules game.Rules, guesses []game.Guess) string {
+	s := solver.New(rules)
+	if cands, ok := s.Candidates(guesses); ok && len(cands) > 0 {
+		return cands[b.rng.Intn(len(cands))]
+	}
+	if code, ok := s.FindConsistent(guesses, b.rng); ok {
+		return code
+	}
+	return b.randomUnplayed(rules, guesses)
+}
+
+func (b *Bot) minimax(rules game.Rules, guesses []game.Guess) string {
+	// Every opening guess is equivalent up to symmetry when symbols are
+	// unique, so there is nothing to search for on the first move.
+	if len(guesses) == 0 {
+		return rules.RandomCode(b.rng)
+	}
+	if code, ok := solver.New(rules).BestGuess(guesses, b.rng); ok {
+		return code
+	}
+	return b.randomConsistent(rules, guesses)
+}
@@ -0,0 +1,216 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+type ClockMode string
+
+const (
+	ClockNone ClockMode = ""
+	// ClockPerMove gives every move the same fixed limit.
+	ClockPerMove ClockMode = "per_move"
+	// ClockFischer gives each player a bank of time that runs down on their
+	// turns and is topped up by an increment after every move.
+	ClockFischer ClockMode = "fischer"
+)
+
+type TimeoutAction string
+
+const (
+	ForfeitTurn TimeoutAction = "forfeit_turn"
+	ForfeitGame TimeoutAction = "forfeit_game"
+)
+
+// End reasons recorded on a completed game.
+const (
+	EndCracked    = "cracked"
+	EndTimeout    = "timeout"
+	EndForfeit    = "forfeit"
+	EndDisconnect = "disconnect"
+)
+
+type Clock struct {
+	Mode             ClockMode     `json:"mode"`
+	MoveSeconds      int           `json:"moveSeconds,omitempty"`
+	BankSeconds      int           `json:"bankSeconds,omitempty"`
+	IncrementSeconds int           `json:"incrementSeconds,omitempty"`
+	OnTimeout        TimeoutAction `json:"onTimeout,omitempty"`
+}
+
+const (
+	defaultMoveSeconds = 60
+	defaultBankSeconds = 300
+)
+
+func (c Clock) resolve() (Clock, error) {
+	switch c.Mode {
+	case ClockNone:
+		return Clock{}, nil
+	case ClockPerMove:
+		if c.MoveSeconds == 0 {
+			c.MoveSeconds = defaultMoveSeconds
+		}
+		if c.MoveSeconds < 5 || c.MoveSeconds > 600 {
+			return c, fmt.Errorf("move limit must be between 5 and 600 seconds")
+		}
+		if c.OnTimeout == "" {
+			c.OnTimeout = ForfeitTurn
+		}
+		if c.OnTimeout != ForfeitTurn && c.OnTimeout != ForfeitGame {
+			return c, fmt.Errorf("unknown timeout action %q", c.OnTimeout)
+		}
+		c.BankSeconds, c.IncrementSeconds = 0, 0
+	case ClockFischer:
+		if c.BankSeconds == 0 {
+			c.BankSeconds = defaultBankSeconds
+		}
+		if c.BankSeconds < 30 || c.BankSeconds > 3600 {
+			return c, fmt.Errorf("time bank must be between 30 and 3600 seconds")
+		}
+		if c.IncrementSeconds < 0 || c.IncrementSeconds > 60 {
+			return c, fmt.Errorf("increment must be between 0 and 60 seconds")
+		}
+		// Running out of bank time always loses the game.
+		c.OnTimeout = ForfeitGame
+		c.MoveSeconds = 0
+	default:
+		return c, fmt.Errorf("unknown clock mode %q", c.Mode)
+	}
+	return c, nil
+}
+
+func (g *GameState) resetClock() {
+	g.TurnStartedAt = 0
+	g.TurnDeadline = 0
+	g.TurnRemainingMs = 0
+	g.PausedAt = 0
+	bank := int64(g.Rules.Clock.BankSeconds) * 1000
+	for _, p := range g.Players {
+		p.TimeLeftMs = bank
+	}
+}
+
+// startTurn arms the clock for whoever is to move now.
+func (g *GameState) startTurn(now time.Time) {
+	if g.Rules.Clock.Mode == ClockNone || g.Status != "active" {
+		g.TurnStartedAt, g.TurnDeadline = 0, 0
+		return
+	}
+	g.TurnStartedAt = now.UnixMilli()
+	if g.Rules.TurnMode == TurnSimultaneous {
+		g.TurnDeadline = g.roundDeadline()
+		return
+	}
+	g.TurnDeadline = g.playerDeadline(g.Player(g.Turn))
+}
+
+// playerDeadline is when p has to have moved by in the current turn or round.
+func (g *GameState) playerDeadline(p *PlayerState) int64 {
+	switch g.Rules.Clock.Mode {
+	case ClockPerMove:
+		return g.TurnStartedAt + int64(g.Rules.Clock.MoveSeconds)*1000
+	case ClockFischer:
+		return g.TurnStartedAt + p.TimeLeftMs
+	}
+	return 0
+}
+
+// chargeClock deducts the time the mover spent from their bank and adds the
+// increment.
+func (g *GameState) chargeClock(p *PlayerState, now time.Time) {
+	if g.Rules.Clock.Mode != ClockFischer || g.TurnStartedAt == 0 {
+		return
+	}
+	p.TimeLeftMs -= now.UnixMilli() - g.TurnStartedAt
+	if p.TimeLeftMs < 0 {
+		p.TimeLeftMs = 0
+	}
+	p.TimeLeftMs += int64(g.Rules.Clock.IncrementSeconds) * 1000
+}
+
+// TimedOut reports whether the player to move has run past the deadline.
+func (g *GameState) TimedOut(now time.Time) bool {
+	return g.Status == "active" && g.PausedAt == 0 && g.TurnDeadline > 0 && now.UnixMilli() >= g.TurnDeadline
+}
+
+// Pause stops the game and its clock, for example while a player
+// reconnects.
+func (g *GameState) Pause(now time.Time) {
+	if g.Status != "active" || g.PausedAt != 0 {
+		return
+	}
+	g.PausedAt = now.UnixMilli()
+}
+
+// Unpause restarts the game, pushing the current turn's deadline back by
+// however long it was paused.
+func (g *GameState) Unpause(now time.Time) {
+	if g.PausedAt == 0 {
+		return
+	}
+	paused := now.UnixMilli() - g.PausedAt
+	if g.TurnStartedAt != 0 {
+		g.TurnStartedAt += paused
+	}
+	if g.TurnDeadline != 0 {
+		g.TurnDeadline += paused
+	}
+	g.PausedAt = 0
+}
+
+// ExpireTurn applies the room's timeout rule if the player to move has run
+// out of time, either passing the turn or forfeiting the game. It reports
+// whether anything changed.
+func (g *GameState) ExpireTurn(now time.Time) bool {
+	if !g.TimedOut(now) {
+		return false
+	}
+	if g.Rules.TurnMode == TurnSimultaneous {
+		return g.expireRound(now)
+	}
+
+	mover := g.Player(g.Turn)
+	mover.passGuesser()
+	if g.Rules.Clock.Mode == ClockFischer {
+		mover.TimeLeftMs = 0
+	}
+
+	// In a free-for-all, forfeiting the game knocks the mover out rather
+	// than ending it for everyone.
+	if g.IsFreeForAll() {
+		if g.Rules.Clock.OnTimeout == ForfeitGame {
+			mover.Eliminated = true
+			if g.finishIfLastStanding(EndTimeout) {
+				return true
+			}
+		}
+		g.Turn = g.nextActive(mover.ID)
+		g.startTurn(now)
+		return true
+	}
+
+	opponent := g.opponent(g.Turn)
+	if g.Rules.Clock.OnTimeout == ForfeitGame || opponent.Solved {
+		g.complete(string(opponent.ID), EndTimeout)
+		return true
+	}
+
+	g.Turn = opponent.ID
+	g.startTurn(now)
+	return true
+}
+
+// StampClock fills in the time left for the current turn as of now, for
+// clients whose own clocks may not agree with the server's.
+func (g *GameState) StampClock(now time.Time) {
+	if g.TurnDeadline == 0 || g.Status != "active" {
+		g.TurnRemainingMs = 0
+		return
+	}
+	if g.PausedAt != 0 {
+		now = time.UnixMilli(g.PausedAt)
+	}
+	g.TurnRemainingMs = max(0, g.TurnDeadline-now.UnixMilli())
+}
@@ -0,0 +1,86 @@
+package game
+
+import "time"
+
+// Event kinds in a game's log.
+const (
+	EventJoined    = "joined"
+	EventSecretSet = "secret_set"
+	EventGuess     = "guess"
+	EventResult    = "result"
+)
+
+// Event is one step of a game as it was played, with the state right after
+// it so a replay can show the board at each step. Joins carry no state since
+// they may predate the game by several rematches.
+type Event struct {
+	Seq    int        `json:"seq"`
+	Type   string     `json:"type"`
+	At     int64      `json:"at"`
+	Player PlayerID   `json:"player,omitempty"`
+	Name   string     `json:"name,omitempty"`
+	Guess  *Guess     `json:"guess,omitempty"`
+	State  *GameState `json:"state,omitempty"`
+}
+
+func (g *GameState) record(kind string, pid PlayerID, name string, guess *Guess) {
+	e := Event{
+		Seq:    len(g.Log) + 1,
+		Type:   kind,
+		At:     time.Now().UnixMilli(),
+		Player: pid,
+		Name:   name,
+	}
+	if guess != nil {
+		gc := *guess
+		e.Guess = &gc
+	}
+	if kind != EventJoined {
+		log := g.Log
+		g.Log = nil
+		e.State = g.Clone()
+		g.Log = log
+	}
+	g.Log = append(g.Log, e)
+}
+
+// trimLog starts the log afresh for the next game, keeping only the joins of
+// players still seated.
+func (g *GameState) trimLog() {
+	var kept []Event
+	for _, e := range g.Log {
+		if e.Type != EventJoined || !g.seats(e.Player, e.Name) {
+			continue
+		}
+		e.Seq = len(kept) + 1
+		kept = append(kept, e)
+	}
+	g.Log = kept
+}
+
+// seats reports whether name sits in pid's seat, alone or in its team.
+func (g *GameState) seats(pid PlayerID, name string) bool {
+	p := g.Player(pid)
+	if p == nil {
+		return false
+	}
+	if len(p.Members) == 0 {
+		return p.Name == name
+	}
+	for _, m := range p.Members {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shiftLog follows everyone seated after pid up one seat when pid leaves.
+func (g *GameState) shiftLog(pid PlayerID) {
+	gone := SeatIndex(pid)
+	for i := range g.Log {
+		if j := SeatIndex(g.Log[i].Player); j > gone {
+			g.Log[i].Player = SeatID(j - 1)
+		}
+	}
+}
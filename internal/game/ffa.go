@@ -0,0 +1,69 @@
+package game
+
+import "time"
+
+// makeFreeForAllGuess scores a guess in a room of three or more players.
+// Cracking a code knocks its owner out, and the last player whose code is
+// still standing wins.
+func (g *GameState) makeFreeForAllGuess(pid, target PlayerID, code string) {
+	guesser := g.Player(pid)
+	victim := g.Player(target)
+	now := time.Now()
+	g.chargeClock(guesser, now)
+
+	bulls, cows := g.Rules.Score(code, victim.Secret)
+	guess := Guess{
+		Code:      code,
+		Bulls:     bulls,
+		Cows:      cows,
+		Timestamp: now.UnixMilli(),
+		Target:    target,
+	}
+	guesser.sign(&guess)
+	guesser.Guesses = append([]Guess{guess}, guesser.Guesses...)
+	g.record(EventGuess, pid, guesser.Name, &guess)
+
+	if bulls == g.Rules.Length {
+		guesser.Solved = true
+		victim.Eliminated = true
+		if g.finishIfLastStanding(EndCracked) {
+			return
+		}
+	}
+	g.Turn = g.nextActive(pid)
+	g.startTurn(now)
+}
+
+// finishIfLastStanding completes the game once only one player is left.
+func (g *GameState) finishIfLastStanding(reason string) bool {
+	left := g.Active()
+	if len(left) != 1 {
+		return false
+	}
+	g.complete(string(left[0].ID), reason)
+	return true
+}
+
+// nextActive returns the next player after pid, in seat order, who is still
+// in the game.
+func (g *GameState) nextActive(pid PlayerID) PlayerID {
+	i := SeatIndex(pid)
+	for step := 1; step <= len(g.Players); step++ {
+		p := g.Players[(i+step)%len(g.Players)]
+		if !p.Eliminated {
+			return p.ID
+		}
+	}
+	return pid
+}
+
+// Active returns the players still in the game.
+func (g *GameState) Active() []*PlayerState {
+	var out []*PlayerState
+	for _, p := range g.Players {
+		if !p.Eliminated {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -2,8 +2,11 @@ package game
 
 import (
 	"crypto/rand"
+	"fmt"
 	"math/big"
 	mrand "math/rand"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,11 +17,38 @@ const (
 	Player2 PlayerID = "p2"
 )
 
+const (
+	MinPlayers = 2
+	MaxPlayers = 8
+)
+
+// SeatID returns the ID of the seat at index i, so seat 0 is "p1".
+func SeatID(i int) PlayerID {
+	return PlayerID(fmt.Sprintf("p%d", i+1))
+}
+
+// SeatIndex is the inverse of SeatID. It returns -1 for anything that is not
+// a seat ID.
+func SeatIndex(pid PlayerID) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(string(pid), "p"))
+	if err != nil || !strings.HasPrefix(string(pid), "p") || n < 1 || n > MaxPlayers {
+		return -1
+	}
+	return n - 1
+}
+
+// Draw is stored in GameState.Winner when neither player wins.
+const Draw = "draw"
+
 type Guess struct {
 	Code      string `json:"code"`
 	Bulls     int    `json:"bulls"`
 	Cows      int    `json:"cows"`
 	Timestamp int64  `json:"timestamp"`
+	// Target is the player whose secret the guess was scored against.
+	Target PlayerID `json:"target,omitempty"`
+	// By names the team member who made the guess in team games.
+	By string `json:"by,omitempty"`
 }
 
 type PlayerState struct {
@@ -28,96 +58,412 @@ type PlayerState struct {
 	Guesses  []Guess  `json:"guesses"`
 	IsWinner bool     `json:"isWinner"`
 	IsReady  bool     `json:"isReady"`
+	Solved   bool     `json:"solved"`
+	// TimeLeftMs is the player's remaining bank under a Fischer clock, as
+	// of the start of the current turn.
+	TimeLeftMs int64 `json:"timeLeftMs,omitempty"`
+	// Pending is the guess locked in for the current simultaneous round.
+	// Only its timestamp is shown to the opponent until the round closes.
+	Pending *Guess `json:"pending,omitempty"`
+	// Eliminated is set once the player's own code has been cracked in a
+	// free-for-all. They stay seated but watch the rest of the game.
+	Eliminated bool `json:"eliminated,omitempty"`
+	// Members are the players sharing this side in a team game, and
+	// Guesser indexes whoever guesses next for it.
+	Members []string `json:"members,omitempty"`
+	Guesser int      `json:"guesser,omitempty"`
+	// Disconnected is set while the seat is held for a player, or a team
+	// member, whose connection dropped.
+	Disconnected bool `json:"disconnected,omitempty"`
+}
+
+// GuessesAt returns the player's guesses against target, newest first.
+func (p *PlayerState) GuessesAt(target PlayerID) []Guess {
+	out := []Guess{}
+	for _, g := range p.Guesses {
+		if g.Target == target {
+			out = append(out, g)
+		}
+	}
+	return out
 }
 
 type GameState struct {
-	RoomCode   string       `json:"roomCode"`
-	Status     string       `json:"status"`
-	Turn       PlayerID     `json:"turn"`
-	OwnerID    PlayerID     `json:"ownerId"`
-	P1         *PlayerState `json:"p1"`
-	P2         *PlayerState `json:"p2"`
-	Spectators int          `json:"spectators"`
-	Winner     string       `json:"winner,omitempty"`
-}
-
-func NewGame(roomCode string) *GameState {
-	return &GameState{
+	RoomCode   string         `json:"roomCode"`
+	Status     string         `json:"status"`
+	Turn       PlayerID       `json:"turn"`
+	OwnerID    PlayerID       `json:"ownerId"`
+	Players    []*PlayerState `json:"players"`
+	Spectators int            `json:"spectators"`
+	Winner     string         `json:"winner,omitempty"`
+	EndReason  string         `json:"endReason,omitempty"`
+	Rules      Rules          `json:"rules"`
+
+	// Round counts simultaneous rounds from 1; LastRound is the outcome of
+	// the most recently closed one.
+	Round     int          `json:"round,omitempty"`
+	LastRound *RoundResult `json:"lastRound,omitempty"`
+
+	Series *Series `json:"series,omitempty"`
+
+	// GameID identifies the game in progress or just finished; StartedAt
+	// and EndedAt are when it went active and completed.
+	GameID    string `json:"gameId,omitempty"`
+	StartedAt int64  `json:"startedAt,omitempty"`
+	EndedAt   int64  `json:"endedAt,omitempty"`
+
+	TurnStartedAt   int64 `json:"turnStartedAt,omitempty"`
+	TurnDeadline    int64 `json:"turnDeadline,omitempty"`
+	TurnRemainingMs int64 `json:"turnRemainingMs,omitempty"`
+	// PausedAt is set while the game is on hold.
+	PausedAt int64 `json:"pausedAt,omitempty"`
+
+	// Log is the current game's events so far.
+	Log []Event `json:"-"`
+}
+
+func NewGame(roomCode string, rules Rules) *GameState {
+	seats := max(rules.Players, MinPlayers)
+	g := &GameState{
 		RoomCode: roomCode,
+		Rules:    rules,
 		Status:   "waiting",
 		Turn:     Player1,
 		OwnerID:  Player1,
-		P1:       &PlayerState{ID: Player1, Guesses: []Guess{}},
-		P2:       &PlayerState{ID: Player2, Guesses: []Guess{}},
+		Players:  make([]*PlayerState, seats),
+		Series:   newSeries(rules.BestOf),
+	}
+	for i := range g.Players {
+		g.Players[i] = &PlayerState{ID: SeatID(i), Guesses: []Guess{}}
 	}
+	g.resetClock()
+	return g
 }
 
-func (g *GameState) SetSecret(pid PlayerID, secret string) {
+// Player returns the player in seat pid, or nil if there is no such seat.
+func (g *GameState) Player(pid PlayerID) *PlayerState {
+	i := SeatIndex(pid)
+	if i < 0 || i >= len(g.Players) {
+		return nil
+	}
+	return g.Players[i]
+}
+
+// opponent is the other player in a two-player game.
+func (g *GameState) opponent(pid PlayerID) *PlayerState {
 	if pid == Player1 {
-		g.P1.Secret = secret
-		g.P1.IsReady = true
-	} else {
-		g.P2.Secret = secret
-		g.P2.IsReady = true
+		return g.Players[1]
 	}
+	return g.Players[0]
+}
 
-	if g.P1.IsReady && g.P2.IsReady {
+// IsFreeForAll reports whether the room seats more than two players.
+func (g *GameState) IsFreeForAll() bool {
+	return len(g.Players) > 2
+}
+
+// Seated returns the players who have taken a seat.
+func (g *GameState) Seated() []*PlayerState {
+	var out []*PlayerState
+	for _, p := range g.Players {
+		if p.Name != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Full reports whether every seat has been taken, and in team games whether
+// every team is complete.
+func (g *GameState) Full() bool {
+	if g.IsTeamGame() {
+		for _, p := range g.Players {
+			if len(p.Members) < g.Rules.TeamSize {
+				return false
+			}
+		}
+		return true
+	}
+	return len(g.Seated()) == len(g.Players)
+}
+
+// Join seats name in the first free seat, or on a team with room, moving the
+// room to setup once the table is full. It returns the seat and, for team
+// games, the member's place in the team, or false if the room is full.
+func (g *GameState) Join(name string) (PlayerID, int, bool) {
+	if g.IsTeamGame() {
+		return g.joinTeam(name)
+	}
+	for _, p := range g.Players {
+		if p.Name == "" {
+			p.Name = name
+			g.record(EventJoined, p.ID, name, nil)
+			if g.Full() {
+				g.Status = "setup"
+			}
+			return p.ID, 0, true
+		}
+	}
+	return "", 0, false
+}
+
+// AllReady reports whether every player has set a secret, or after a game,
+// asked for a rematch.
+func (g *GameState) AllReady() bool {
+	for _, p := range g.Players {
+		if !p.IsReady {
+			return false
+		}
+	}
+	return true
+}
+
+// GuessCount is the number of guesses scored so far in the game.
+func (g *GameState) GuessCount() int {
+	n := 0
+	for _, p := range g.Players {
+		n += len(p.Guesses)
+	}
+	return n
+}
+
+func (g *GameState) SetSecret(pid PlayerID, secret string) {
+	p := g.Player(pid)
+	if p == nil {
+		return
+	}
+	p.Secret = secret
+	p.IsReady = true
+
+	if g.Full() && g.AllReady() {
+		now := time.Now()
 		g.Status = "active"
-		g.Turn = Player1
+		g.Turn = g.FirstMover()
+		if g.Rules.TurnMode == TurnSimultaneous {
+			g.Round = 1
+		}
+		g.GameID = newGameID()
+		g.StartedAt = now.UnixMilli()
+		g.startTurn(now)
 	}
+	g.record(EventSecretSet, pid, p.Name, nil)
 }
 
-func (g *GameState) MakeGuess(pid PlayerID, code string) {
-	var guesser, opponent *PlayerState
-	if pid == Player1 {
-		guesser = g.P1
-		opponent = g.P2
-	} else {
-		guesser = g.P2
-		opponent = g.P1
+// TargetFor checks that pid may attack target. In a two-player game the
+// target may be left empty and is always the opponent.
+func (g *GameState) TargetFor(pid, target PlayerID) (PlayerID, bool) {
+	if !g.IsFreeForAll() {
+		opponent := g.opponent(pid).ID
+		return opponent, target == "" || target == opponent
 	}
+	t := g.Player(target)
+	if t == nil || t.ID == pid || t.Eliminated {
+		return "", false
+	}
+	return t.ID, true
+}
 
-	bulls, cows := CalculateBullsCows(code, opponent.Secret)
+// MakeGuess scores code against target's secret. Callers check the target
+// with TargetFor first.
+func (g *GameState) MakeGuess(pid, target PlayerID, code string) {
+	if g.Rules.TurnMode == TurnSimultaneous {
+		g.submitRoundGuess(pid, code)
+		return
+	}
+	if g.IsFreeForAll() {
+		g.makeFreeForAllGuess(pid, target, code)
+		return
+	}
+
+	guesser := g.Player(pid)
+	opponent := g.opponent(pid)
+	now := time.Now()
+	g.chargeClock(guesser, now)
+
+	bulls, cows := g.Rules.Score(code, opponent.Secret)
 	guess := Guess{
 		Code:      code,
 		Bulls:     bulls,
 		Cows:      cows,
-		Timestamp: time.Now().UnixMilli(),
+		Timestamp: now.UnixMilli(),
+		Target:    opponent.ID,
 	}
+	guesser.sign(&guess)
 
 	guesser.Guesses = append([]Guess{guess}, guesser.Guesses...)
+	g.record(EventGuess, pid, guesser.Name, &guess)
+
+	cracked := bulls == g.Rules.Length
+	if cracked {
+		guesser.Solved = true
+	}
+
+	switch {
+	case cracked && g.Rules.WinCondition == WinEqualTurns && len(opponent.Guesses) < len(guesser.Guesses):
+		g.Turn = opponent.ID
+		g.startTurn(now)
+	case cracked && opponent.Solved:
+		g.complete(Draw, EndCracked)
+	case cracked:
+		g.complete(string(pid), EndCracked)
+	case opponent.Solved:
+		g.complete(string(opponent.ID), EndCracked)
+	default:
+		g.Turn = opponent.ID
+		g.startTurn(now)
+	}
+}
 
-	if bulls == 4 {
-		g.Status = "completed"
-		guesser.IsWinner = true
-		g.Winner = string(pid)
-		g.P1.IsReady = false
-		g.P2.IsReady = false
+// sign credits guess to the team member making it and passes the next
+// guess to their teammate.
+func (p *PlayerState) sign(guess *Guess) {
+	if len(p.Members) == 0 {
+		return
+	}
+	guess.By = p.GuesserName()
+	p.passGuesser()
+}
+
+func (g *GameState) complete(winner, reason string) {
+	g.Status = "completed"
+	g.Winner = winner
+	g.EndReason = reason
+	g.EndedAt = time.Now().UnixMilli()
+	g.TurnDeadline = 0
+	for _, p := range g.Players {
+		p.IsWinner = winner == string(p.ID)
+		p.IsReady = false
+	}
+	if g.Series != nil {
+		g.Series.record(winner, reason)
+	}
+	if p := g.Player(PlayerID(winner)); p != nil {
+		g.record(EventResult, p.ID, p.Name, nil)
 	} else {
-		if g.Turn == Player1 {
-			g.Turn = Player2
-		} else {
-			g.Turn = Player1
+		g.record(EventResult, "", "", nil)
+	}
+}
+
+// Forfeit ends pid's part in an active game for reason. Their opponent wins a
+// two-sided game; in a free-for-all they are knocked out and the game goes on
+// unless one player is left. It reports whether the game is now over.
+func (g *GameState) Forfeit(pid PlayerID, reason string) bool {
+	p := g.Player(pid)
+	if g.Status != "active" || p == nil {
+		return false
+	}
+	if !g.IsFreeForAll() {
+		g.complete(string(g.opponent(pid).ID), reason)
+		return true
+	}
+	p.Eliminated = true
+	if g.finishIfLastStanding(reason) {
+		return true
+	}
+	if g.Turn == pid {
+		g.Turn = g.nextActive(pid)
+		g.startTurn(time.Now())
+	}
+	return false
+}
+
+// FirstMover is the player who opens the current game.
+func (g *GameState) FirstMover() PlayerID {
+	if g.Series != nil {
+		return g.Series.FirstMover
+	}
+	return Player1
+}
+
+// Clone returns a deep copy that is safe to read after the room lock is
+// released.
+func (g *GameState) Clone() *GameState {
+	c := *g
+	c.Players = make([]*PlayerState, len(g.Players))
+	for i, p := range g.Players {
+		c.Players[i] = p.clone()
+	}
+	c.Series = g.Series.clone()
+	c.Log = append([]Event(nil), g.Log...)
+	return &c
+}
+
+func (p *PlayerState) clone() *PlayerState {
+	c := *p
+	c.Guesses = append([]Guess{}, p.Guesses...)
+	c.Members = append([]string(nil), p.Members...)
+	return &c
+}
+
+// Vacate frees pid's seat and sends the room back to waiting for a full
+// table, abandoning any game or series in progress. Everyone seated after
+// pid moves up a seat, so the first seat is always filled while anyone is
+// left; Vacate reports whether anyone moved. Ownership moves with the
+// owner's seat, or passes to the first seat if the owner is the one leaving.
+func (g *GameState) Vacate(pid PlayerID) bool {
+	i := SeatIndex(pid)
+	if i < 0 || i >= len(g.Players) {
+		return false
+	}
+	moved := false
+	for j := i + 1; j < len(g.Players); j++ {
+		if g.Players[j].Name != "" {
+			moved = true
 		}
 	}
+
+	g.shiftLog(pid)
+	g.Players = append(g.Players[:i], g.Players[i+1:]...)
+	g.Players = append(g.Players, &PlayerState{Guesses: []Guess{}})
+	for j, p := range g.Players {
+		p.ID = SeatID(j)
+	}
+	switch owner := SeatIndex(g.OwnerID); {
+	case owner == i:
+		g.OwnerID = Player1
+	case owner > i:
+		g.OwnerID = SeatID(owner - 1)
+	}
+	g.Series = newSeries(g.Rules.BestOf)
+	g.Reset()
+	return moved
 }
 
+// Reset clears the board for the next game. In a series it moves on to the
+// next game once the current one is finished, or starts a new series once
+// the last one has been decided.
 func (g *GameState) Reset() {
-	if g.P2.Name != "" {
+	if g.Full() {
 		g.Status = "setup"
 	} else {
 		g.Status = "waiting"
 	}
-	g.Turn = Player1
-	g.P1.Secret = ""
-	g.P1.Guesses = []Guess{}
-	g.P1.IsWinner = false
-	g.P1.IsReady = false
-	g.P2.Secret = ""
-	g.P2.Guesses = []Guess{}
-	g.P2.IsWinner = false
-	g.P2.IsReady = false
+	if s := g.Series; s != nil {
+		if s.Over() {
+			g.Series = newSeries(g.Rules.BestOf)
+		} else if len(s.Results) == s.Game {
+			s.next()
+		}
+	}
+	g.Turn = g.FirstMover()
+	for _, p := range g.Players {
+		p.Secret = ""
+		p.Guesses = []Guess{}
+		p.IsWinner = false
+		p.IsReady = false
+		p.Solved = false
+		p.Pending = nil
+		p.Eliminated = false
+	}
 	g.Winner = ""
+	g.EndReason = ""
+	g.GameID = ""
+	g.StartedAt = 0
+	g.EndedAt = 0
+	g.Round = 0
+	g.LastRound = nil
+	g.resetClock()
+	g.trimLog()
 }
 
 func CalculateBullsCows(guess, secret string) (int, int) {
@@ -125,23 +471,27 @@ func CalculateBullsCows(guess, secret string) (int, int) {
 	cows := 0
 	secretArr := []rune(secret)
 	guessArr := []rune(guess)
+	n := min(len(secretArr), len(guessArr))
+	secretUsed := make([]bool, n)
+	guessUsed := make([]bool, n)
 
-	for i := 0; i < 4; i++ {
+	for i := 0; i < n; i++ {
 		if guessArr[i] == secretArr[i] {
 			bulls++
-			secretArr[i] = 'X'
-			guessArr[i] = 'Y'
+			secretUsed[i] = true
+			guessUsed[i] = true
 		}
 	}
 
-	for i := 0; i < 4; i++ {
-		if guessArr[i] != 'Y' {
-			for j := 0; j < 4; j++ {
-				if guessArr[i] == secretArr[j] {
-					cows++
-					secretArr[j] = 'X'
-					break
-				}
+	for i := 0; i < n; i++ {
+		if guessUsed[i] {
+			continue
+		}
+		for j := 0; j < n; j++ {
+			if !secretUsed[j] && guessArr[i] == secretArr[j] {
+				cows++
+				secretUsed[j] = true
+				break
 			}
 		}
 	}
@@ -149,6 +499,63 @@ func CalculateBullsCows(guess, secret string) (int, int) {
 	return bulls, cows
 }
 
+// CalculateBullsCowsMultiset scores codes that may repeat symbols. A symbol
+// counts as a cow at most as many times as it appears, unmatched, in both
+// the guess and the secret, so "1122" against "2211" is 0 bulls, 4 cows and
+// "1111" against "1234" is 1 bull, 0 cows.
+func CalculateBullsCowsMultiset(guess, secret string) (int, int) {
+	guessArr := []rune(guess)
+	secretArr := []rune(secret)
+	n := min(len(guessArr), len(secretArr))
+
+	bulls := 0
+	guessCounts := make(map[rune]int)
+	secretCounts := make(map[rune]int)
+	for i := 0; i < n; i++ {
+		if guessArr[i] == secretArr[i] {
+			bulls++
+			continue
+		}
+		guessCounts[guessArr[i]]++
+		secretCounts[secretArr[i]]++
+	}
+
+	cows := 0
+	for r, gc := range guessCounts {
+		cows += min(gc, secretCounts[r])
+	}
+
+	return bulls, cows
+}
+
+// Contradiction reports the earliest guess whose feedback rules out code as
+// the secret. guesses are newest first, as stored on PlayerState.
+func Contradiction(rules Rules, code string, guesses []Guess) (Guess, bool) {
+	for i := len(guesses) - 1; i >= 0; i-- {
+		g := guesses[i]
+		bulls, cows := rules.Score(g.Code, code)
+		if bulls != g.Bulls || cows != g.Cows {
+			return g, true
+		}
+	}
+	return Guess{}, false
+}
+
+// IsConsistent reports whether code could still be the secret given the
+// feedback in guesses.
+func IsConsistent(rules Rules, code string, guesses []Guess) bool {
+	_, contradicts := Contradiction(rules, code, guesses)
+	return !contradicts
+}
+
+func newGameID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return fmt.Sprintf("%x", b)
+}
+
 func GenerateRoomCode() string {
 	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	const length = 6
@@ -163,20 +570,3 @@ func GenerateRoomCode() string {
 	}
 	return string(b)
 }
-
-func IsValidSecret(code string) bool {
-	if len(code) != 4 {
-		return false
-	}
-	seen := make(map[rune]bool)
-	for _, r := range code {
-		if r < '0' || r > '9' {
-			return false
-		}
-		if seen[r] {
-			return false
-		}
-		seen[r] = true
-	}
-	return true
-}
@@ -0,0 +1,154 @@
+package game
+
+import "time"
+
+type TurnMode string
+
+const (
+	// TurnAlternate passes the turn back and forth after every guess.
+	TurnAlternate TurnMode = "alternate"
+	// TurnSimultaneous has both players guess every round, with feedback
+	// revealed to both once the round closes.
+	TurnSimultaneous TurnMode = "simultaneous"
+)
+
+// Tiebreak decides a simultaneous round in which both players crack.
+type Tiebreak string
+
+const (
+	TiebreakDraw Tiebreak = "draw"
+	// TiebreakTime awards the game to whoever submitted first.
+	TiebreakTime Tiebreak = "time"
+)
+
+// RoundResult is what both players learn when a simultaneous round closes.
+// A nil guess means that player ran out of time before submitting.
+type RoundResult struct {
+	Round  int    `json:"round"`
+	P1     *Guess `json:"p1,omitempty"`
+	P2     *Guess `json:"p2,omitempty"`
+	Winner string `json:"winner,omitempty"`
+}
+
+// CanGuess reports whether pid may submit a guess right now.
+func (g *GameState) CanGuess(pid PlayerID) bool {
+	p := g.Player(pid)
+	if g.Status != "active" || g.PausedAt != 0 || p == nil || p.Eliminated {
+		return false
+	}
+	if g.Rules.TurnMode == TurnSimultaneous {
+		return p.Pending == nil
+	}
+	return g.Turn == pid
+}
+
+// submitRoundGuess locks in pid's guess for the current simultaneous round
+// and closes the round once both players have submitted.
+func (g *GameState) submitRoundGuess(pid PlayerID, code string) {
+	now := time.Now()
+	p := g.Player(pid)
+	g.chargeClock(p, now)
+	p.Pending = &Guess{Code: code, Timestamp: now.UnixMilli(), Target: g.opponent(pid).ID}
+	p.sign(p.Pending)
+
+	if g.Players[0].Pending == nil || g.Players[1].Pending == nil {
+		g.TurnDeadline = g.roundDeadline()
+		return
+	}
+	g.resolveRound(now)
+}
+
+// resolveRound scores every pending guess at once and either ends the game or
+// opens the next round. The outcome is kept in LastRound.
+func (g *GameState) resolveRound(now time.Time) {
+	result := &RoundResult{Round: g.Round}
+
+	for _, p := range g.Players {
+		if p.Pending == nil {
+			continue
+		}
+		guess := *p.Pending
+		guess.Bulls, guess.Cows = g.Rules.Score(guess.Code, g.opponent(p.ID).Secret)
+		p.Guesses = append([]Guess{guess}, p.Guesses...)
+		p.Pending = nil
+		g.record(EventGuess, p.ID, p.Name, &guess)
+		if guess.Bulls == g.Rules.Length {
+			p.Solved = true
+		}
+		if p.ID == Player1 {
+			result.P1 = &guess
+		} else {
+			result.P2 = &guess
+		}
+	}
+
+	switch {
+	case g.Players[0].Solved && g.Players[1].Solved:
+		winner := Draw
+		if g.Rules.Tiebreak == TiebreakTime && result.P1.Timestamp != result.P2.Timestamp {
+			winner = string(Player1)
+			if result.P2.Timestamp < result.P1.Timestamp {
+				winner = string(Player2)
+			}
+		}
+		g.complete(winner, EndCracked)
+	case g.Players[0].Solved:
+		g.complete(string(Player1), EndCracked)
+	case g.Players[1].Solved:
+		g.complete(string(Player2), EndCracked)
+	default:
+		g.Round++
+		g.startTurn(now)
+	}
+
+	result.Winner = g.Winner
+	g.LastRound = result
+}
+
+// expireRound applies the timeout rule to a simultaneous round. Players who
+// have not submitted either sit the round out or lose the game; if both run
+// out under forfeit_game the game is drawn.
+func (g *GameState) expireRound(now time.Time) bool {
+	var late []*PlayerState
+	for _, p := range g.Players {
+		if p.Pending == nil && g.playerDeadline(p) <= now.UnixMilli() {
+			late = append(late, p)
+		}
+	}
+	if len(late) == 0 {
+		return false
+	}
+
+	if g.Rules.Clock.OnTimeout != ForfeitGame {
+		g.resolveRound(now)
+		return true
+	}
+
+	winner := Draw
+	if len(late) == 1 {
+		winner = string(g.opponent(late[0].ID).ID)
+	}
+	for _, p := range late {
+		if g.Rules.Clock.Mode == ClockFischer {
+			p.TimeLeftMs = 0
+		}
+	}
+	g.Players[0].Pending, g.Players[1].Pending = nil, nil
+	g.complete(winner, EndTimeout)
+	g.LastRound = &RoundResult{Round: g.Round, Winner: g.Winner}
+	return true
+}
+
+// roundDeadline is the earliest deadline among players yet to submit.
+func (g *GameState) roundDeadline() int64 {
+	var deadline int64
+	for _, p := range g.Players {
+		if p.Pending != nil {
+			continue
+		}
+		if d := g.playerDeadline(p); deadline == 0 || d < deadline {
+			deadline = d
+		}
+	}
+	return deadline
+}
@@ -0,0 +1,303 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Alphabet string
+
+const (
+	AlphabetDigits  Alphabet = "digits"
+	AlphabetHex     Alphabet = "hex"
+	AlphabetLetters Alphabet = "letters"
+	AlphabetCustom  Alphabet = "custom"
+)
+
+// WinCondition decides what happens when a player cracks the opposing code.
+type WinCondition string
+
+const (
+	// WinFirstCrack ends the game as soon as either player cracks the code.
+	WinFirstCrack WinCondition = "first_crack"
+	// WinEqualTurns gives the player who moved second one last guess to
+	// equalise, in which case the game is drawn.
+	WinEqualTurns WinCondition = "equal_turns"
+)
+
+const (
+	MinCodeLength     = 3
+	MaxCodeLength     = 8
+	DefaultCodeLength = 4
+	maxCustomSymbols  = 36
+	MaxBestOf         = 7
+)
+
+var alphabetSymbols = map[Alphabet]string{
+	AlphabetDigits:  "0123456789",
+	AlphabetHex:     "0123456789ABCDEF",
+	AlphabetLetters: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+}
+
+type Rules struct {
+	Length          int          `json:"length"`
+	Alphabet        Alphabet     `json:"alphabet"`
+	Symbols         string       `json:"symbols"`
+	WinCondition    WinCondition `json:"winCondition"`
+	AllowDuplicates bool         `json:"allowDuplicates"`
+	// HardMode only accepts guesses that could still be the secret given
+	// the feedback the player has already received.
+	HardMode bool     `json:"hardMode"`
+	Clock    Clock    `json:"clock"`
+	TurnMode TurnMode `json:"turnMode"`
+	// Tiebreak only applies to simultaneous rounds.
+	Tiebreak Tiebreak `json:"tiebreak,omitempty"`
+	// BestOf plays the room as a series of up to that many games; 1 is a
+	// single game.
+	BestOf int `json:"bestOf"`
+	// Players is the number of seats. More than two makes the room a
+	// free-for-all.
+	Players int `json:"players"`
+	// TeamSize above 1 makes each side a team sharing one secret.
+	TeamSize      int           `json:"teamSize"`
+	SpectatorView SpectatorView `json:"spectatorView"`
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		Length:        DefaultCodeLength,
+		Alphabet:      AlphabetDigits,
+		Symbols:       alphabetSymbols[AlphabetDigits],
+		WinCondition:  WinFirstCrack,
+		TurnMode:      TurnAlternate,
+		BestOf:        1,
+		Players:       MinPlayers,
+		TeamSize:      1,
+		SpectatorView: SpectatorsOpen,
+	}
+}
+
+// Resolve fills in defaults for unset fields and checks that the ruleset is
+// playable. The returned Rules always carry the concrete symbol set.
+func (r Rules) Resolve() (Rules, error) {
+	if r.Length == 0 {
+		r.Length = DefaultCodeLength
+	}
+	if r.Length < MinCodeLength || r.Length > MaxCodeLength {
+		return r, fmt.Errorf("code length must be between %d and %d", MinCodeLength, MaxCodeLength)
+	}
+
+	if r.Alphabet == "" {
+		r.Alphabet = AlphabetDigits
+	}
+	switch r.Alphabet {
+	case AlphabetDigits, AlphabetHex, AlphabetLetters:
+		r.Symbols = alphabetSymbols[r.Alphabet]
+	case AlphabetCustom:
+		symbols, err := parseCustomSymbols(r.Symbols)
+		if err != nil {
+			return r, err
+		}
+		r.Symbols = symbols
+	default:
+		return r, fmt.Errorf("unknown alphabet %q", r.Alphabet)
+	}
+
+	if !r.AllowDuplicates && len([]rune(r.Symbols)) < r.Length {
+		return r, fmt.Errorf("alphabet has fewer than %d symbols", r.Length)
+	}
+
+	if r.WinCondition == "" {
+		r.WinCondition = WinFirstCrack
+	}
+	switch r.WinCondition {
+	case WinFirstCrack, WinEqualTurns:
+	default:
+		return r, fmt.Errorf("unknown win condition %q", r.WinCondition)
+	}
+
+	if r.TurnMode == "" {
+		r.TurnMode = TurnAlternate
+	}
+	switch r.TurnMode {
+	case TurnAlternate:
+		r.Tiebreak = ""
+	case TurnSimultaneous:
+		// Both players always have had the same number of guesses, so the
+		// equal turns rule has nothing to add.
+		r.WinCondition = WinFirstCrack
+		if r.Tiebreak == "" {
+			r.Tiebreak = TiebreakDraw
+		}
+		if r.Tiebreak != TiebreakDraw && r.Tiebreak != TiebreakTime {
+			return r, fmt.Errorf("unknown tiebreak %q", r.Tiebreak)
+		}
+	default:
+		return r, fmt.Errorf("unknown turn mode %q", r.TurnMode)
+	}
+
+	if r.BestOf == 0 {
+		r.BestOf = 1
+	}
+	if r.BestOf < 1 || r.BestOf > MaxBestOf || r.BestOf%2 == 0 {
+		return r, fmt.Errorf("best of must be an odd number of games up to %d", MaxBestOf)
+	}
+
+	if r.Players == 0 {
+		r.Players = MinPlayers
+	}
+	if r.Players < MinPlayers || r.Players > MaxPlayers {
+		return r, fmt.Errorf("rooms seat between %d and %d players", MinPlayers, MaxPlayers)
+	}
+	if r.Players > 2 {
+		switch {
+		case r.TurnMode == TurnSimultaneous:
+			return r, fmt.Errorf("free-for-all rooms take turns")
+		case r.WinCondition == WinEqualTurns:
+			return r, fmt.Errorf("free-for-all rooms end when one code is left standing")
+		case r.BestOf > 1:
+			return r, fmt.Errorf("free-for-all rooms play single games")
+		}
+	}
+
+	if r.TeamSize == 0 {
+		r.TeamSize = 1
+	}
+	if r.TeamSize < 1 || r.TeamSize > MaxTeamSize {
+		return r, fmt.Errorf("teams have between 1 and %d players", MaxTeamSize)
+	}
+	if r.TeamSize > 1 && r.Players != 2 {
+		return r, fmt.Errorf("team games are played between two teams")
+	}
+
+	// Spectators could pass secrets on to a team, so team rooms hide them
+	// unless asked not to.
+	if r.SpectatorView == "" {
+		r.SpectatorView = SpectatorsOpen
+		if r.TeamSize > 1 {
+			r.SpectatorView = SpectatorsHidden
+		}
+	}
+	if r.SpectatorView != SpectatorsOpen && r.SpectatorView != SpectatorsHidden {
+		return r, fmt.Errorf("unknown spectator view %q", r.SpectatorView)
+	}
+
+	clock, err := r.Clock.resolve()
+	if err != nil {
+		return r, err
+	}
+	r.Clock = clock
+
+	return r, nil
+}
+
+func parseCustomSymbols(symbols string) (string, error) {
+	seen := make(map[rune]bool)
+	var b strings.Builder
+	for _, r := range symbols {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			continue
+		}
+		if seen[r] {
+			return "", fmt.Errorf("custom alphabet repeats %q", r)
+		}
+		seen[r] = true
+		b.WriteRune(r)
+	}
+	if len(seen) < 2 {
+		return "", fmt.Errorf("custom alphabet needs at least 2 symbols")
+	}
+	if len(seen) > maxCustomSymbols {
+		return "", fmt.Errorf("custom alphabet allows at most %d symbols", maxCustomSymbols)
+	}
+	return b.String(), nil
+}
+
+// Normalize upper-cases input for the built-in alphabets so that "a1b2" and
+// "A1B2" are the same hex code. Custom alphabets are case sensitive.
+func (r Rules) Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	if r.Alphabet == AlphabetCustom {
+		return code
+	}
+	return strings.ToUpper(code)
+}
+
+// IsValidSecret reports whether code is a legal secret or guess under these
+// rules. Repeated symbols are only accepted when AllowDuplicates is set.
+func (r Rules) IsValidSecret(code string) bool {
+	runes := []rune(code)
+	if len(runes) != r.Length {
+		return false
+	}
+	seen := make(map[rune]bool)
+	for _, c := range runes {
+		if !strings.ContainsRune(r.Symbols, c) {
+			return false
+		}
+		if seen[c] && !r.AllowDuplicates {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+// Score returns the bulls and cows for guess against secret using the
+// counting method that matches the ruleset.
+func (r Rules) Score(guess, secret string) (int, int) {
+	if r.AllowDuplicates {
+		return CalculateBullsCowsMultiset(guess, secret)
+	}
+	return CalculateBullsCows(guess, secret)
+}
+
+// Describe returns a short human readable summary used in error messages,
+// e.g. "4 unique digits".
+func (r Rules) Describe() string {
+	var kind string
+	switch r.Alphabet {
+	case AlphabetDigits:
+		kind = "digits"
+	case AlphabetHex:
+		kind = "hex characters (0-9, A-F)"
+	case AlphabetLetters:
+		kind = "letters"
+	default:
+		kind = fmt.Sprintf("symbols from %q", r.Symbols)
+	}
+	if r.AllowDuplicates {
+		return fmt.Sprintf("%d %s", r.Length, kind)
+	}
+	return fmt.Sprintf("%d unique %s", r.Length, kind)
+}
+
+func (r Rules) String() string {
+	s := fmt.Sprintf("%d %s, %s", r.Length, r.Alphabet, r.WinCondition)
+	if r.TurnMode == TurnSimultaneous {
+		s += fmt.Sprintf(", simultaneous (%s tiebreak)", r.Tiebreak)
+	}
+	if r.AllowDuplicates {
+		s += ", duplicates"
+	}
+	if r.HardMode {
+		s += ", hard"
+	}
+	switch r.Clock.Mode {
+	case ClockPerMove:
+		s += fmt.Sprintf(", %ds/move", r.Clock.MoveSeconds)
+	case ClockFischer:
+		s += fmt.Sprintf(", %ds+%ds", r.Clock.BankSeconds, r.Clock.IncrementSeconds)
+	}
+	if r.BestOf > 1 {
+		s += fmt.Sprintf(", best of %d", r.BestOf)
+	}
+	if r.Players > 2 {
+		s += fmt.Sprintf(", %d-player free-for-all", r.Players)
+	}
+	if r.TeamSize > 1 {
+		s += fmt.Sprintf(", %dv%d", r.TeamSize, r.TeamSize)
+	}
+	return s
+}
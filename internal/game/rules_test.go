@@ -0,0 +1,129 @@
+package game
+
+import "testing"
+
+func TestCalculateBullsCowsMultiset(t *testing.T) {
+	tests := []struct {
+		name          string
+		guess, secret string
+		bulls, cows   int
+	}{
+		{"no repeats, exact", "1234", "1234", 4, 0},
+		{"no repeats, all cows", "1234", "4321", 0, 4},
+		{"no repeats, nothing", "1234", "5678", 0, 0},
+
+		// Repeats in the secret only.
+		{"secret repeats, guess hits once", "1234", "1111", 1, 0},
+		{"secret repeats, one cow for one copy in guess", "2341", "1155", 0, 1},
+		{"secret repeats, two bulls", "1213", "1112", 2, 1},
+		{"secret repeats, cows capped by guess", "5612", "1155", 0, 2},
+
+		// Repeats in the guess only.
+		{"guess repeats, one bull", "1111", "1234", 1, 0},
+		{"guess repeats, hits in place", "2222", "1234", 1, 0},
+		{"guess repeats, symbol absent", "7777", "1234", 0, 0},
+		{"guess repeats, cow capped by secret", "1122", "1234", 1, 1},
+		{"guess repeats, bull not also a cow", "4334", "1234", 2, 0},
+
+		// Repeats in both.
+		{"both repeat, swapped pairs", "1122", "2211", 0, 4},
+		{"both repeat, exact", "1122", "1122", 4, 0},
+		{"both repeat, partial overlap", "1112", "1222", 2, 0},
+		{"both repeat, more in guess", "1111", "1122", 2, 0},
+		{"both repeat, more in secret", "1122", "1111", 2, 0},
+		{"both repeat, cows only", "2211", "1321", 1, 2},
+		{"both repeat, letters", "AABB", "BBAA", 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bulls, cows := CalculateBullsCowsMultiset(tt.guess, tt.secret)
+			if bulls != tt.bulls || cows != tt.cows {
+				t.Errorf("CalculateBullsCowsMultiset(%q, %q) = %d, %d; want %d, %d",
+					tt.guess, tt.secret, bulls, cows, tt.bulls, tt.cows)
+			}
+		})
+	}
+}
+
+func TestRulesIsValidSecret(t *testing.T) {
+	unique := mustResolve(t, Rules{})
+	dups := mustResolve(t, Rules{AllowDuplicates: true})
+	hex := mustResolve(t, Rules{Alphabet: AlphabetHex, Length: 5})
+	custom := mustResolve(t, Rules{Alphabet: AlphabetCustom, Symbols: "ab", Length: 3, AllowDuplicates: true})
+
+	tests := []struct {
+		name  string
+		rules Rules
+		code  string
+		want  bool
+	}{
+		{"unique code", unique, "1234", true},
+		{"repeat rejected", unique, "1123", false},
+		{"all the same rejected", unique, "1111", false},
+		{"too short", unique, "123", false},
+		{"too long", unique, "12345", false},
+		{"outside alphabet", unique, "12a4", false},
+		{"empty", unique, "", false},
+
+		{"duplicates, unique code", dups, "1234", true},
+		{"duplicates, one repeat", dups, "1123", true},
+		{"duplicates, all the same", dups, "1111", true},
+		{"duplicates, still checks length", dups, "111", false},
+		{"duplicates, still checks alphabet", dups, "11x1", false},
+
+		{"hex", hex, "0A1B2", true},
+		{"hex, lower case not normalised", hex, "0a1b2", false},
+		{"hex, repeat rejected", hex, "AA123", false},
+
+		{"custom, repeats allowed", custom, "aab", true},
+		{"custom, outside alphabet", custom, "abc", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.IsValidSecret(tt.code); got != tt.want {
+				t.Errorf("IsValidSecret(%q) = %v; want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesScore(t *testing.T) {
+	unique := mustResolve(t, Rules{})
+	dups := mustResolve(t, Rules{AllowDuplicates: true})
+
+	tests := []struct {
+		name          string
+		rules         Rules
+		guess, secret string
+		bulls, cows   int
+	}{
+		{"unique", unique, "1234", "1243", 2, 2},
+		{"unique, nothing", unique, "1234", "5678", 0, 0},
+
+		// With duplicates allowed, Score must use multiset counting.
+		{"secret repeats", dups, "1234", "1111", 1, 0},
+		{"guess repeats", dups, "1111", "1234", 1, 0},
+		{"guess repeats, cow capped by secret", dups, "1122", "1234", 1, 1},
+		{"both repeat", dups, "1122", "2211", 0, 4},
+		{"both repeat, partial overlap", dups, "1112", "1222", 2, 0},
+		{"no repeats", dups, "1234", "4321", 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bulls, cows := tt.rules.Score(tt.guess, tt.secret)
+			if bulls != tt.bulls || cows != tt.cows {
+				t.Errorf("Score(%q, %q) = %d, %d; want %d, %d",
+					tt.guess, tt.secret, bulls, cows, tt.bulls, tt.cows)
+			}
+		})
+	}
+}
+
+func mustResolve(t *testing.T, r Rules) Rules {
+	t.Helper()
+	resolved, err := r.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve(%+v): %v", r, err)
+	}
+	return resolved
+}
@@ -0,0 +1,103 @@
+package game
+
+import "fmt"
+
+// Series tracks a best-of-N match played as consecutive games in one room.
+type Series struct {
+	BestOf int              `json:"bestOf"`
+	Game   int              `json:"game"`
+	Wins   map[PlayerID]int `json:"wins"`
+	Draws  int              `json:"draws"`
+	// FirstMover starts the current game; it alternates between games.
+	FirstMover PlayerID     `json:"firstMover"`
+	Results    []GameResult `json:"results"`
+	Winner     string       `json:"winner,omitempty"`
+}
+
+// GameResult is the outcome of one finished game in a series.
+type GameResult struct {
+	Game       int      `json:"game"`
+	FirstMover PlayerID `json:"firstMover"`
+	Winner     string   `json:"winner"`
+	EndReason  string   `json:"endReason"`
+}
+
+func newSeries(bestOf int) *Series {
+	if bestOf <= 1 {
+		return nil
+	}
+	return &Series{
+		BestOf:     bestOf,
+		Game:       1,
+		Wins:       map[PlayerID]int{Player1: 0, Player2: 0},
+		FirstMover: Player1,
+		Results:    []GameResult{},
+	}
+}
+
+// WinsNeeded is how many games a player must take to clinch the series.
+func (s *Series) WinsNeeded() int {
+	return s.BestOf/2 + 1
+}
+
+// Over reports whether the series has been decided.
+func (s *Series) Over() bool {
+	return s.Winner != ""
+}
+
+// record adds a finished game and decides the series once a player has
+// clinched it or every game has been played, in which case the player with
+// more wins takes it and a level score is a draw.
+func (s *Series) record(winner, reason string) {
+	s.Results = append(s.Results, GameResult{
+		Game:       s.Game,
+		FirstMover: s.FirstMover,
+		Winner:     winner,
+		EndReason:  reason,
+	})
+	if winner == Draw {
+		s.Draws++
+	} else {
+		s.Wins[PlayerID(winner)]++
+	}
+
+	p1, p2 := s.Wins[Player1], s.Wins[Player2]
+	switch {
+	case p1 >= s.WinsNeeded():
+		s.Winner = string(Player1)
+	case p2 >= s.WinsNeeded():
+		s.Winner = string(Player2)
+	case s.Game >= s.BestOf && p1 > p2:
+		s.Winner = string(Player1)
+	case s.Game >= s.BestOf && p2 > p1:
+		s.Winner = string(Player2)
+	case s.Game >= s.BestOf:
+		s.Winner = Draw
+	}
+}
+
+// next moves on to the following game, handing the first move to the other
+// player.
+func (s *Series) next() {
+	s.Game++
+	if s.FirstMover == Player1 {
+		s.FirstMover = Player2
+	} else {
+		s.FirstMover = Player1
+	}
+}
+
+// Score formats the running score as "P1-P2", e.g. "2-1".
+func (s *Series) Score() string {
+	return fmt.Sprintf("%d-%d", s.Wins[Player1], s.Wins[Player2])
+}
+
+func (s *Series) clone() *Series {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	c.Wins = map[PlayerID]int{Player1: s.Wins[Player1], Player2: s.Wins[Player2]}
+	c.Results = append([]GameResult{}, s.Results...)
+	return &c
+}
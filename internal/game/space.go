@@ -0,0 +1,37 @@
+package game
+
+import (
+	"math"
+	mrand "math/rand"
+)
+
+// SpaceSize is the number of distinct codes allowed by the rules. It is a
+// float64 because long codes with duplicates overflow int64 quickly.
+func (r Rules) SpaceSize() float64 {
+	n := float64(len([]rune(r.Symbols)))
+	if r.AllowDuplicates {
+		return math.Pow(n, float64(r.Length))
+	}
+	size := 1.0
+	for i := 0; i < r.Length; i++ {
+		size *= n - float64(i)
+	}
+	return size
+}
+
+// RandomCode returns a uniformly random legal code.
+func (r Rules) RandomCode(rng *mrand.Rand) string {
+	symbols := []rune(r.Symbols)
+	code := make([]rune, r.Length)
+	if r.AllowDuplicates {
+		for i := range code {
+			code[i] = symbols[rng.Intn(len(symbols))]
+		}
+		return string(code)
+	}
+	perm := rng.Perm(len(symbols))
+	for i := range code {
+		code[i] = symbols[perm[i]]
+	}
+	return string(code)
+}
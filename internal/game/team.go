@@ -0,0 +1,86 @@
+package game
+
+import "strings"
+
+// SpectatorView decides whether spectators see secrets while a game is in
+// progress.
+type SpectatorView string
+
+const (
+	SpectatorsOpen   SpectatorView = "open"
+	SpectatorsHidden SpectatorView = "hidden"
+)
+
+const MaxTeamSize = 4
+
+// IsTeamGame reports whether each side is a team sharing one secret.
+func (g *GameState) IsTeamGame() bool {
+	return g.Rules.TeamSize > 1
+}
+
+// joinTeam adds name to the side with the fewest members, filling the first
+// side on a tie.
+func (g *GameState) joinTeam(name string) (PlayerID, int, bool) {
+	var side *PlayerState
+	for _, p := range g.Players {
+		if len(p.Members) < g.Rules.TeamSize && (side == nil || len(p.Members) < len(side.Members)) {
+			side = p
+		}
+	}
+	if side == nil {
+		return "", 0, false
+	}
+	side.Members = append(side.Members, name)
+	side.syncName()
+	g.record(EventJoined, side.ID, name, nil)
+	if g.Full() {
+		g.Status = "setup"
+	}
+	return side.ID, len(side.Members) - 1, true
+}
+
+// RemoveMember takes member out of team pid, abandoning any game in progress
+// since the teams are no longer full. Teammates after member move up one
+// place. The last member of a team leaves through Vacate instead.
+func (g *GameState) RemoveMember(pid PlayerID, member int) {
+	side := g.Player(pid)
+	if side == nil || member < 0 || member >= len(side.Members) {
+		return
+	}
+	side.Members = append(side.Members[:member], side.Members[member+1:]...)
+	side.syncName()
+	g.Reset()
+}
+
+// CanGuessAs reports whether member of side pid may guess now. In team games
+// members take turns guessing for their side.
+func (g *GameState) CanGuessAs(pid PlayerID, member int) bool {
+	if !g.CanGuess(pid) {
+		return false
+	}
+	return !g.IsTeamGame() || g.Player(pid).Guesser == member
+}
+
+// GuesserName is who guesses next for side p.
+func (p *PlayerState) GuesserName() string {
+	if len(p.Members) == 0 {
+		return p.Name
+	}
+	return p.Members[p.Guesser%len(p.Members)]
+}
+
+// passGuesser hands the next guess to the following teammate.
+func (p *PlayerState) passGuesser() {
+	if len(p.Members) > 0 {
+		p.Guesser = (p.Guesser + 1) % len(p.Members)
+	}
+}
+
+func (p *PlayerState) syncName() {
+	p.Name = strings.Join(p.Members, " & ")
+	if len(p.Members) > 0 {
+		p.Guesser %= len(p.Members)
+	} else {
+		p.Guesser = 0
+	}
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// Store keeps the record of finished games and series. RecordGame keeps the
+// game's full transcript, which GetGame and GamesInRoom return; GetGame
+// returns ErrNotFound for an unknown ID. Both writes are keyed by the game's
+// ID, and recording the same game twice stores it once.
+type Store interface {
+	RecordGame(gs *game.GameState) error
+	RecordSeries(gs *game.GameState) error
+	GetRecentGames(limit int) ([]GameRecord, error)
+	GetGame(id string) (*Transcript, error)
+	GamesInRoom(roomCode string) ([]Transcript, error)
+	Close() error
+}
+
+type GameRecord struct {
+	ID        string `json:"id,omitempty"`
+	RoomCode  string `json:"roomCode,omitempty"`
+	Timestamp string `json:"timestamp"`
+	P1Name    string `json:"p1Name"`
+	P2Name    string `json:"p2Name"`
+	Winner    string `json:"winner"`
+	Rules     string `json:"rules,omitempty"`
+	EndReason string `json:"endReason,omitempty"`
+	Series    string `json:"series,omitempty"`
+}
+
+type SeriesRecord struct {
+	Timestamp string `json:"timestamp"`
+	P1Name    string `json:"p1Name"`
+	P2Name    string `json:"p2Name"`
+	Winner    string `json:"winner"`
+	Score     string `json:"score"`
+	Rules     string `json:"rules"`
+	Games     string `json:"games"`
+}
+
+// NewGameRecord summarises a finished game, stamped with the time it ended.
+// In a free-for-all everyone after the first player shares P2Name.
+func NewGameRecord(gs *game.GameState) GameRecord {
+	var series string
+	if gs.Series != nil {
+		series = fmt.Sprintf("Game %d of %d (%s)", gs.Series.Game, gs.Series.BestOf, gs.Series.Score())
+	}
+	var others []string
+	for _, p := range gs.Players[1:] {
+		others = append(others, p.Name)
+	}
+	return GameRecord{
+		ID:        gs.GameID,
+		RoomCode:  gs.RoomCode,
+		Timestamp: endTime(gs).UTC().Format(time.RFC3339),
+		P1Name:    gs.Players[0].Name,
+		P2Name:    strings.Join(others, ", "),
+		Winner:    WinnerName(gs, gs.Winner),
+		Rules:     gs.Rules.String(),
+		EndReason: gs.EndReason,
+		Series:    series,
+	}
+}
+
+// NewSeriesRecord summarises a decided series with the result of each game,
+// stamped with the time its last game ended.
+func NewSeriesRecord(gs *game.GameState) SeriesRecord {
+	series := gs.Series
+	games := make([]string, len(series.Results))
+	for i, r := range series.Results {
+		games[i] = fmt.Sprintf("%d: %s (%s)", r.Game, WinnerName(gs, r.Winner), r.EndReason)
+	}
+	return SeriesRecord{
+		Timestamp: endTime(gs).UTC().Format(time.RFC3339),
+		P1Name:    gs.Players[0].Name,
+		P2Name:    gs.Players[1].Name,
+		Winner:    WinnerName(gs, series.Winner),
+		Score:     series.Score(),
+		Rules:     gs.Rules.String(),
+		Games:     strings.Join(games, "; "),
+	}
+}
+
+// endTime is when gs ended, which can be long before the outbox delivers
+// its record.
+func endTime(gs *game.GameState) time.Time {
+	if gs.EndedAt == 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(gs.EndedAt)
+}
+
+// SortNewestFirst orders records by when their games ended. Records whose
+// timestamp does not parse go last, in the order they were given.
+func SortNewestFirst(records []GameRecord) {
+	at := func(r GameRecord) time.Time {
+		t, _ := time.Parse(time.RFC3339, r.Timestamp)
+		return t
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return at(records[i]).After(at(records[j]))
+	})
+}
+
+// SortTranscriptsNewestFirst orders transcripts by when their games ended.
+func SortTranscriptsNewestFirst(transcripts []Transcript) {
+	sort.SliceStable(transcripts, func(i, j int) bool {
+		return transcripts[i].EndedAt > transcripts[j].EndedAt
+	})
+}
+
+func WinnerName(gs *game.GameState, winner string) string {
+	if winner == game.Draw {
+		return "Draw"
+	}
+	if p := gs.Player(game.PlayerID(winner)); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+// Config picks a backend from HISTORY_BACKEND ("sheets", "sqlite", "jsonl"
+// or "none") and, for the local backends, the file in HISTORY_PATH. Records
+// wait in the outbox directory, HISTORY_OUTBOX, until the backend has them.
+type Config struct {
+	Backend string
+	Path    string
+	Outbox  string
+}
+
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Backend: strings.ToLower(os.Getenv("HISTORY_BACKEND")),
+		Path:    os.Getenv("HISTORY_PATH"),
+		Outbox:  os.Getenv("HISTORY_OUTBOX"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = "sheets"
+	}
+	if cfg.Outbox == "" {
+		cfg.Outbox = "outbox"
+	}
+	return cfg
+}
+
+// Open returns the configured local store. Sheets is opened by its own
+// package, so Open only handles the file-based backends and "none".
+func Open(cfg Config) (Store, error) {
+	path := cfg.Path
+	switch cfg.Backend {
+	case "sqlite":
+		if path == "" {
+			path = "history.db"
+		}
+		store, err := OpenSQLite(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "jsonl":
+		if path == "" {
+			path = "history.jsonl"
+		}
+		store, err := OpenJSONL(path)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown history backend %q", cfg.Backend)
+}
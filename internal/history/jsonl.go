@@ -0,0 +1,157 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// JSONLStore appends history to a file with one JSON object per line.
+type JSONLStore struct {
+	mu   sync.Mutex
+	file *os.File
+	// seen holds the key of every entry in the file, so a game recorded
+	// twice is only written once.
+	seen map[string]bool
+}
+
+type jsonlEntry struct {
+	Key        string        `json:"key,omitempty"`
+	Kind       string        `json:"kind"`
+	Game       *GameRecord   `json:"game,omitempty"`
+	Transcript *Transcript   `json:"transcript,omitempty"`
+	Series     *SeriesRecord `json:"series,omitempty"`
+}
+
+func OpenJSONL(path string) (*JSONLStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %v", path, err)
+	}
+	s := &JSONLStore{file: f, seen: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry jsonlEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Key != "" {
+			s.seen[entry.Key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *JSONLStore) append(entry jsonlEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[entry.Key] {
+		return nil
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.seen[entry.Key] = true
+	return nil
+}
+
+func (s *JSONLStore) RecordGame(gs *game.GameState) error {
+	r := NewGameRecord(gs)
+	t := NewTranscript(gs)
+	return s.append(jsonlEntry{Key: "game-" + gs.GameID, Kind: "game", Game: &r, Transcript: &t})
+}
+
+func (s *JSONLStore) RecordSeries(gs *game.GameState) error {
+	if gs.Series == nil || !gs.Series.Over() {
+		return nil
+	}
+	r := NewSeriesRecord(gs)
+	return s.append(jsonlEntry{Key: "series-" + gs.GameID, Kind: "series", Series: &r})
+}
+
+// games reads every game entry in the file, oldest first.
+func (s *JSONLStore) games() ([]jsonlEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read history: %v", err)
+	}
+	defer f.Close()
+
+	var games []jsonlEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry jsonlEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Game == nil {
+			continue
+		}
+		games = append(games, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read history: %v", err)
+	}
+	return games, nil
+}
+
+func (s *JSONLStore) GetRecentGames(limit int) ([]GameRecord, error) {
+	games, err := s.games()
+	if err != nil {
+		return nil, err
+	}
+	var records []GameRecord
+	for i := len(games) - 1; i >= 0; i-- {
+		records = append(records, *games[i].Game)
+	}
+	SortNewestFirst(records)
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+func (s *JSONLStore) GetGame(id string) (*Transcript, error) {
+	games, err := s.games()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range games {
+		if e.Transcript != nil && e.Transcript.ID == id {
+			return e.Transcript, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *JSONLStore) GamesInRoom(roomCode string) ([]Transcript, error) {
+	games, err := s.games()
+	if err != nil {
+		return nil, err
+	}
+	var transcripts []Transcript
+	for i := len(games) - 1; i >= 0; i-- {
+		if t := games[i].Transcript; t != nil && t.RoomCode == roomCode {
+			transcripts = append(transcripts, *t)
+		}
+	}
+	SortTranscriptsNewestFirst(transcripts)
+	return transcripts, nil
+}
+
+func (s *JSONLStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,284 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBaseBackoff  = 2 * time.Second
+	outboxMaxBackoff   = 10 * time.Minute
+	// outboxMaxAttempts is how many deliveries are tried before a record is
+	// moved to the dead-letter list.
+	outboxMaxAttempts = 12
+)
+
+// OutboxEntry is one record waiting to be written to the history backend.
+// Key is its idempotency key, so a retried delivery is never stored twice.
+type OutboxEntry struct {
+	Key         string          `json:"key"`
+	Kind        string          `json:"kind"`
+	RoomCode    string          `json:"roomCode"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+	Game        *game.GameState `json:"game,omitempty"`
+	Log         []game.Event    `json:"log,omitempty"`
+}
+
+// Outbox is a Store that saves finished games to disk before anything else,
+// then delivers them to the real backend in the background, retrying with
+// exponential backoff. Records that keep failing go to a dead-letter list.
+// Reads go straight to the backend.
+type Outbox struct {
+	backend Store
+	pending string
+	dead    string
+	mu      sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func NewOutbox(dir string, backend Store) (*Outbox, error) {
+	o, err := newOutbox(dir, backend)
+	if err != nil {
+		return nil, err
+	}
+	go o.run()
+	return o, nil
+}
+
+// newOutbox opens the outbox in dir without starting delivery.
+func newOutbox(dir string, backend Store) (*Outbox, error) {
+	o := &Outbox{
+		backend: backend,
+		pending: filepath.Join(dir, "pending"),
+		dead:    filepath.Join(dir, "dead"),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, d := range []string{o.pending, o.dead} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create outbox: %v", err)
+		}
+	}
+	return o, nil
+}
+
+func (o *Outbox) RecordGame(gs *game.GameState) error {
+	return o.enqueue("game", gs)
+}
+
+func (o *Outbox) RecordSeries(gs *game.GameState) error {
+	if gs.Series == nil || !gs.Series.Over() {
+		return nil
+	}
+	return o.enqueue("series", gs)
+}
+
+func (o *Outbox) GetRecentGames(limit int) ([]GameRecord, error) {
+	return o.backend.GetRecentGames(limit)
+}
+
+func (o *Outbox) GetGame(id string) (*Transcript, error) {
+	return o.backend.GetGame(id)
+}
+
+func (o *Outbox) GamesInRoom(roomCode string) ([]Transcript, error) {
+	return o.backend.GamesInRoom(roomCode)
+}
+
+// Close stops delivery; anything still pending is sent after a restart.
+func (o *Outbox) Close() error {
+	close(o.stop)
+	<-o.stopped
+	return o.backend.Close()
+}
+
+// Pending lists the records still to be delivered, oldest first.
+func (o *Outbox) Pending() ([]OutboxEntry, error) {
+	return o.list(o.pending)
+}
+
+// Dead lists the records that ran out of attempts.
+func (o *Outbox) Dead() ([]OutboxEntry, error) {
+	return o.list(o.dead)
+}
+
+func (o *Outbox) enqueue(kind string, gs *game.GameState) error {
+	now := time.Now()
+	entry := OutboxEntry{
+		Key:         kind + "-" + gs.GameID,
+		Kind:        kind,
+		RoomCode:    gs.RoomCode,
+		CreatedAt:   now,
+		NextAttempt: now,
+		Game:        gs,
+		Log:         gs.Log,
+	}
+	o.mu.Lock()
+	err := writeEntry(o.pending, &entry)
+	o.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (o *Outbox) run() {
+	defer close(o.stopped)
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		o.deliverDue(time.Now())
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *Outbox) deliverDue(now time.Time) {
+	entries, err := o.list(o.pending)
+	if err != nil {
+		slog.Error("failed to read history outbox", "error", err)
+		return
+	}
+	for _, e := range entries {
+		if e.NextAttempt.After(now) {
+			continue
+		}
+		select {
+		case <-o.stop:
+			return
+		default:
+		}
+		o.deliver(e)
+	}
+}
+
+func (o *Outbox) deliver(e OutboxEntry) {
+	gs := e.Game
+	gs.Log = e.Log
+	var err error
+	switch e.Kind {
+	case "game":
+		err = o.backend.RecordGame(gs)
+	case "series":
+		err = o.backend.RecordSeries(gs)
+	default:
+		err = fmt.Errorf("unknown record kind %q", e.Kind)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err == nil {
+		os.Remove(entryPath(o.pending, e.Key))
+		slog.Info("delivered history record", "key", e.Key, "attempts", e.Attempts+1)
+		return
+	}
+
+	e.Attempts++
+	e.LastError = err.Error()
+	if e.Attempts >= outboxMaxAttempts {
+		slog.Error("giving up on history record", "key", e.Key, "error", err)
+		if werr := writeEntry(o.dead, &e); werr == nil {
+			os.Remove(entryPath(o.pending, e.Key))
+		}
+		return
+	}
+	e.NextAttempt = time.Now().Add(outboxBackoff(e.Attempts))
+	slog.Warn("failed to deliver history record", "key", e.Key, "attempt", e.Attempts, "retry", e.NextAttempt, "error", err)
+	writeEntry(o.pending, &e)
+}
+
+// outboxBackoff doubles the wait after each failed attempt.
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxBaseBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, outboxMaxBackoff)
+}
+
+func (o *Outbox) list(dir string) ([]OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := []OutboxEntry{}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var e OutboxEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			slog.Warn("skipping unreadable outbox entry", "file", f.Name(), "error", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+func entryPath(dir, key string) string {
+	return filepath.Join(dir, key+".json")
+}
+
+// writeEntry saves e through a temporary file so a crash never leaves a
+// half-written record behind.
+func writeEntry(dir string, e *OutboxEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), entryPath(dir, e.Key))
+}
@@ -0,0 +1,178 @@
+package history
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// flakyStore fails its first failures deliveries and then keeps what it is
+// sent.
+type flakyStore struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	games    []*game.GameState
+}
+
+func (s *flakyStore) RecordGame(gs *game.GameState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("backend down")
+	}
+	s.games = append(s.games, gs)
+	return nil
+}
+
+func (s *flakyStore) RecordSeries(gs *game.GameState) error             { return nil }
+func (s *flakyStore) GetRecentGames(limit int) ([]GameRecord, error)    { return nil, nil }
+func (s *flakyStore) GetGame(id string) (*Transcript, error)            { return nil, ErrNotFound }
+func (s *flakyStore) GamesInRoom(roomCode string) ([]Transcript, error) { return nil, nil }
+func (s *flakyStore) Close() error                                      { return nil }
+
+func (s *flakyStore) delivered() []*game.GameState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*game.GameState(nil), s.games...)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{9, 512 * time.Second},
+		{10, outboxMaxBackoff},
+		{outboxMaxAttempts, outboxMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v; want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestOutboxRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		// attempts is how many deliveries are made, and dead whether the
+		// record ends up dead-lettered rather than delivered.
+		attempts int
+		dead     bool
+	}{
+		{"delivered first time", 0, 1, false},
+		{"delivered after failures", 3, 4, false},
+		{"delivered on the last attempt", outboxMaxAttempts - 1, outboxMaxAttempts, false},
+		{"dead-lettered", outboxMaxAttempts, outboxMaxAttempts, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &flakyStore{failures: tt.failures}
+			o, err := newOutbox(t.TempDir(), store)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.RecordGame(finishedGame("g1")); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 1; i <= tt.attempts; i++ {
+				before := time.Now()
+				o.deliverDue(before.Add(time.Hour))
+				pending := mustList(t, o.Pending)
+				if i == tt.attempts {
+					break
+				}
+				if len(pending) != 1 || pending[0].Attempts != i || pending[0].LastError == "" {
+					t.Fatalf("after %d failures pending = %+v", i, pending)
+				}
+				// The retry waits out the backoff.
+				wait := pending[0].NextAttempt.Sub(before)
+				if wait < outboxBackoff(i) || wait > outboxBackoff(i)+time.Minute {
+					t.Errorf("retry %d waits %v; want %v", i, wait, outboxBackoff(i))
+				}
+				o.deliverDue(time.Now())
+				if store.calls != i {
+					t.Fatalf("retried before the backoff ran out")
+				}
+			}
+
+			if pending := mustList(t, o.Pending); len(pending) != 0 {
+				t.Errorf("%d records still pending", len(pending))
+			}
+			dead := mustList(t, o.Dead)
+			delivered := store.delivered()
+			if tt.dead {
+				if len(dead) != 1 || dead[0].Attempts != outboxMaxAttempts || len(delivered) != 0 {
+					t.Errorf("dead = %+v, delivered %d; want one dead record", dead, len(delivered))
+				}
+				return
+			}
+			if len(dead) != 0 || len(delivered) != 1 || delivered[0].GameID != "g1" {
+				t.Errorf("dead = %+v, delivered %d; want g1 delivered", dead, len(delivered))
+			}
+		})
+	}
+}
+
+func TestOutboxReloadsPending(t *testing.T) {
+	dir := t.TempDir()
+	down := &flakyStore{failures: outboxMaxAttempts}
+	o, err := newOutbox(dir, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := finishedGame("g1")
+	if err := o.RecordGame(gs); err != nil {
+		t.Fatal(err)
+	}
+	o.deliverDue(time.Now())
+
+	// A fresh outbox over the same directory, as after a restart, delivers
+	// the record once its retry is due.
+	store := &flakyStore{}
+	o, err = newOutbox(dir, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := mustList(t, o.Pending); len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("reloaded pending = %+v; want the one failed record", pending)
+	}
+	o.deliverDue(time.Now().Add(time.Hour))
+	delivered := store.delivered()
+	if len(delivered) != 1 {
+		t.Fatalf("%d delivered after the restart; want 1", len(delivered))
+	}
+	if got := delivered[0]; got.GameID != gs.GameID || got.EndedAt != gs.EndedAt || len(got.Log) != len(gs.Log) {
+		t.Errorf("delivered %s ended %d with %d events; want %s ended %d with %d",
+			got.GameID, got.EndedAt, len(got.Log), gs.GameID, gs.EndedAt, len(gs.Log))
+	}
+}
+
+func finishedGame(id string) *game.GameState {
+	gs := game.NewGame("ROOM", game.DefaultRules())
+	gs.Join("alice")
+	gs.Join("bob")
+	gs.GameID = id
+	gs.Status = "completed"
+	gs.EndedAt = time.Now().Add(-time.Hour).UnixMilli()
+	return gs
+}
+
+func mustList(t *testing.T, list func() ([]OutboxEntry, error)) []OutboxEntry {
+	t.Helper()
+	entries, err := list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
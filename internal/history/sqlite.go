@@ -0,0 +1,149 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adimail/colosseum/internal/game"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS games (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	game_id    TEXT NOT NULL,
+	room_code  TEXT NOT NULL,
+	timestamp  TEXT NOT NULL,
+	p1_name    TEXT NOT NULL,
+	p2_name    TEXT NOT NULL,
+	winner     TEXT NOT NULL,
+	rules      TEXT NOT NULL,
+	end_reason TEXT NOT NULL,
+	series     TEXT NOT NULL,
+	transcript TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS games_game_id ON games (game_id);
+CREATE INDEX IF NOT EXISTS games_room_code ON games (room_code);
+CREATE INDEX IF NOT EXISTS games_timestamp ON games (timestamp);
+CREATE TABLE IF NOT EXISTS series (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	game_id   TEXT NOT NULL UNIQUE,
+	timestamp TEXT NOT NULL,
+	p1_name   TEXT NOT NULL,
+	p2_name   TEXT NOT NULL,
+	winner    TEXT NOT NULL,
+	score     TEXT NOT NULL,
+	rules     TEXT NOT NULL,
+	games     TEXT NOT NULL
+);`
+
+// SQLiteStore keeps history in a local SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %v", path, err)
+	}
+	// SQLite allows one writer at a time.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to create history tables: %v", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) RecordGame(gs *game.GameState) error {
+	r := NewGameRecord(gs)
+	transcript, err := json.Marshal(NewTranscript(gs))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO games (game_id, room_code, timestamp, p1_name, p2_name, winner, rules, end_reason, series, transcript)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (game_id) DO NOTHING`,
+		r.ID, r.RoomCode, r.Timestamp, r.P1Name, r.P2Name, r.Winner, r.Rules, r.EndReason, r.Series, string(transcript),
+	)
+	return err
+}
+
+func (s *SQLiteStore) RecordSeries(gs *game.GameState) error {
+	if gs.Series == nil || !gs.Series.Over() {
+		return nil
+	}
+	r := NewSeriesRecord(gs)
+	_, err := s.db.Exec(
+		`INSERT INTO series (game_id, timestamp, p1_name, p2_name, winner, score, rules, games)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (game_id) DO NOTHING`,
+		gs.GameID, r.Timestamp, r.P1Name, r.P2Name, r.Winner, r.Score, r.Rules, r.Games,
+	)
+	return err
+}
+
+func (s *SQLiteStore) GetRecentGames(limit int) ([]GameRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT game_id, room_code, timestamp, p1_name, p2_name, winner, rules, end_reason, series
+		 FROM games ORDER BY timestamp DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query games: %v", err)
+	}
+	defer rows.Close()
+
+	var records []GameRecord
+	for rows.Next() {
+		var r GameRecord
+		if err := rows.Scan(&r.ID, &r.RoomCode, &r.Timestamp, &r.P1Name, &r.P2Name, &r.Winner, &r.Rules, &r.EndReason, &r.Series); err != nil {
+			return nil, fmt.Errorf("unable to read game: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLiteStore) GetGame(id string) (*Transcript, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT transcript FROM games WHERE game_id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to query game: %v", err)
+	}
+	var t Transcript
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		return nil, fmt.Errorf("unable to decode transcript: %v", err)
+	}
+	return &t, nil
+}
+
+func (s *SQLiteStore) GamesInRoom(roomCode string) ([]Transcript, error) {
+	rows, err := s.db.Query(`SELECT transcript FROM games WHERE room_code = ? ORDER BY timestamp DESC, id DESC`, roomCode)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query games: %v", err)
+	}
+	defer rows.Close()
+
+	var transcripts []Transcript
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("unable to read game: %v", err)
+		}
+		var t Transcript
+		if err := json.Unmarshal([]byte(data), &t); err != nil {
+			return nil, fmt.Errorf("unable to decode transcript: %v", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
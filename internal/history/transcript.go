@@ -0,0 +1,79 @@
+package history
+
+import (
+	"errors"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+var ErrNotFound = errors.New("game not found")
+
+// Transcript is the full record of one finished game: enough to replay it or
+// settle a dispute about it.
+type Transcript struct {
+	ID         string             `json:"id"`
+	RoomCode   string             `json:"roomCode"`
+	Rules      game.Rules         `json:"rules"`
+	Players    []TranscriptPlayer `json:"players"`
+	FirstMover game.PlayerID      `json:"firstMover"`
+	StartedAt  int64              `json:"startedAt"`
+	EndedAt    int64              `json:"endedAt"`
+	DurationMs int64              `json:"durationMs"`
+	// Turns counts the guesses made by every player.
+	Turns      int    `json:"turns"`
+	Winner     string `json:"winner"`
+	WinnerName string `json:"winnerName"`
+	EndReason  string `json:"endReason"`
+	SeriesGame int    `json:"seriesGame,omitempty"`
+	BestOf     int    `json:"bestOf,omitempty"`
+	// Events is the game's log, in order, for replays.
+	Events []game.Event `json:"events,omitempty"`
+}
+
+// TranscriptPlayer is one side of a game with its guesses in the order they
+// were made.
+type TranscriptPlayer struct {
+	ID      game.PlayerID `json:"id"`
+	Name    string        `json:"name"`
+	Members []string      `json:"members,omitempty"`
+	Secret  string        `json:"secret"`
+	Guesses []game.Guess  `json:"guesses"`
+}
+
+func NewTranscript(gs *game.GameState) Transcript {
+	t := Transcript{
+		ID:         gs.GameID,
+		RoomCode:   gs.RoomCode,
+		Rules:      gs.Rules,
+		FirstMover: gs.FirstMover(),
+		StartedAt:  gs.StartedAt,
+		EndedAt:    gs.EndedAt,
+		Winner:     gs.Winner,
+		WinnerName: WinnerName(gs, gs.Winner),
+		EndReason:  gs.EndReason,
+		Events:     gs.Log,
+	}
+	if gs.StartedAt != 0 && gs.EndedAt >= gs.StartedAt {
+		t.DurationMs = gs.EndedAt - gs.StartedAt
+	}
+	if gs.Series != nil {
+		t.SeriesGame = gs.Series.Game
+		t.BestOf = gs.Series.BestOf
+	}
+	for _, p := range gs.Players {
+		// Guesses are kept newest first during play.
+		guesses := make([]game.Guess, len(p.Guesses))
+		for i, g := range p.Guesses {
+			guesses[len(guesses)-1-i] = g
+		}
+		t.Players = append(t.Players, TranscriptPlayer{
+			ID:      p.ID,
+			Name:    p.Name,
+			Members: p.Members,
+			Secret:  p.Secret,
+			Guesses: guesses,
+		})
+		t.Turns += len(guesses)
+	}
+	return t
+}
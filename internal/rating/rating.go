@@ -0,0 +1,279 @@
+package rating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Initial = 1500
+	// Players are provisional for their first games and move faster until
+	// their rating has settled.
+	provisionalGames = 30
+	kProvisional     = 40
+	kEstablished     = 20
+)
+
+// Player is someone taking part in a rated game. UserID is their account's,
+// or empty for a guest, who is known by name alone.
+type Player struct {
+	UserID string
+	Name   string
+}
+
+// key keeps a registered player's rating with their account and a guest's
+// with their name, case-insensitively so "Alice" and "alice" share one.
+func (p Player) key() string {
+	if p.UserID != "" {
+		return "user:" + p.UserID
+	}
+	return "guest:" + strings.ToLower(strings.TrimSpace(p.Name))
+}
+
+// fresh is the rating of a player who has never played.
+func (p Player) fresh() *Rating {
+	return &Rating{UserID: p.UserID, Name: p.Name, Rating: Initial}
+}
+
+// Rating is one player's Elo rating and record.
+type Rating struct {
+	UserID    string    `json:"userId,omitempty"`
+	Name      string    `json:"name"`
+	Rating    float64   `json:"rating"`
+	Games     int       `json:"games"`
+	Wins      int       `json:"wins"`
+	Losses    int       `json:"losses"`
+	Draws     int       `json:"draws"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Change is how one game moved a player's rating.
+type Change struct {
+	Name   string  `json:"name"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+func (c Change) Delta() float64 {
+	return c.After - c.Before
+}
+
+// Store keeps every player's rating in memory and saves the lot to a JSON
+// file in the background after each rated game, so recording a game never
+// waits on the disk. Games recorded in quick succession are saved together.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	ratings map[string]*Rating
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		ratings: make(map[string]*Rating),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		go s.write()
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ratings: %v", err)
+	}
+
+	var list []*Rating
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unable to parse ratings: %v", err)
+	}
+	for _, r := range list {
+		s.ratings[r.player().key()] = r
+	}
+	go s.write()
+	return s, nil
+}
+
+func (r *Rating) player() Player {
+	return Player{UserID: r.UserID, Name: r.Name}
+}
+
+// Get returns p's rating, or a fresh one if they have never played.
+func (s *Store) Get(p Player) Rating {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.ratings[p.key()]; ok {
+		return *r
+	}
+	return *p.fresh()
+}
+
+// All returns every rating, highest first.
+func (s *Store) All() []Rating {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Rating, 0, len(s.ratings))
+	for _, r := range s.ratings {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Rating != list[j].Rating {
+			return list[i].Rating > list[j].Rating
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Record rates a game between two sides, each one or more players, where
+// score is side a's result: 1 for a win, 0.5 for a draw and 0 for a loss.
+// A team plays at its members' average rating and every member moves by the
+// same amount.
+func (s *Store) Record(a, b []Player, score float64) []Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sideA, sideB := s.side(a), s.side(b)
+	ra, rb := average(sideA), average(sideB)
+	expected := 1 / (1 + math.Pow(10, (rb-ra)/400))
+
+	var changes []Change
+	for _, side := range []struct {
+		players []*Rating
+		score   float64
+		delta   float64
+	}{
+		{sideA, score, score - expected},
+		{sideB, 1 - score, expected - score},
+	} {
+		for _, r := range side.players {
+			c := Change{Name: r.Name, Before: r.Rating}
+			r.Rating = math.Round((r.Rating+kFactor(r)*side.delta)*10) / 10
+			r.Games++
+			switch side.score {
+			case 1:
+				r.Wins++
+			case 0:
+				r.Losses++
+			default:
+				r.Draws++
+			}
+			r.UpdatedAt = now
+			c.After = r.Rating
+			changes = append(changes, c)
+		}
+	}
+	s.saveLater()
+	return changes
+}
+
+// saveLater has the writer save the ratings. The caller must hold s.mu.
+func (s *Store) saveLater() {
+	if s.closed {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close saves whatever has not been saved yet. Games recorded after Close
+// are rated but not saved.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.wake)
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *Store) write() {
+	defer close(s.done)
+	for range s.wake {
+		if err := s.save(); err != nil {
+			slog.Error("failed to save ratings", "path", s.path, "error", err)
+		}
+	}
+}
+
+// side looks up, creating if need be, the ratings of a side's players.
+func (s *Store) side(side []Player) []*Rating {
+	players := make([]*Rating, 0, len(side))
+	for _, p := range side {
+		r, ok := s.ratings[p.key()]
+		if !ok {
+			r = p.fresh()
+			s.ratings[p.key()] = r
+		}
+		players = append(players, r)
+	}
+	return players
+}
+
+func average(players []*Rating) float64 {
+	if len(players) == 0 {
+		return Initial
+	}
+	var sum float64
+	for _, r := range players {
+		sum += r.Rating
+	}
+	return sum / float64(len(players))
+}
+
+func kFactor(r *Rating) float64 {
+	if r.Games < provisionalGames {
+		return kProvisional
+	}
+	return kEstablished
+}
+
+// save writes the ratings through a temporary file so a crash never leaves
+// the file half written.
+func (s *Store) save() error {
+	s.mu.Lock()
+	list := make([]*Rating, 0, len(s.ratings))
+	for _, r := range s.ratings {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".ratings-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
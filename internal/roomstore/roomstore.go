@@ -0,0 +1,139 @@
+// Package roomstore keeps a snapshot of every open room on disk so that
+// rooms outlive a restart of the server. Snapshots are written in the
+// background, only the latest of each room's snapshots reaching the disk,
+// so saving never waits on it.
+package roomstore
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const ext = ".json"
+
+type Store struct {
+	dir string
+
+	mu sync.Mutex
+	// pending holds the latest snapshot of each room not yet written, or
+	// nil for a room whose snapshot is to be removed.
+	pending map[string][]byte
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+// Open keeps snapshots in dir. Snapshots hold room password hashes and
+// invite secrets, so only the server's own user may read them.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create %s: %v", dir, err)
+	}
+	s := &Store{
+		dir:     dir,
+		pending: make(map[string][]byte),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go s.write()
+	return s, nil
+}
+
+// Load returns every snapshot on disk by room code.
+func (s *Store) Load() (map[string][]byte, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", s.dir, err)
+	}
+	snapshots := make(map[string][]byte)
+	for _, e := range entries {
+		code, ok := strings.CutSuffix(e.Name(), ext)
+		if e.IsDir() || !ok {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			slog.Warn("skipping unreadable room snapshot", "file", e.Name(), "error", err)
+			continue
+		}
+		snapshots[code] = data
+	}
+	return snapshots, nil
+}
+
+// Save replaces the room's snapshot.
+func (s *Store) Save(code string, data []byte) {
+	s.queue(code, data)
+}
+
+// Delete removes the room's snapshot.
+func (s *Store) Delete(code string) {
+	s.queue(code, nil)
+}
+
+func (s *Store) queue(code string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.pending[code] = data
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close writes out whatever is still pending. Snapshots saved after Close
+// are dropped.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.wake)
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *Store) write() {
+	defer close(s.done)
+	for range s.wake {
+		s.flush()
+	}
+	s.flush()
+}
+
+func (s *Store) flush() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string][]byte)
+	s.mu.Unlock()
+
+	for code, data := range batch {
+		path := filepath.Join(s.dir, code+ext)
+		if data == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				slog.Warn("could not remove room snapshot", "code", code, "error", err)
+			}
+			continue
+		}
+		// Writing beside the snapshot and renaming over it means a crash
+		// mid-write leaves the previous snapshot intact.
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			slog.Warn("could not save room snapshot", "code", code, "error", err)
+			continue
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			slog.Warn("could not save room snapshot", "code", code, "error", err)
+		}
+	}
+}
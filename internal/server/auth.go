@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/adimail/colosseum/internal/auth"
+)
+
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	creds, ok := s.readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := s.Accounts.Register(creds.Username, creds.Password)
+	switch {
+	case errors.Is(err, auth.ErrUsernameTaken):
+		http.Error(w, "That username is already taken", http.StatusConflict)
+		return
+	case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrInvalidPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.Error("failed to register account", "error", err)
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	s.Accounts.SetSession(w, r, user)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	creds, ok := s.readCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := s.Accounts.Login(creds.Username, creds.Password)
+	var backoff *auth.BackoffError
+	if errors.As(err, &backoff) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(backoff.RetryAfter.Seconds()))))
+		http.Error(w, "Too many failed logins. Try again later.", http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+
+	s.Accounts.SetSession(w, r, user)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if s.Accounts != nil {
+		s.Accounts.ClearSession(w, r)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if s.Accounts == nil {
+		http.Error(w, "Accounts are not available", http.StatusServiceUnavailable)
+		return
+	}
+	user, ok := s.Accounts.SessionUser(r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// readCredentials decodes a username and password posted as JSON, writing
+// the error response itself when it cannot.
+func (s *Server) readCredentials(w http.ResponseWriter, r *http.Request) (credentials, bool) {
+	var creds credentials
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return creds, false
+	}
+	if s.Accounts == nil {
+		http.Error(w, "Accounts are not available", http.StatusServiceUnavailable)
+		return creds, false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&creds); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return creds, false
+	}
+	return creds, true
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adimail/colosseum/internal/backplane"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	clusterNodes = 3
+	readTimeout  = 3 * time.Second
+)
+
+// TestCluster runs several servers against one backplane and plays a game
+// across them: alice opens a room on the first node, bob joins it from the
+// second and carol watches from the third, while dave sees it come and go in
+// the lobby of the second. Bob then drops and resumes from the first node.
+func TestCluster(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		shared := backplane.NewMemory()
+		t.Cleanup(func() { shared.Close() })
+		testCluster(t, func() (backplane.Backplane, error) { return shared, nil })
+	})
+	t.Run("redis", func(t *testing.T) {
+		url := os.Getenv("REDIS_URL")
+		if url == "" {
+			t.Skip("REDIS_URL is not set")
+		}
+		testCluster(t, func() (backplane.Backplane, error) { return backplane.DialRedis(url) })
+	})
+}
+
+func testCluster(t *testing.T, connect func() (backplane.Backplane, error)) {
+	var urls []string
+	for i := 0; i < clusterNodes; i++ {
+		bp, err := connect()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { bp.Close() })
+		srv := NewServer(":0", "./dist", nil, nil, nil, nil, nil)
+		if err := srv.Hub.UseBackplane(bp, fmt.Sprintf("node-%d-%d", i, time.Now().UnixNano())); err != nil {
+			t.Fatal(err)
+		}
+		ts := httptest.NewServer(srv.Router)
+		t.Cleanup(ts.Close)
+		urls = append(urls, ts.URL)
+	}
+
+	dave := dial(t, urls[1], "dave")
+	dave.send("subscribe_lobby", nil)
+	if _, err := dave.next("lobby"); err != nil {
+		t.Fatalf("dave watching the lobby: %v", err)
+	}
+
+	alice := dial(t, urls[0], "alice")
+	alice.send("create_room", map[string]string{"name": "alice"})
+	state, err := alice.state(nil)
+	if err != nil {
+		t.Fatalf("alice creating the room: %v", err)
+	}
+	code, _ := state["roomCode"].(string)
+
+	if _, err := dave.next("room_created"); err != nil {
+		t.Fatalf("dave seeing the room in another node's lobby: %v", err)
+	}
+	var rooms []map[string]interface{}
+	if err := getJSON(urls[2]+"/api/rooms", &rooms); err != nil {
+		t.Fatalf("listing rooms from another node: %v", err)
+	}
+	if len(rooms) != 1 || rooms[0]["roomCode"] != code {
+		t.Fatalf("rooms listed on another node = %v, want %s", rooms, code)
+	}
+
+	var info map[string]interface{}
+	if err := getJSON(urls[1]+"/api/room/"+code, &info); err != nil {
+		t.Fatalf("looking the room up from another node: %v", err)
+	}
+	if info["ownerName"] != "alice" {
+		t.Fatalf("room looked up from another node has owner %v", info["ownerName"])
+	}
+
+	bob := dial(t, urls[1], "bob")
+	bob.send("join_room", map[string]string{"name": "bob", "code": code})
+	session, err := bob.next("session")
+	if err != nil {
+		t.Fatalf("bob joining from another node: %v", err)
+	}
+	if _, err := alice.state(status("setup")); err != nil {
+		t.Fatalf("alice seeing bob join: %v", err)
+	}
+
+	carol := dial(t, urls[2], "carol")
+	carol.send("spectate", map[string]string{"code": code})
+	if _, err := carol.state(func(m map[string]interface{}) bool { return m["role"] == "spectator" }); err != nil {
+		t.Fatalf("carol spectating from a third node: %v", err)
+	}
+
+	bob.send("chat", map[string]string{"channel": "players", "text": "ave"})
+	if _, err := alice.next("chat"); err != nil {
+		t.Fatalf("alice hearing bob's chat: %v", err)
+	}
+
+	// Bob drops and picks his seat back up through the first node.
+	bob.Close()
+	if _, err := alice.next("notification"); err != nil {
+		t.Fatalf("alice hearing bob drop: %v", err)
+	}
+	bob = dial(t, urls[0], "bob")
+	bob.send("resume", session["payload"])
+	if _, err := bob.state(func(m map[string]interface{}) bool { return m["playerId"] == "p2" }); err != nil {
+		t.Fatalf("bob resuming: %v", err)
+	}
+
+	alice.send("secret", map[string]string{"data": "1234"})
+	bob.send("secret", map[string]string{"data": "5678"})
+	active, err := bob.state(status("active"))
+	if err != nil {
+		t.Fatalf("starting the game: %v", err)
+	}
+	if active["turn"] == "p1" {
+		alice.send("submit_guess", map[string]string{"data": "5678"})
+	} else {
+		bob.send("submit_guess", map[string]string{"data": "1234"})
+	}
+	for _, c := range []*client{alice, bob, carol} {
+		if _, err := c.state(status("completed")); err != nil {
+			t.Fatalf("%s seeing the game end: %v", c.name, err)
+		}
+	}
+	if _, err := dave.next("room_closed"); err != nil {
+		t.Fatalf("dave seeing the finished room leave the lobby: %v", err)
+	}
+}
+
+type client struct {
+	*websocket.Conn
+	name string
+}
+
+func dial(t *testing.T, base, name string) *client {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(base, "http") + "/ws"
+	for {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		if err == nil {
+			t.Cleanup(func() { conn.Close() })
+			return &client{Conn: conn, name: name}
+		}
+		if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("%s connecting: %v", name, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (c *client) send(msgType string, payload interface{}) {
+	c.WriteJSON(map[string]interface{}{"type": msgType, "payload": payload})
+}
+
+// next reads until a message of msgType arrives.
+func (c *client) next(msgType string) (map[string]interface{}, error) {
+	c.SetReadDeadline(time.Now().Add(readTimeout))
+	for {
+		var m map[string]interface{}
+		if err := c.ReadJSON(&m); err != nil {
+			return nil, fmt.Errorf("no %s message: %v", msgType, err)
+		}
+		if m["type"] == msgType {
+			return m, nil
+		}
+	}
+}
+
+// state reads until a state message that match accepts arrives.
+func (c *client) state(match func(map[string]interface{}) bool) (map[string]interface{}, error) {
+	for {
+		m, err := c.next("state")
+		if err != nil {
+			return nil, err
+		}
+		if match == nil || match(m) {
+			return payload(m), nil
+		}
+	}
+}
+
+func status(want string) func(map[string]interface{}) bool {
+	return func(m map[string]interface{}) bool { return payload(m)["status"] == want }
+}
+
+func payload(m map[string]interface{}) map[string]interface{} {
+	p, _ := m["payload"].(map[string]interface{})
+	return p
+}
+
+func getJSON(url string, v interface{}) error {
+	for {
+		resp, err := http.Get(url)
+		if err != nil {
+			return err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests:
+			time.Sleep(time.Second)
+			continue
+		case http.StatusOK:
+			return json.Unmarshal(body, v)
+		}
+		return errors.New(strings.TrimSpace(string(body)))
+	}
+}
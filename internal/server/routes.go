@@ -2,14 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
-	"time"
 
-	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/history"
+	"github.com/adimail/colosseum/internal/websocket"
 )
 
 func (s *Server) routes() {
@@ -17,6 +17,17 @@ func (s *Server) routes() {
 	s.Router.HandleFunc("/api/rooms", RateLimitMiddleware(s.handleGetRooms))
 	s.Router.HandleFunc("/api/room/", RateLimitMiddleware(s.handleGetRoom))
 	s.Router.HandleFunc("/api/games", RateLimitMiddleware(s.handleGetGames))
+	s.Router.HandleFunc("/api/games/", RateLimitMiddleware(s.handleGetGame))
+	s.Router.HandleFunc("/api/leaderboard", RateLimitMiddleware(s.handleLeaderboard))
+	s.Router.HandleFunc("/api/players/", RateLimitMiddleware(s.handlePlayerStats))
+	s.Router.HandleFunc("/api/tournaments", RateLimitMiddleware(s.handleTournaments))
+	s.Router.HandleFunc("/api/tournaments/", RateLimitMiddleware(s.handleGetTournament))
+	s.Router.HandleFunc("/api/analysis/", RateLimitMiddleware(s.handleGetAnalysis))
+	s.Router.HandleFunc("/api/auth/register", RateLimitMiddleware(s.handleRegister))
+	s.Router.HandleFunc("/api/auth/login", RateLimitMiddleware(s.handleLogin))
+	s.Router.HandleFunc("/api/auth/logout", RateLimitMiddleware(s.handleLogout))
+	s.Router.HandleFunc("/api/auth/me", RateLimitMiddleware(s.handleMe))
+	s.Router.HandleFunc("/api/admin/outbox", RateLimitMiddleware(AdminMiddleware(s.handleGetOutbox)))
 	s.Router.HandleFunc("/ws", RateLimitMiddleware(s.handleWebSocket))
 
 	staticFileServer := http.FileServer(http.Dir(s.StaticDir))
@@ -44,56 +55,8 @@ func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-type RoomInfo struct {
-	RoomCode    string    `json:"roomCode"`
-	OwnerName   string    `json:"ownerName"`
-	PlayerCount int       `json:"playerCount"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"createdAt"`
-}
-
 func (s *Server) handleGetRooms(w http.ResponseWriter, r *http.Request) {
-	s.Hub.Mutex.Lock()
-	defer s.Hub.Mutex.Unlock()
-
-	roomsList := make([]RoomInfo, 0, len(s.Hub.Rooms))
-	for _, room := range s.Hub.Rooms {
-		room.Mutex.Lock()
-
-		if room.GameState.Status == "completed" {
-			room.Mutex.Unlock()
-			continue
-		}
-
-		var ownerName string
-		if room.GameState.OwnerID == game.Player1 {
-			ownerName = room.GameState.P1.Name
-		} else {
-			ownerName = room.GameState.P2.Name
-		}
-
-		playerCount := 0
-		if room.GameState.P1 != nil && room.GameState.P1.Name != "" {
-			playerCount++
-		}
-		if room.GameState.P2 != nil && room.GameState.P2.Name != "" {
-			playerCount++
-		}
-
-		info := RoomInfo{
-			RoomCode:    room.GameState.RoomCode,
-			OwnerName:   ownerName,
-			PlayerCount: playerCount,
-			Status:      room.GameState.Status,
-			CreatedAt:   room.CreatedAt,
-		}
-		roomsList = append(roomsList, info)
-		room.Mutex.Unlock()
-	}
-
-	sort.Slice(roomsList, func(i, j int) bool {
-		return roomsList[i].CreatedAt.After(roomsList[j].CreatedAt)
-	})
+	roomsList := s.Hub.LobbyRooms()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(roomsList); err != nil {
@@ -108,36 +71,34 @@ func (s *Server) handleGetRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.Hub.Mutex.Lock()
-	room, exists := s.Hub.Rooms[code]
-	s.Hub.Mutex.Unlock()
-
+	info, exists := s.Hub.RoomInfo(code)
 	if !exists {
 		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
 
-	room.Mutex.Lock()
-	var ownerName string
-	if room.GameState.P1 != nil {
-		ownerName = room.GameState.P1.Name
-	}
-	room.Mutex.Unlock()
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"roomCode":  code,
-		"ownerName": ownerName,
-	})
+	json.NewEncoder(w).Encode(info)
 }
 
 func (s *Server) handleGetGames(w http.ResponseWriter, r *http.Request) {
-	if s.SheetsService == nil {
+	if s.History == nil {
 		http.Error(w, "Game history service is not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	games, err := s.SheetsService.GetRecentGames(50)
+	var games interface{}
+	var err error
+	if room := r.URL.Query().Get("room"); room != "" {
+		var transcripts []history.Transcript
+		transcripts, err = s.History.GamesInRoom(room)
+		for i := range transcripts {
+			transcripts[i].Events = nil
+		}
+		games = transcripts
+	} else {
+		games, err = s.History.GetRecentGames(50)
+	}
 	if err != nil {
 		http.Error(w, "Failed to retrieve game history", http.StatusInternalServerError)
 		return
@@ -149,6 +110,98 @@ func (s *Server) handleGetGames(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleGetGame(w http.ResponseWriter, r *http.Request) {
+	id, view, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/games/"), "/")
+	if id == "" || (view != "" && view != "replay") {
+		http.Error(w, "Game ID required", http.StatusBadRequest)
+		return
+	}
+	if s.History == nil {
+		http.Error(w, "Game history service is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	transcript, err := s.History.GetGame(id)
+	if errors.Is(err, history.ErrNotFound) {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retrieve game", http.StatusInternalServerError)
+		return
+	}
+
+	var body interface{} = transcript.Events
+	if view == "" {
+		transcript.Events = nil
+		body = transcript
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, "Failed to encode game", http.StatusInternalServerError)
+	}
+}
+
+// handleGetOutbox lists the history records still waiting for delivery and
+// those that gave up, without their game payloads.
+func (s *Server) handleGetOutbox(w http.ResponseWriter, r *http.Request) {
+	outbox, ok := s.History.(*history.Outbox)
+	if !ok {
+		http.Error(w, "History outbox is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	pending, err := outbox.Pending()
+	if err != nil {
+		http.Error(w, "Failed to read outbox", http.StatusInternalServerError)
+		return
+	}
+	dead, err := outbox.Dead()
+	if err != nil {
+		http.Error(w, "Failed to read outbox", http.StatusInternalServerError)
+		return
+	}
+	for _, entries := range [][]history.OutboxEntry{pending, dead} {
+		for i := range entries {
+			entries[i].Game = nil
+			entries[i].Log = nil
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]history.OutboxEntry{
+		"pending": pending,
+		"dead":    dead,
+	})
+}
+
+func (s *Server) handleGetAnalysis(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, "/api/analysis/")
+	if code == "" {
+		http.Error(w, "Room code required", http.StatusBadRequest)
+		return
+	}
+
+	// Private rooms want the password, kept out of the URL in a header, or
+	// an invite.
+	password := r.Header.Get("X-Room-Password")
+	analysis, err := s.Hub.RoomAnalysis(code, password, r.URL.Query().Get("invite"))
+	switch {
+	case errors.Is(err, websocket.ErrRoomLocked):
+		http.Error(w, "This room needs its password or an invite", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "No completed game to analyse", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(analysis); err != nil {
+		http.Error(w, "Failed to encode analysis", http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	s.Hub.ServeWS(w, r)
 }
@@ -2,34 +2,45 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
-	"github.com/adimail/colosseum/internal/sheets"
+	"github.com/adimail/colosseum/internal/auth"
+	"github.com/adimail/colosseum/internal/history"
+	"github.com/adimail/colosseum/internal/rating"
+	"github.com/adimail/colosseum/internal/roomstore"
+	"github.com/adimail/colosseum/internal/stats"
 	"github.com/adimail/colosseum/internal/websocket"
 )
 
 type Server struct {
-	Addr          string
-	StaticDir     string
-	Router        *http.ServeMux
-	Hub           *websocket.Hub
-	httpServer    *http.Server
-	SheetsService *sheets.Service
+	Addr       string
+	StaticDir  string
+	Router     *http.ServeMux
+	Hub        *websocket.Hub
+	httpServer *http.Server
+	History    history.Store
+	Ratings    *rating.Store
+	Accounts   *auth.Accounts
+	Stats      *stats.Store
 }
 
-func NewServer(addr, staticDir string, sheetsService *sheets.Service) *Server {
-	hub := websocket.NewHub(sheetsService)
+func NewServer(addr, staticDir string, store history.Store, ratings *rating.Store, accounts *auth.Accounts, results *stats.Store, snapshots *roomstore.Store) *Server {
+	hub := websocket.NewHub(store, ratings, accounts, results, snapshots)
 	go hub.Run()
 
 	router := http.NewServeMux()
 
 	s := &Server{
-		Addr:          addr,
-		StaticDir:     staticDir,
-		Router:        router,
-		Hub:           hub,
-		SheetsService: sheetsService,
+		Addr:      addr,
+		StaticDir: staticDir,
+		Router:    router,
+		Hub:       hub,
+		History:   store,
+		Ratings:   ratings,
+		Accounts:  accounts,
+		Stats:     results,
 	}
 
 	s.httpServer = &http.Server{
@@ -48,6 +59,10 @@ func (s *Server) Start() error {
 	return s.httpServer.ListenAndServe()
 }
 
+// Shutdown stops taking new connections, then saves the rooms and closes
+// the WebSocket connections, which the HTTP server does not track. The
+// rooms are saved even if the HTTP server did not stop cleanly.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	return errors.Join(err, s.Hub.Shutdown(ctx))
 }
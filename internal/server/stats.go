@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/rating"
+	"github.com/adimail/colosseum/internal/stats"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// windowStart turns a ?window= of week, month or all into the time results
+// are counted from.
+func windowStart(window string, now time.Time) (time.Time, bool) {
+	switch window {
+	case "week":
+		return now.AddDate(0, 0, -7), true
+	case "month":
+		return now.AddDate(0, -1, 0), true
+	case "", "all":
+		return time.Time{}, true
+	}
+	return time.Time{}, false
+}
+
+func pageParams(r *http.Request) (limit, offset int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+	offset, err = strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return min(limit, maxPageSize), offset
+}
+
+type LeaderboardPage struct {
+	Window  string              `json:"window"`
+	Total   int                 `json:"total"`
+	Limit   int                 `json:"limit"`
+	Offset  int                 `json:"offset"`
+	Players []stats.PlayerStats `json:"players"`
+}
+
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if s.Stats == nil {
+		http.Error(w, "Statistics are not available", http.StatusServiceUnavailable)
+		return
+	}
+	window := r.URL.Query().Get("window")
+	since, ok := windowStart(window, time.Now())
+	if !ok {
+		http.Error(w, "Window must be week, month or all", http.StatusBadRequest)
+		return
+	}
+	if window == "" {
+		window = "all"
+	}
+	limit, offset := pageParams(r)
+
+	board := s.Stats.Leaderboard(since)
+	page := LeaderboardPage{
+		Window:  window,
+		Total:   len(board),
+		Limit:   limit,
+		Offset:  offset,
+		Players: board[min(offset, len(board)):min(offset+limit, len(board))],
+	}
+	for i := range page.Players {
+		s.addRating(&page.Players[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, "Failed to encode leaderboard", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handlePlayerStats(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/players/"), "/stats")
+	if !ok || name == "" {
+		http.Error(w, "Player name required", http.StatusBadRequest)
+		return
+	}
+	if s.Stats == nil {
+		http.Error(w, "Statistics are not available", http.StatusServiceUnavailable)
+		return
+	}
+	since, ok := windowStart(r.URL.Query().Get("window"), time.Now())
+	if !ok {
+		http.Error(w, "Window must be week, month or all", http.StatusBadRequest)
+		return
+	}
+
+	var userID string
+	if s.Accounts != nil {
+		userID = s.Accounts.UserID(name)
+	}
+	player, ok := s.Stats.Player(userID, name, since)
+	if !ok {
+		http.Error(w, "No games found for that player", http.StatusNotFound)
+		return
+	}
+	s.addRating(&player)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(player); err != nil {
+		http.Error(w, "Failed to encode statistics", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) addRating(p *stats.PlayerStats) {
+	if s.Ratings != nil {
+		p.Rating = s.Ratings.Get(rating.Player{UserID: p.UserID, Name: p.Name}).Rating
+	}
+}
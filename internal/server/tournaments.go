@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/tournament"
+)
+
+type CreateTournamentRequest struct {
+	Name   string            `json:"name"`
+	Format tournament.Format `json:"format"`
+	Roster []string          `json:"roster"`
+	Rules  *game.Rules       `json:"rules"`
+}
+
+func (s *Server) handleTournaments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Hub.Tournaments())
+	case http.MethodPost:
+		s.handleCreateTournament(w, r)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCreateTournament lets a logged-in organizer start a tournament.
+func (s *Server) handleCreateTournament(w http.ResponseWriter, r *http.Request) {
+	if s.Accounts == nil {
+		http.Error(w, "Accounts are not available", http.StatusServiceUnavailable)
+		return
+	}
+	user, ok := s.Accounts.SessionUser(r)
+	if !ok {
+		http.Error(w, "Log in to organise a tournament", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateTournamentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16*1024)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	rules := game.DefaultRules()
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+
+	t, err := s.Hub.CreateTournament(req.Name, user.Username, req.Format, req.Roster, rules)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t)
+}
+
+func (s *Server) handleGetTournament(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/tournaments/")
+	if id == "" {
+		http.Error(w, "Tournament ID required", http.StatusBadRequest)
+		return
+	}
+
+	t, ok := s.Hub.Tournament(id)
+	if !ok {
+		http.Error(w, "Tournament not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, "Failed to encode tournament", http.StatusInternalServerError)
+	}
+}
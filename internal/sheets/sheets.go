@@ -2,26 +2,23 @@ package sheets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log/slog"
 	"os"
-	"time"
 
 	"github.com/adimail/colosseum/internal/game"
-	"github.com/joho/godotenv"
+	"github.com/adimail/colosseum/internal/history"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
-const sheetName = "Games"
-
-type GameRecord struct {
-	Timestamp string `json:"timestamp"`
-	P1Name    string `json:"p1Name"`
-	P2Name    string `json:"p2Name"`
-	Winner    string `json:"winner"`
-}
+const (
+	sheetName            = "Games"
+	seriesSheetName      = "Series"
+	transcriptsSheetName = "Transcripts"
+	eventsSheetName      = "Events"
+)
 
 type Service struct {
 	sheetsService *sheets.Service
@@ -29,11 +26,6 @@ type Service struct {
 }
 
 func New() (*Service, error) {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		slog.Warn("no .env file found")
-	}
-
 	// Get spreadsheet ID from environment
 	spreadsheetID := os.Getenv("SPREADSHEET_ID")
 	if spreadsheetID == "" {
@@ -66,70 +58,182 @@ func New() (*Service, error) {
 	}, nil
 }
 
-func (s *Service) RecordGame(gs *game.GameState) {
-	var winnerName string
-	if gs.Winner == string(game.Player1) {
-		winnerName = gs.P1.Name
-	} else {
-		winnerName = gs.P2.Name
+func (s *Service) RecordGame(gs *game.GameState) error {
+	r := history.NewGameRecord(gs)
+	err := s.appendOnce(sheetName, "H", r.ID, "USER_ENTERED", [][]interface{}{
+		{r.Timestamp, r.P1Name, r.P2Name, r.Winner, r.Rules, r.EndReason, r.Series, r.ID, r.RoomCode},
+	})
+	if err != nil {
+		return err
 	}
 
-	row := &sheets.ValueRange{
-		Values: [][]interface{}{
-			{
-				time.Now().UTC().Format(time.RFC3339),
-				gs.P1.Name,
-				gs.P2.Name,
-				winnerName,
-			},
-		},
+	// The full transcript goes to its own sheet as JSON, keyed by game ID.
+	// Its events get a row each so no single cell outgrows the sheet limit.
+	t := history.NewTranscript(gs)
+	events := t.Events
+	t.Events = nil
+	transcript, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	err = s.appendOnce(transcriptsSheetName, "A", r.ID, "RAW", [][]interface{}{
+		{r.ID, r.RoomCode, r.Timestamp, string(transcript)},
+	})
+	if err != nil || len(events) == 0 {
+		return err
 	}
 
-	_, err := s.sheetsService.Spreadsheets.Values.Append(
-		s.spreadsheetID,
-		sheetName,
-		row,
-	).ValueInputOption("USER_ENTERED").Do()
+	rows := make([][]interface{}, len(events))
+	for i, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		rows[i] = []interface{}{r.ID, e.Seq, string(data)}
+	}
+	return s.appendOnce(eventsSheetName, "A", r.ID, "RAW", rows)
+}
+
+// RecordSeries appends a finished best-of-N series, with the result of each
+// game, to the Series sheet.
+func (s *Service) RecordSeries(gs *game.GameState) error {
+	if gs.Series == nil || !gs.Series.Over() {
+		return nil
+	}
+	r := history.NewSeriesRecord(gs)
+	return s.appendOnce(seriesSheetName, "H", gs.GameID, "USER_ENTERED", [][]interface{}{
+		{r.Timestamp, r.P1Name, r.P2Name, r.Winner, r.Score, r.Rules, r.Games, gs.GameID},
+	})
+}
 
+// appendOnce appends rows to sheet unless column col already holds key, so
+// retrying a partly written record does not duplicate the rows that made it.
+func (s *Service) appendOnce(sheet, col, key, inputOption string, rows [][]interface{}) error {
+	readRange := fmt.Sprintf("%s!%s:%s", sheet, col, col)
+	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, readRange).Do()
 	if err != nil {
-		slog.Error("failed to record game to Google Sheets", "error", err)
-	} else {
-		slog.Info("successfully recorded game to Google Sheets", "room", gs.RoomCode)
+		return fmt.Errorf("unable to retrieve data from sheet: %v", err)
+	}
+	for _, row := range resp.Values {
+		if len(row) > 0 && fmt.Sprintf("%v", row[0]) == key {
+			return nil
+		}
 	}
+
+	_, err = s.sheetsService.Spreadsheets.Values.Append(
+		s.spreadsheetID,
+		sheet,
+		&sheets.ValueRange{Values: rows},
+	).ValueInputOption(inputOption).Do()
+	return err
 }
 
-func (s *Service) GetRecentGames(limit int) ([]GameRecord, error) {
-	readRange := fmt.Sprintf("%s!A:D", sheetName)
+func (s *Service) GetRecentGames(limit int) ([]history.GameRecord, error) {
+	readRange := fmt.Sprintf("%s!A:I", sheetName)
 
 	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, readRange).Do()
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve data from sheet: %v", err)
 	}
 
-	var records []GameRecord
+	var records []history.GameRecord
 	if len(resp.Values) <= 1 {
 		return records, nil
 	}
 
-	startIndex := len(resp.Values) - 1
-	count := 0
-
-	for i := startIndex; i > 0 && count < limit; i-- {
+	// Rows are appended as the outbox delivers them, which need not be the
+	// order the games ended in, so every row is read and sorted.
+	for i := len(resp.Values) - 1; i > 0; i-- {
 		row := resp.Values[i]
 		if len(row) < 4 {
 			continue
 		}
 
-		record := GameRecord{
+		record := history.GameRecord{
 			Timestamp: fmt.Sprintf("%v", row[0]),
 			P1Name:    fmt.Sprintf("%v", row[1]),
 			P2Name:    fmt.Sprintf("%v", row[2]),
 			Winner:    fmt.Sprintf("%v", row[3]),
 		}
+		if len(row) > 4 {
+			record.Rules = fmt.Sprintf("%v", row[4])
+		}
+		if len(row) > 5 {
+			record.EndReason = fmt.Sprintf("%v", row[5])
+		}
+		if len(row) > 6 {
+			record.Series = fmt.Sprintf("%v", row[6])
+		}
+		if len(row) > 8 {
+			record.ID = fmt.Sprintf("%v", row[7])
+			record.RoomCode = fmt.Sprintf("%v", row[8])
+		}
 
 		records = append(records, record)
-		count++
 	}
 
+	history.SortNewestFirst(records)
+	if len(records) > limit {
+		records = records[:limit]
+	}
 	return records, nil
 }
+
+// transcripts reads every transcript on the Transcripts sheet for which keep
+// returns true, newest first.
+func (s *Service) transcripts(keep func(id, roomCode string) bool) ([]history.Transcript, error) {
+	readRange := fmt.Sprintf("%s!A:D", transcriptsSheetName)
+	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, readRange).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve data from sheet: %v", err)
+	}
+
+	var transcripts []history.Transcript
+	for i := len(resp.Values) - 1; i >= 0; i-- {
+		row := resp.Values[i]
+		if len(row) < 4 || !keep(fmt.Sprintf("%v", row[0]), fmt.Sprintf("%v", row[1])) {
+			continue
+		}
+		var t history.Transcript
+		if err := json.Unmarshal([]byte(fmt.Sprintf("%v", row[3])), &t); err != nil {
+			continue
+		}
+		transcripts = append(transcripts, t)
+	}
+	history.SortTranscriptsNewestFirst(transcripts)
+	return transcripts, nil
+}
+
+func (s *Service) GetGame(id string) (*history.Transcript, error) {
+	found, err := s.transcripts(func(gameID, _ string) bool { return gameID == id })
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, history.ErrNotFound
+	}
+
+	readRange := fmt.Sprintf("%s!A:C", eventsSheetName)
+	resp, err := s.sheetsService.Spreadsheets.Values.Get(s.spreadsheetID, readRange).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve data from sheet: %v", err)
+	}
+	for _, row := range resp.Values {
+		if len(row) < 3 || fmt.Sprintf("%v", row[0]) != id {
+			continue
+		}
+		var e game.Event
+		if err := json.Unmarshal([]byte(fmt.Sprintf("%v", row[2])), &e); err == nil {
+			found[0].Events = append(found[0].Events, e)
+		}
+	}
+	return &found[0], nil
+}
+
+func (s *Service) GamesInRoom(roomCode string) ([]history.Transcript, error) {
+	return s.transcripts(func(_, code string) bool { return code == roomCode })
+}
+
+func (s *Service) Close() error {
+	return nil
+}
@@ -0,0 +1,155 @@
+package solver
+
+import (
+	"errors"
+	"math"
+	mrand "math/rand"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+const (
+	analysisNodeLimit = 2_000_000
+	estimateSamples   = 20_000
+)
+
+type GuessAnalysis struct {
+	Code  string `json:"code"`
+	Bulls int    `json:"bulls"`
+	Cows  int    `json:"cows"`
+	// CandidatesBefore and CandidatesAfter count the codes still consistent
+	// with the player's feedback around this guess. They are estimates when
+	// Exact is false.
+	CandidatesBefore float64 `json:"candidatesBefore"`
+	CandidatesAfter  float64 `json:"candidatesAfter"`
+	InfoBits         float64 `json:"infoBits"`
+	// WorstCase is how many candidates the played guess could have left in
+	// the worst case, to compare with OptimalWorstCase.
+	WorstCase        int    `json:"worstCase,omitempty"`
+	OptimalGuess     string `json:"optimalGuess,omitempty"`
+	OptimalWorstCase int    `json:"optimalWorstCase,omitempty"`
+	Exact            bool   `json:"exact"`
+}
+
+// PlayerAnalysis covers one player's guesses at one opponent's code.
+type PlayerAnalysis struct {
+	Player    game.PlayerID   `json:"player"`
+	Name      string          `json:"name"`
+	Target    game.PlayerID   `json:"target"`
+	Guesses   []GuessAnalysis `json:"guesses"`
+	TotalBits float64         `json:"totalBits"`
+}
+
+type Analysis struct {
+	RoomCode string           `json:"roomCode"`
+	Rules    game.Rules       `json:"rules"`
+	Players  []PlayerAnalysis `json:"players"`
+}
+
+// Analyze scores every guess of a completed game. gs is only read, but the
+// caller must not mutate it concurrently.
+func Analyze(gs *game.GameState) (*Analysis, error) {
+	if gs.Status != "completed" {
+		return nil, errors.New("game is not completed")
+	}
+
+	s := New(gs.Rules)
+	rng := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+
+	a := &Analysis{RoomCode: gs.RoomCode, Rules: gs.Rules}
+	for _, p := range gs.Players {
+		for _, target := range gs.Players {
+			guesses := p.GuessesAt(target.ID)
+			if target == p || len(guesses) == 0 && gs.IsFreeForAll() {
+				continue
+			}
+			a.Players = append(a.Players, s.analyzePlayer(p, target.ID, guesses, rng))
+		}
+	}
+	return a, nil
+}
+
+func (s *Solver) analyzePlayer(p *game.PlayerState, target game.PlayerID, guesses []game.Guess, rng *mrand.Rand) PlayerAnalysis {
+	// Guesses are stored newest first.
+	history := make([]game.Guess, len(guesses))
+	for i, g := range guesses {
+		history[len(guesses)-1-i] = g
+	}
+
+	pa := PlayerAnalysis{Player: p.ID, Name: p.Name, Target: target, Guesses: []GuessAnalysis{}}
+	cands, listed := s.candidates(nil, analysisNodeLimit)
+	before := s.rules.SpaceSize()
+
+	for k, g := range history {
+		ga := GuessAnalysis{
+			Code:             g.Code,
+			Bulls:            g.Bulls,
+			Cows:             g.Cows,
+			CandidatesBefore: before,
+			Exact:            listed,
+		}
+
+		if listed {
+			played := s.encode(g.Code)
+			ga.WorstCase = s.worstCase(&played, cands)
+			best, worst := s.minimax(cands, rng)
+			ga.OptimalGuess = s.decode(best)
+			ga.OptimalWorstCase = worst
+
+			cons := s.constraints(history[k : k+1])
+			kept := cands[:0:0]
+			for i := range cands {
+				if s.consistent(&cands[i], cons) {
+					kept = append(kept, cands[i])
+				}
+			}
+			cands = kept
+			ga.CandidatesAfter = float64(len(cands))
+		} else {
+			cands, listed = s.candidates(history[:k+1], analysisNodeLimit)
+			if listed {
+				ga.CandidatesAfter = float64(len(cands))
+			} else {
+				ga.CandidatesAfter = s.estimate(history[:k+1], rng)
+			}
+			ga.Exact = false
+		}
+
+		if ga.CandidatesAfter > 0 && ga.CandidatesBefore > 0 {
+			ga.InfoBits = math.Log2(ga.CandidatesBefore / ga.CandidatesAfter)
+		}
+		pa.TotalBits += ga.InfoBits
+		pa.Guesses = append(pa.Guesses, ga)
+		before = ga.CandidatesAfter
+	}
+	return pa
+}
+
+func (s *Solver) worstCase(guess *code, cands []code) int {
+	width := s.rules.Length + 1
+	buckets := make([]int, width*width)
+	worst := 0
+	for i := range cands {
+		bulls, cows := s.score(guess, &cands[i])
+		k := bulls*width + cows
+		buckets[k]++
+		worst = max(worst, buckets[k])
+	}
+	return worst
+}
+
+// estimate approximates the number of consistent codes by sampling when the
+// space is too large to search. The true secret is always consistent, so the
+// result is never below one.
+func (s *Solver) estimate(guesses []game.Guess, rng *mrand.Rand) float64 {
+	cons := s.constraints(guesses)
+	hits := 0
+	for i := 0; i < estimateSamples; i++ {
+		c := s.encode(s.rules.RandomCode(rng))
+		if s.consistent(&c, cons) {
+			hits++
+		}
+	}
+	return math.Max(1, s.rules.SpaceSize()*float64(hits)/estimateSamples)
+}
@@ -0,0 +1,416 @@
+package solver
+
+import (
+	"math/bits"
+	mrand "math/rand"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+const (
+	// MaxCandidates bounds how many consistent codes are kept in memory.
+	MaxCandidates = 200_000
+	// searchNodeLimit bounds the depth-first search used to list candidates.
+	searchNodeLimit = 5_000_000
+	// minimaxBudget caps guesses x candidates scored when searching for the
+	// best guess.
+	minimaxBudget = 1_000_000
+)
+
+// code is a guess or secret stored as indexes into the symbol set, with a
+// bitmask of the symbols it contains so unique-symbol codes score with a
+// single popcount.
+type code struct {
+	sym  [game.MaxCodeLength]uint8
+	mask uint64
+}
+
+type Solver struct {
+	rules   game.Rules
+	symbols []rune
+	index   map[rune]int
+}
+
+func New(rules game.Rules) *Solver {
+	symbols := []rune(rules.Symbols)
+	index := make(map[rune]int, len(symbols))
+	for i, r := range symbols {
+		index[r] = i
+	}
+	return &Solver{rules: rules, symbols: symbols, index: index}
+}
+
+func (s *Solver) encode(str string) code {
+	var c code
+	for i, r := range []rune(str) {
+		if i >= s.rules.Length {
+			break
+		}
+		c.sym[i] = uint8(s.index[r])
+		c.mask |= 1 << uint(s.index[r])
+	}
+	return c
+}
+
+func (s *Solver) decode(c code) string {
+	out := make([]rune, s.rules.Length)
+	for i := range out {
+		out[i] = s.symbols[c.sym[i]]
+	}
+	return string(out)
+}
+
+func (s *Solver) score(guess, secret *code) (int, int) {
+	bulls := 0
+	for i := 0; i < s.rules.Length; i++ {
+		if guess.sym[i] == secret.sym[i] {
+			bulls++
+		}
+	}
+	if !s.rules.AllowDuplicates {
+		return bulls, bits.OnesCount64(guess.mask&secret.mask) - bulls
+	}
+
+	var counts [64]int8
+	for i := 0; i < s.rules.Length; i++ {
+		counts[secret.sym[i]]++
+	}
+	matches := 0
+	for i := 0; i < s.rules.Length; i++ {
+		if counts[guess.sym[i]] > 0 {
+			counts[guess.sym[i]]--
+			matches++
+		}
+	}
+	return bulls, matches - bulls
+}
+
+type constraint struct {
+	guess   code
+	counts  [64]int8
+	bulls   int
+	matches int
+}
+
+func (s *Solver) constraints(guesses []game.Guess) []constraint {
+	cons := make([]constraint, len(guesses))
+	for i, g := range guesses {
+		c := constraint{guess: s.encode(g.Code), bulls: g.Bulls, matches: g.Bulls + g.Cows}
+		for p := 0; p < s.rules.Length; p++ {
+			c.counts[c.guess.sym[p]]++
+		}
+		cons[i] = c
+	}
+	return cons
+}
+
+func (s *Solver) consistent(c *code, cons []constraint) bool {
+	for i := range cons {
+		bulls, cows := s.score(&cons[i].guess, c)
+		if bulls != cons[i].bulls || bulls+cows != cons[i].matches {
+			return false
+		}
+	}
+	return true
+}
+
+// walk visits codes consistent with cons depth first, pruning any prefix
+// that can no longer produce the required bulls and matches. Symbols are
+// tried in a random order when rng is set. visit returns false to stop. walk
+// reports false if it gave up after nodeLimit nodes or was stopped.
+func (s *Solver) walk(cons []constraint, rng *mrand.Rand, nodeLimit int, visit func(code) bool) bool {
+	length := s.rules.Length
+	var (
+		cur      code
+		used     [64]int8
+		bulls    = make([]int, len(cons))
+		matches  = make([]int, len(cons))
+		capacity = make([]int, len(cons))
+		nodes    int
+		stopped  bool
+	)
+	for i := range capacity {
+		capacity[i] = length
+	}
+
+	order := make([]int, len(s.symbols))
+	for i := range order {
+		order[i] = i
+	}
+
+	var step func(pos int) bool
+	step = func(pos int) bool {
+		if pos == length {
+			for i := range cons {
+				if bulls[i] != cons[i].bulls || matches[i] != cons[i].matches {
+					return true
+				}
+			}
+			if !visit(cur) {
+				stopped = true
+				return false
+			}
+			return true
+		}
+
+		syms := order
+		if rng != nil {
+			syms = rng.Perm(len(s.symbols))
+		}
+		remaining := length - pos - 1
+		for _, sym := range syms {
+			if nodes++; nodeLimit > 0 && nodes > nodeLimit {
+				return false
+			}
+			if used[sym] > 0 && !s.rules.AllowDuplicates {
+				continue
+			}
+
+			ok := true
+			for i := range cons {
+				c := &cons[i]
+				nb, nm, spare := bulls[i], matches[i], capacity[i]
+				if int(c.guess.sym[pos]) == sym {
+					nb++
+				}
+				if used[sym] < c.counts[sym] {
+					nm++
+					spare--
+				}
+				if nb > c.bulls || nb+remaining < c.bulls || nm > c.matches || nm+min(remaining, spare) < c.matches {
+					ok = false
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+
+			for i := range cons {
+				c := &cons[i]
+				if int(c.guess.sym[pos]) == sym {
+					bulls[i]++
+				}
+				if used[sym] < c.counts[sym] {
+					matches[i]++
+					capacity[i]--
+				}
+			}
+			used[sym]++
+			cur.sym[pos] = uint8(sym)
+			cur.mask |= 1 << uint(sym)
+
+			cont := step(pos + 1)
+
+			used[sym]--
+			if used[sym] == 0 {
+				cur.mask &^= 1 << uint(sym)
+			}
+			for i := range cons {
+				c := &cons[i]
+				if int(c.guess.sym[pos]) == sym {
+					bulls[i]--
+				}
+				if used[sym] < c.counts[sym] {
+					matches[i]--
+					capacity[i]++
+				}
+			}
+			if !cont {
+				return false
+			}
+		}
+		return true
+	}
+
+	return step(0) && !stopped
+}
+
+// candidates lists every code consistent with the guesses. It returns false
+// if there are more than MaxCandidates or the search exceeds nodeLimit, which
+// is unbounded when zero.
+func (s *Solver) candidates(guesses []game.Guess, nodeLimit int) ([]code, bool) {
+	var out []code
+	complete := s.walk(s.constraints(guesses), nil, nodeLimit, func(c code) bool {
+		out = append(out, c)
+		return len(out) <= MaxCandidates
+	})
+	if !complete {
+		return nil, false
+	}
+	return out, true
+}
+
+// Candidates lists every code that could still be the secret given the
+// feedback so far, or returns false if there are too many to list.
+func (s *Solver) Candidates(guesses []game.Guess) ([]string, bool) {
+	cands, ok := s.candidates(guesses, searchNodeLimit)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, len(cands))
+	for i, c := range cands {
+		out[i] = s.decode(c)
+	}
+	return out, true
+}
+
+// FindConsistent returns a random code consistent with the guesses. When the
+// space is too large for a full search it tries several randomised searches
+// and then hill-climbs; if neither turns up a consistent code it returns
+// false.
+func (s *Solver) FindConsistent(guesses []game.Guess, rng *mrand.Rand) (string, bool) {
+	cons := s.constraints(guesses)
+	const restarts, nodeLimit = 20, 50_000
+
+	for i := 0; i < restarts; i++ {
+		var found code
+		var ok bool
+		s.walk(cons, rng, nodeLimit, func(c code) bool {
+			found, ok = c, true
+			return false
+		})
+		if ok {
+			return s.decode(found), true
+		}
+	}
+
+	best := s.encode(s.rules.RandomCode(rng))
+	bestScore := s.violation(&best, cons)
+	for i := 0; i < restarts && bestScore > 0; i++ {
+		cur := s.encode(s.rules.RandomCode(rng))
+		score := s.violation(&cur, cons)
+		for step := 0; step < nodeLimit/restarts && score > 0; step++ {
+			next := s.mutate(cur, rng)
+			if v := s.violation(&next, cons); v <= score {
+				cur, score = next, v
+			}
+		}
+		if score < bestScore {
+			best, bestScore = cur, score
+		}
+	}
+	if bestScore > 0 {
+		return "", false
+	}
+	return s.decode(best), true
+}
+
+func (s *Solver) violation(c *code, cons []constraint) int {
+	total := 0
+	for i := range cons {
+		bulls, cows := s.score(&cons[i].guess, c)
+		total += abs(bulls-cons[i].bulls) + abs(bulls+cows-cons[i].matches)
+	}
+	return total
+}
+
+// mutate changes one position to a random symbol, swapping instead when the
+// rules forbid the symbol appearing twice.
+func (s *Solver) mutate(c code, rng *mrand.Rand) code {
+	pos := rng.Intn(s.rules.Length)
+	sym := uint8(rng.Intn(len(s.symbols)))
+	if !s.rules.AllowDuplicates && c.mask&(1<<uint(sym)) != 0 {
+		for i := 0; i < s.rules.Length; i++ {
+			if c.sym[i] == sym {
+				c.sym[i], c.sym[pos] = c.sym[pos], c.sym[i]
+				return c
+			}
+		}
+	}
+	c.sym[pos] = sym
+	c.mask = 0
+	for i := 0; i < s.rules.Length; i++ {
+		c.mask |= 1 << uint(c.sym[i])
+	}
+	return c
+}
+
+// BestGuess returns the guess that minimises the worst-case number of
+// candidates left after it is played, preferring guesses that could win
+// outright. It returns false when the candidates cannot be listed.
+func (s *Solver) BestGuess(guesses []game.Guess, rng *mrand.Rand) (string, bool) {
+	cands, ok := s.candidates(guesses, searchNodeLimit)
+	if !ok || len(cands) == 0 {
+		return "", false
+	}
+	best, _ := s.minimax(cands, rng)
+	return s.decode(best), true
+}
+
+// minimax scores a pool of guesses against the candidates and returns the
+// best one with its worst-case partition size. Large sets are sampled to
+// stay within minimaxBudget.
+func (s *Solver) minimax(cands []code, rng *mrand.Rand) (code, int) {
+	if len(cands) <= 2 {
+		return cands[0], 1
+	}
+
+	scored := cands
+	if len(scored)*len(scored) > minimaxBudget {
+		scored = sample(cands, minimaxBudget/len(cands), rng)
+	}
+
+	// In hard mode only candidates may be played, so the pool is not
+	// padded with codes that are already ruled out.
+	limit := minimaxBudget / len(scored)
+	pool := cands
+	if len(pool) > limit {
+		pool = sample(pool, limit, rng)
+	} else if extra := limit - len(pool); extra > 0 && !s.rules.HardMode {
+		pool = append([]code{}, pool...)
+		for i := 0; i < extra; i++ {
+			pool = append(pool, s.encode(s.rules.RandomCode(rng)))
+		}
+	}
+	width := s.rules.Length + 1
+	buckets := make([]int, width*width)
+	bestIdx, bestWorst := 0, len(scored)+1
+	for gi := range pool {
+		clear(buckets)
+		worst := 0
+		for si := range scored {
+			bulls, cows := s.score(&pool[gi], &scored[si])
+			k := bulls*width + cows
+			buckets[k]++
+			if buckets[k] > worst {
+				worst = buckets[k]
+			}
+			if worst > bestWorst {
+				break
+			}
+		}
+		// Candidates come first in the pool, so keeping the first of equal
+		// guesses favours one that could win outright.
+		if worst < bestWorst {
+			bestIdx, bestWorst = gi, worst
+		}
+	}
+
+	// Scale the worst case back up if the candidates were sampled.
+	if len(scored) < len(cands) {
+		bestWorst = bestWorst * len(cands) / len(scored)
+	}
+	return pool[bestIdx], bestWorst
+}
+
+func sample(codes []code, n int, rng *mrand.Rand) []code {
+	if n >= len(codes) {
+		return codes
+	}
+	if n < 1 {
+		n = 1
+	}
+	out := make([]code, n)
+	for i, j := range rng.Perm(len(codes))[:n] {
+		out[i] = codes[j]
+	}
+	return out
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,291 @@
+package solver
+
+import (
+	"math"
+	mrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   game.Rules
+		secret  string
+		guesses []string
+	}{
+		{"unique, no guesses", game.Rules{Length: 3}, "012", nil},
+		{"unique", game.Rules{Length: 3}, "012", []string{"345", "102"}},
+		{"unique, four digits", game.Rules{}, "1234", []string{"5678", "1243", "2134"}},
+		{"duplicates, no guesses", game.Rules{Length: 3, AllowDuplicates: true}, "112", nil},
+		{"duplicates", game.Rules{Length: 3, AllowDuplicates: true}, "112", []string{"111", "221"}},
+		{"duplicates, four digits", game.Rules{AllowDuplicates: true}, "0090", []string{"0000", "1234", "9009"}},
+		{"hex", game.Rules{Length: 3, Alphabet: game.AlphabetHex}, "A0F", []string{"ABC", "0F1"}},
+		{"custom, duplicates", game.Rules{Length: 4, Alphabet: game.AlphabetCustom, Symbols: "abcde", AllowDuplicates: true}, "aabe", []string{"abcd", "eeaa"}},
+		{"cracked", game.Rules{Length: 3}, "789", []string{"789"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := mustResolve(t, tt.rules)
+			guesses := feedback(rules, tt.secret, tt.guesses)
+			got, ok := New(rules).Candidates(guesses)
+			if !ok {
+				t.Fatalf("Candidates gave up")
+			}
+			sort.Strings(got)
+			want := bruteForce(rules, guesses)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%d candidates; brute force finds %d", len(got), len(want))
+			}
+			if len(guesses) == 0 && float64(len(got)) != rules.SpaceSize() {
+				t.Errorf("%d candidates with no guesses; space holds %v", len(got), rules.SpaceSize())
+			}
+		})
+	}
+}
+
+func TestFindConsistent(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   game.Rules
+		secret  string
+		guesses []string
+	}{
+		{"unique", game.Rules{}, "1234", []string{"5678", "1243"}},
+		{"duplicates", game.Rules{AllowDuplicates: true}, "0090", []string{"0000", "9009"}},
+		{"hard mode", game.Rules{HardMode: true}, "9876", []string{"0123", "4567", "8796"}},
+		{"long hex with duplicates", game.Rules{Length: 6, Alphabet: game.AlphabetHex, AllowDuplicates: true}, "A0A0FF", []string{"000000", "AAAAAA", "0123AF"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := mustResolve(t, tt.rules)
+			guesses := feedback(rules, tt.secret, tt.guesses)
+			rng := mrand.New(mrand.NewSource(1))
+			for i := 0; i < 20; i++ {
+				got, ok := New(rules).FindConsistent(guesses, rng)
+				if !ok {
+					t.Fatalf("FindConsistent found nothing")
+				}
+				if !rules.IsValidSecret(got) || !game.IsConsistent(rules, got, guesses) {
+					t.Fatalf("FindConsistent = %q, which contradicts the feedback", got)
+				}
+			}
+		})
+	}
+
+	t.Run("contradictory feedback", func(t *testing.T) {
+		rules := mustResolve(t, game.Rules{Length: 3})
+		guesses := []game.Guess{{Code: "123", Bulls: 3}, {Code: "456", Bulls: 1}}
+		if got, ok := New(rules).FindConsistent(guesses, mrand.New(mrand.NewSource(1))); ok {
+			t.Errorf("FindConsistent = %q; want nothing", got)
+		}
+	})
+}
+
+func TestBestGuess(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   game.Rules
+		secret  string
+		guesses []string
+	}{
+		{"unique", game.Rules{Length: 3}, "012", []string{"345"}},
+		{"duplicates", game.Rules{Length: 3, AllowDuplicates: true}, "112", []string{"123"}},
+		{"hard mode", game.Rules{Length: 3, HardMode: true}, "012", []string{"345", "102"}},
+		{"hard mode, duplicates", game.Rules{Length: 3, AllowDuplicates: true, HardMode: true}, "112", []string{"111"}},
+		{"one left", game.Rules{Length: 3}, "789", []string{"987", "879", "798"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := mustResolve(t, tt.rules)
+			guesses := feedback(rules, tt.secret, tt.guesses)
+			cands := bruteForce(rules, guesses)
+			got, ok := New(rules).BestGuess(guesses, mrand.New(mrand.NewSource(1)))
+			if !ok {
+				t.Fatalf("BestGuess gave up")
+			}
+			if !rules.IsValidSecret(got) {
+				t.Fatalf("BestGuess = %q, which is not a legal code", got)
+			}
+			if rules.HardMode && !game.IsConsistent(rules, got, guesses) {
+				t.Errorf("BestGuess = %q, which hard mode forbids", got)
+			}
+			if len(cands) == 1 && got != cands[0] {
+				t.Errorf("BestGuess = %q; want the last candidate %q", got, cands[0])
+			}
+			// Every candidate is in the pool when there are this few, so none
+			// may leave a worse worst case than the guess picked.
+			worst := worstCase(rules, got, cands)
+			for _, c := range cands {
+				if w := worstCase(rules, c, cands); w < worst {
+					t.Errorf("BestGuess = %q leaves %d in the worst case; %q leaves %d", got, worst, c, w)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules game.Rules
+	}{
+		{"unique", game.Rules{}},
+		{"duplicates", game.Rules{AllowDuplicates: true}},
+		{"hard mode", game.Rules{Length: 3, HardMode: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := mustResolve(t, tt.rules)
+			gs := playGame(t, rules, 1)
+			a, err := Analyze(gs)
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if len(a.Players) != 2 {
+				t.Fatalf("%d player analyses; want 2", len(a.Players))
+			}
+
+			pa := a.Players[0]
+			history := gs.Players[0].GuessesAt(game.Player2)
+			if len(pa.Guesses) != len(history) {
+				t.Fatalf("%d guesses analysed; want %d", len(pa.Guesses), len(history))
+			}
+			if pa.Guesses[0].CandidatesBefore != rules.SpaceSize() {
+				t.Errorf("first guess starts from %v candidates; want %v", pa.Guesses[0].CandidatesBefore, rules.SpaceSize())
+			}
+			for k, ga := range pa.Guesses {
+				// The history is newest first; the analysis oldest first.
+				want := len(bruteForce(rules, history[len(history)-1-k:]))
+				if !ga.Exact || ga.CandidatesAfter != float64(want) {
+					t.Errorf("guess %d (%s): %v candidates after, exact %v; want %d", k+1, ga.Code, ga.CandidatesAfter, ga.Exact, want)
+				}
+			}
+			if last := pa.Guesses[len(pa.Guesses)-1]; last.CandidatesAfter != 1 {
+				t.Errorf("the cracking guess leaves %v candidates; want 1", last.CandidatesAfter)
+			}
+			if want := math.Log2(rules.SpaceSize()); math.Abs(pa.TotalBits-want) > 1e-9 {
+				t.Errorf("cracking took %v bits; want %v", pa.TotalBits, want)
+			}
+		})
+	}
+
+	t.Run("not completed", func(t *testing.T) {
+		if _, err := Analyze(game.NewGame("TEST", mustResolve(t, game.Rules{}))); err == nil {
+			t.Errorf("Analyze succeeded on a game still being played")
+		}
+	})
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		rules game.Rules
+	}{
+		{"4 digits unique", game.Rules{}},
+		{"6 hex with duplicates", game.Rules{Length: 6, Alphabet: game.AlphabetHex, AllowDuplicates: true}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			rules := mustResolve(b, bm.rules)
+			gs := playGame(b, rules, 1)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Analyze(gs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func mustResolve(tb testing.TB, r game.Rules) game.Rules {
+	tb.Helper()
+	resolved, err := r.Resolve()
+	if err != nil {
+		tb.Fatalf("Resolve(%+v): %v", r, err)
+	}
+	return resolved
+}
+
+// feedback scores codes against secret, newest first as games keep them.
+func feedback(rules game.Rules, secret string, codes []string) []game.Guess {
+	guesses := make([]game.Guess, len(codes))
+	for i, c := range codes {
+		bulls, cows := rules.Score(c, secret)
+		guesses[len(codes)-1-i] = game.Guess{Code: c, Bulls: bulls, Cows: cows}
+	}
+	return guesses
+}
+
+// bruteForce lists, in order, every legal code consistent with guesses.
+func bruteForce(rules game.Rules, guesses []game.Guess) []string {
+	symbols := []rune(rules.Symbols)
+	var out []string
+	code := make([]rune, rules.Length)
+	var fill func(pos int)
+	fill = func(pos int) {
+		if pos == rules.Length {
+			c := string(code)
+			if rules.IsValidSecret(c) && game.IsConsistent(rules, c, guesses) {
+				out = append(out, c)
+			}
+			return
+		}
+		for _, r := range symbols {
+			code[pos] = r
+			fill(pos + 1)
+		}
+	}
+	fill(0)
+	sort.Strings(out)
+	return out
+}
+
+// worstCase is the most candidates guess could leave, by brute force.
+func worstCase(rules game.Rules, guess string, cands []string) int {
+	buckets := make(map[[2]int]int)
+	worst := 0
+	for _, c := range cands {
+		bulls, cows := rules.Score(guess, c)
+		buckets[[2]int{bulls, cows}]++
+		worst = max(worst, buckets[[2]int{bulls, cows}])
+	}
+	return worst
+}
+
+// playGame plays a game to the end in which the first player always
+// guesses a code consistent with their feedback, and so cracks the second
+// player's code first, while the second guesses at random.
+func playGame(tb testing.TB, rules game.Rules, seed int64) *game.GameState {
+	tb.Helper()
+	rng := mrand.New(mrand.NewSource(seed))
+	s := New(rules)
+	gs := game.NewGame("TEST", rules)
+	gs.Join("solver")
+	gs.Join("random")
+	gs.SetSecret(game.Player1, rules.RandomCode(rng))
+	gs.SetSecret(game.Player2, rules.RandomCode(rng))
+
+	for turns := 0; gs.Status == "active"; turns++ {
+		if turns > 200 {
+			tb.Fatalf("game not over after %d turns", turns)
+		}
+		guess := rules.RandomCode(rng)
+		if gs.Turn == game.Player1 {
+			var ok bool
+			if guess, ok = s.FindConsistent(gs.Players[0].Guesses, rng); !ok {
+				tb.Fatalf("no code fits the solver's feedback")
+			}
+		}
+		gs.MakeGuess(gs.Turn, "", guess)
+	}
+	if gs.Winner != string(game.Player1) {
+		tb.Fatalf("winner %q; want the solver", gs.Winner)
+	}
+	return gs
+}
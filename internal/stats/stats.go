@@ -0,0 +1,397 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+const (
+	Win  = "win"
+	Loss = "loss"
+	Draw = "draw"
+)
+
+// Result is how one finished game went, reduced to what the statistics
+// need.
+type Result struct {
+	GameID    string `json:"gameId"`
+	RoomCode  string `json:"roomCode"`
+	EndedAt   int64  `json:"endedAt"`
+	EndReason string `json:"endReason"`
+	Sides     []Side `json:"sides"`
+}
+
+// Side is one seat's outcome. Team sides list every member.
+type Side struct {
+	Names []string `json:"names"`
+	// UserIDs holds the account of each of Names, or "" for a guest.
+	UserIDs []string `json:"userIds,omitempty"`
+	Outcome string   `json:"outcome"`
+	Guesses int      `json:"guesses"`
+	// CrackMs is how long after the start the side cracked a code, or 0 if
+	// it never did.
+	CrackMs int64 `json:"crackMs,omitempty"`
+}
+
+// NewResult sums up a finished game. userID returns the ID of the account
+// a player's name belongs to, or "" for a guest.
+func NewResult(gs *game.GameState, userID func(name string) string) Result {
+	r := Result{
+		GameID:    gs.GameID,
+		RoomCode:  gs.RoomCode,
+		EndedAt:   gs.EndedAt,
+		EndReason: gs.EndReason,
+	}
+	for _, p := range gs.Players {
+		side := Side{Names: []string{p.Name}, Guesses: len(p.Guesses), Outcome: Loss}
+		if len(p.Members) > 0 {
+			side.Names = p.Members
+		}
+		side.UserIDs = userIDs(side.Names, userID)
+		switch {
+		case gs.Winner == game.Draw:
+			side.Outcome = Draw
+		case gs.Winner == string(p.ID):
+			side.Outcome = Win
+		}
+		// Guesses are kept newest first, so the side's first crack is its
+		// last exact guess. In a free-for-all it may have cracked several.
+		if p.Solved && gs.StartedAt > 0 {
+			for i := len(p.Guesses) - 1; i >= 0; i-- {
+				if p.Guesses[i].Bulls == gs.Rules.Length {
+					side.CrackMs = max(p.Guesses[i].Timestamp-gs.StartedAt, 0)
+					break
+				}
+			}
+		}
+		r.Sides = append(r.Sides, side)
+	}
+	return r
+}
+
+func userIDs(names []string, userID func(name string) string) []string {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = userID(name)
+	}
+	return ids
+}
+
+// player keys a side's i'th player: by account when they have one,
+// otherwise case-insensitively by name.
+func (s Side) player(i int) string {
+	if i < len(s.UserIDs) && s.UserIDs[i] != "" {
+		return "user:" + s.UserIDs[i]
+	}
+	return guestKey(s.Names[i])
+}
+
+func guestKey(name string) string {
+	return "guest:" + strings.ToLower(strings.TrimSpace(name))
+}
+
+// Store keeps every result in memory to compute statistics from, and
+// appends them to a JSON lines file in the background so that recording a
+// game never waits on the disk.
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	results []Result
+	seen    map[string]bool
+	// pending holds the lines not yet written.
+	pending [][]byte
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		seen: make(map[string]bool),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		go s.write()
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read results: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		s.add(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read results: %v", err)
+	}
+	go s.write()
+	return s, nil
+}
+
+func (s *Store) add(r Result) {
+	s.seen[r.GameID] = true
+	s.results = append(s.results, r)
+}
+
+// Record saves a result, once per game.
+func (s *Store) Record(r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[r.GameID] {
+		return
+	}
+	s.add(r)
+	if s.closed {
+		return
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		slog.Error("error marshalling game result", "game", r.GameID, "error", err)
+		return
+	}
+	s.pending = append(s.pending, append(line, '\n'))
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close writes out whatever is still pending. Results recorded after Close
+// count towards the statistics but are not saved.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.wake)
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *Store) write() {
+	defer close(s.done)
+	for range s.wake {
+		s.flush()
+	}
+	s.flush()
+}
+
+func (s *Store) flush() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		slog.Error("failed to save game results", "path", s.path, "error", err)
+		return
+	}
+	defer f.Close()
+	for _, line := range batch {
+		if _, err := f.Write(line); err != nil {
+			slog.Error("failed to save game results", "path", s.path, "error", err)
+			return
+		}
+	}
+}
+
+// PlayerStats sums up a player's games within a time window.
+type PlayerStats struct {
+	UserID  string  `json:"userId,omitempty"`
+	Name    string  `json:"name"`
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	Losses  int     `json:"losses"`
+	Draws   int     `json:"draws"`
+	WinRate float64 `json:"winRate"`
+	// AvgGuesses is the average number of guesses in games the player
+	// cracked a code in.
+	AvgGuesses     float64 `json:"avgGuesses"`
+	FastestCrackMs int64   `json:"fastestCrackMs,omitempty"`
+	// Streak counts the player's latest run of wins, or of losses as a
+	// negative number. A draw ends either.
+	Streak     int          `json:"streak"`
+	Rating     float64      `json:"rating,omitempty"`
+	HeadToHead []HeadToHead `json:"headToHead,omitempty"`
+	cracks     int
+	guesses    int
+	opponents  map[string]*HeadToHead
+}
+
+// HeadToHead is a player's record against one opponent.
+type HeadToHead struct {
+	Opponent string `json:"opponent"`
+	Games    int    `json:"games"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+	Draws    int    `json:"draws"`
+}
+
+func (h *HeadToHead) add(outcome string) {
+	h.Games++
+	switch outcome {
+	case Win:
+		h.Wins++
+	case Loss:
+		h.Losses++
+	default:
+		h.Draws++
+	}
+}
+
+// Leaderboard ranks everyone who played since the given time by wins, then
+// win rate, then games played.
+func (s *Store) Leaderboard(since time.Time) []PlayerStats {
+	players := s.compute(since)
+	list := make([]PlayerStats, 0, len(players))
+	for _, p := range players {
+		p.HeadToHead = nil
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch {
+		case a.Wins != b.Wins:
+			return a.Wins > b.Wins
+		case a.WinRate != b.WinRate:
+			return a.WinRate > b.WinRate
+		case a.Games != b.Games:
+			return a.Games > b.Games
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return list
+}
+
+// Player returns one player's statistics since the given time, with their
+// head-to-head records most played first. userID picks out a registered
+// player; guests, with none, are looked up by name.
+func (s *Store) Player(userID, name string, since time.Time) (PlayerStats, bool) {
+	k := guestKey(name)
+	if userID != "" {
+		k = "user:" + userID
+	}
+	p, ok := s.compute(since)[k]
+	if !ok {
+		return PlayerStats{}, false
+	}
+	for _, h := range p.opponents {
+		p.HeadToHead = append(p.HeadToHead, *h)
+	}
+	sort.Slice(p.HeadToHead, func(i, j int) bool {
+		if p.HeadToHead[i].Games != p.HeadToHead[j].Games {
+			return p.HeadToHead[i].Games > p.HeadToHead[j].Games
+		}
+		return p.HeadToHead[i].Opponent < p.HeadToHead[j].Opponent
+	})
+	return *p, true
+}
+
+func (s *Store) compute(since time.Time) map[string]*PlayerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := since.UnixMilli()
+	players := make(map[string]*PlayerStats)
+	// Results are appended as games finish, so walking them in order lets
+	// the streak simply follow the latest games.
+	for _, r := range s.results {
+		if r.EndedAt < cutoff {
+			continue
+		}
+		for i, side := range r.Sides {
+			for j, name := range side.Names {
+				k := side.player(j)
+				p, ok := players[k]
+				if !ok {
+					p = &PlayerStats{Name: name, opponents: make(map[string]*HeadToHead)}
+					if j < len(side.UserIDs) {
+						p.UserID = side.UserIDs[j]
+					}
+					players[k] = p
+				}
+				p.addGame(r, i, side)
+			}
+		}
+	}
+
+	for _, p := range players {
+		if p.Games > 0 {
+			p.WinRate = float64(p.Wins) / float64(p.Games)
+		}
+		if p.cracks > 0 {
+			p.AvgGuesses = float64(p.guesses) / float64(p.cracks)
+		}
+	}
+	return players
+}
+
+func (p *PlayerStats) addGame(r Result, seat int, side Side) {
+	p.Games++
+	switch side.Outcome {
+	case Win:
+		p.Wins++
+		p.Streak = max(p.Streak, 0) + 1
+	case Loss:
+		p.Losses++
+		p.Streak = min(p.Streak, 0) - 1
+	default:
+		p.Draws++
+		p.Streak = 0
+	}
+
+	if side.CrackMs > 0 {
+		p.cracks++
+		p.guesses += side.Guesses
+		if p.FastestCrackMs == 0 || side.CrackMs < p.FastestCrackMs {
+			p.FastestCrackMs = side.CrackMs
+		}
+	}
+
+	// Against each opponent a free-for-all counts as a draw unless one of
+	// the two won it.
+	for j, other := range r.Sides {
+		if j == seat {
+			continue
+		}
+		outcome := side.Outcome
+		if outcome == Loss && other.Outcome != Win {
+			outcome = Draw
+		}
+		for k, name := range other.Names {
+			h, ok := p.opponents[other.player(k)]
+			if !ok {
+				h = &HeadToHead{Opponent: name}
+				p.opponents[other.player(k)] = h
+			}
+			h.add(outcome)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package tournament
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+type Format string
+
+const (
+	SingleElimination Format = "single_elimination"
+	DoubleElimination Format = "double_elimination"
+	RoundRobin        Format = "round_robin"
+)
+
+const (
+	MinPlayers = 2
+	MaxPlayers = 32
+)
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+)
+
+// Match statuses. A pending match is waiting for its players to be decided,
+// a ready one can be played, and a skipped one turned out not to be needed.
+const (
+	MatchPending = "pending"
+	MatchReady   = "ready"
+	MatchDone    = "done"
+	MatchSkipped = "skipped"
+)
+
+const (
+	BracketWinners = "winners"
+	BracketLosers  = "losers"
+	BracketFinal   = "final"
+)
+
+var (
+	ErrUnknownMatch = errors.New("no such match")
+	ErrNotPlayable  = errors.New("that match is not being played")
+	ErrNotInMatch   = errors.New("the winner is not in that match")
+	ErrNeedsWinner  = errors.New("elimination matches cannot be drawn")
+)
+
+// Source feeds a match slot from an earlier match: its winner, or its loser
+// in a double elimination's losers bracket.
+type Source struct {
+	Match string `json:"match"`
+	Loser bool   `json:"loser,omitempty"`
+}
+
+// Match is one pairing in the tournament. An empty player in a decided slot
+// is a bye.
+type Match struct {
+	ID       string     `json:"id"`
+	Bracket  string     `json:"bracket,omitempty"`
+	Round    int        `json:"round"`
+	Players  [2]string  `json:"players"`
+	From     [2]*Source `json:"from"`
+	Status   string     `json:"status"`
+	Winner   string     `json:"winner,omitempty"`
+	Loser    string     `json:"loser,omitempty"`
+	Draw     bool       `json:"draw,omitempty"`
+	RoomCode string     `json:"roomCode,omitempty"`
+}
+
+func (m *Match) decided() bool {
+	return m.Status == MatchDone || m.Status == MatchSkipped
+}
+
+// Standing is a player's record in a round robin. A win is worth a point and
+// a draw half of one.
+type Standing struct {
+	Name   string  `json:"name"`
+	Played int     `json:"played"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Draws  int     `json:"draws"`
+	Points float64 `json:"points"`
+}
+
+type Tournament struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Organizer string     `json:"organizer"`
+	Format    Format     `json:"format"`
+	Rules     game.Rules `json:"rules"`
+	Roster    []string   `json:"roster"`
+	Status    string     `json:"status"`
+	Champion  string     `json:"champion,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Matches   []*Match   `json:"matches"`
+	Standings []Standing `json:"standings,omitempty"`
+}
+
+// New draws up the bracket for roster, seeded in the order given.
+func New(name, organizer string, format Format, roster []string, rules game.Rules) (*Tournament, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("the tournament needs a name")
+	}
+	if len(roster) < MinPlayers || len(roster) > MaxPlayers {
+		return nil, fmt.Errorf("a tournament needs %d to %d players", MinPlayers, MaxPlayers)
+	}
+	seen := make(map[string]bool)
+	for i, p := range roster {
+		roster[i] = strings.TrimSpace(p)
+		if roster[i] == "" {
+			return nil, errors.New("every player needs a name")
+		}
+		if seen[strings.ToLower(roster[i])] {
+			return nil, fmt.Errorf("%s is on the roster twice", roster[i])
+		}
+		seen[strings.ToLower(roster[i])] = true
+	}
+
+	t := &Tournament{
+		ID:        newID(),
+		Name:      name,
+		Organizer: organizer,
+		Format:    format,
+		Rules:     rules,
+		Roster:    roster,
+		Status:    StatusRunning,
+		CreatedAt: time.Now(),
+	}
+	switch format {
+	case SingleElimination:
+		t.buildElimination(false)
+	case DoubleElimination:
+		t.buildElimination(true)
+	case RoundRobin:
+		t.buildRoundRobin()
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	t.resolve()
+	return t, nil
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (t *Tournament) Match(id string) *Match {
+	for _, m := range t.Matches {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// Playable lists the ready matches that have no room yet.
+func (t *Tournament) Playable() []*Match {
+	var list []*Match
+	for _, m := range t.Matches {
+		if m.Status == MatchReady && m.RoomCode == "" {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// Report records the result of a match, winner being empty for a draw, and
+// moves everyone on to their next matches.
+func (t *Tournament) Report(matchID, winner string) error {
+	m := t.Match(matchID)
+	if m == nil {
+		return ErrUnknownMatch
+	}
+	if m.Status != MatchReady {
+		return ErrNotPlayable
+	}
+
+	switch {
+	case winner == "" && t.Format != RoundRobin:
+		return ErrNeedsWinner
+	case winner == "":
+		m.Draw = true
+	case strings.EqualFold(winner, m.Players[0]):
+		m.Winner, m.Loser = m.Players[0], m.Players[1]
+	case strings.EqualFold(winner, m.Players[1]):
+		m.Winner, m.Loser = m.Players[1], m.Players[0]
+	default:
+		return ErrNotInMatch
+	}
+	m.Status = MatchDone
+	t.resolve()
+	return nil
+}
+
+// Clone copies the tournament so it can be read while play goes on.
+func (t *Tournament) Clone() *Tournament {
+	c := *t
+	c.Roster = append([]string(nil), t.Roster...)
+	c.Standings = append([]Standing(nil), t.Standings...)
+	c.Matches = make([]*Match, len(t.Matches))
+	for i, m := range t.Matches {
+		mc := *m
+		c.Matches[i] = &mc
+	}
+	return &c
+}
+
+// resolve fills in every slot whose feeding match has finished, passes byes
+// straight through and readies whatever can now be played.
+func (t *Tournament) resolve() {
+	if t.Format == RoundRobin {
+		t.resolveRoundRobin()
+		return
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, m := range t.Matches {
+			if m.Status != MatchPending || !t.slotsDecided(m) {
+				continue
+			}
+			changed = true
+			for i, src := range m.From {
+				if src != nil {
+					m.Players[i] = t.result(src)
+				}
+			}
+			if m.ID == resetMatch {
+				// The reset is only played if the grand final went to the
+				// player from the losers bracket.
+				gf := t.Match(grandFinal)
+				if gf.Winner == gf.Players[0] || gf.Players[1] == "" {
+					m.Status = MatchSkipped
+					continue
+				}
+			}
+			switch {
+			case m.Players[0] == "" || m.Players[1] == "":
+				m.Winner = m.Players[0] + m.Players[1]
+				m.Status = MatchDone
+			default:
+				m.Status = MatchReady
+			}
+		}
+	}
+
+	final := t.Matches[len(t.Matches)-1]
+	if final.Status == MatchSkipped {
+		final = t.Match(grandFinal)
+	}
+	if final.decided() {
+		t.Status = StatusCompleted
+		t.Champion = final.Winner
+	}
+}
+
+func (t *Tournament) slotsDecided(m *Match) bool {
+	for _, src := range m.From {
+		if src != nil && !t.Match(src.Match).decided() {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tournament) result(src *Source) string {
+	m := t.Match(src.Match)
+	if src.Loser {
+		return m.Loser
+	}
+	return m.Winner
+}
+
+const (
+	grandFinal = "GF1"
+	resetMatch = "GF2"
+)
+
+// buildElimination lays out a knockout bracket. Byes make up the numbers to
+// a power of two and go to the top seeds. A double elimination adds a
+// losers bracket and a grand final, replayed if the losers' champion wins.
+func (t *Tournament) buildElimination(double bool) {
+	size, rounds := 1, 0
+	for size < len(t.Roster) {
+		size *= 2
+		rounds++
+	}
+
+	var prev []*Match
+	winners := make([][]*Match, rounds+1)
+	for round := 1; round <= rounds; round++ {
+		var cur []*Match
+		for i := 0; i < size>>round; i++ {
+			m := &Match{
+				ID:      fmt.Sprintf("W%d.%d", round, i+1),
+				Bracket: BracketWinners,
+				Round:   round,
+				Status:  MatchPending,
+			}
+			if round == 1 {
+				order := seedOrder(size)
+				m.Players = [2]string{t.seed(order[2*i]), t.seed(order[2*i+1])}
+			} else {
+				m.From = [2]*Source{{Match: prev[2*i].ID}, {Match: prev[2*i+1].ID}}
+			}
+			cur = append(cur, m)
+			t.Matches = append(t.Matches, m)
+		}
+		winners[round] = cur
+		prev = cur
+	}
+	if !double {
+		return
+	}
+
+	// The losers bracket alternates between rounds among its own survivors
+	// and rounds where they meet the players just knocked out of the
+	// winners bracket, fed in reverse to put off rematches.
+	losersRound := 0
+	addLosers := func(from [][2]*Source) []*Match {
+		losersRound++
+		var cur []*Match
+		for i, f := range from {
+			m := &Match{
+				ID:      fmt.Sprintf("L%d.%d", losersRound, i+1),
+				Bracket: BracketLosers,
+				Round:   losersRound,
+				From:    f,
+				Status:  MatchPending,
+			}
+			cur = append(cur, m)
+			t.Matches = append(t.Matches, m)
+		}
+		return cur
+	}
+	losersOf := func(ms []*Match, reverse bool) []*Source {
+		src := make([]*Source, len(ms))
+		for i, m := range ms {
+			j := i
+			if reverse {
+				j = len(ms) - 1 - i
+			}
+			src[j] = &Source{Match: m.ID, Loser: true}
+		}
+		return src
+	}
+
+	var champion *Source
+	if rounds == 1 {
+		champion = &Source{Match: winners[1][0].ID, Loser: true}
+	} else {
+		first := losersOf(winners[1], false)
+		var pairs [][2]*Source
+		for i := 0; i < len(first); i += 2 {
+			pairs = append(pairs, [2]*Source{first[i], first[i+1]})
+		}
+		lb := addLosers(pairs)
+		for round := 2; round <= rounds; round++ {
+			dropped := losersOf(winners[round], round%2 == 0)
+			pairs = nil
+			for i, m := range lb {
+				pairs = append(pairs, [2]*Source{{Match: m.ID}, dropped[i]})
+			}
+			lb = addLosers(pairs)
+			if round < rounds {
+				pairs = nil
+				for i := 0; i < len(lb); i += 2 {
+					pairs = append(pairs, [2]*Source{{Match: lb[i].ID}, {Match: lb[i+1].ID}})
+				}
+				lb = addLosers(pairs)
+			}
+		}
+		champion = &Source{Match: lb[0].ID}
+	}
+
+	gf := &Match{
+		ID:      grandFinal,
+		Bracket: BracketFinal,
+		Round:   1,
+		From:    [2]*Source{{Match: winners[rounds][0].ID}, champion},
+		Status:  MatchPending,
+	}
+	reset := &Match{
+		ID:      resetMatch,
+		Bracket: BracketFinal,
+		Round:   2,
+		From:    [2]*Source{{Match: grandFinal}, {Match: grandFinal, Loser: true}},
+		Status:  MatchPending,
+	}
+	t.Matches = append(t.Matches, gf, reset)
+}
+
+func (t *Tournament) seed(n int) string {
+	if n > len(t.Roster) {
+		return ""
+	}
+	return t.Roster[n-1]
+}
+
+// seedOrder lists seeds 1 to size in bracket order, so that the top seeds
+// can only meet in the later rounds.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+// buildRoundRobin pairs everyone with everyone by the circle method, one
+// round at a time.
+func (t *Tournament) buildRoundRobin() {
+	players := append([]string(nil), t.Roster...)
+	if len(players)%2 == 1 {
+		players = append(players, "")
+	}
+	n := len(players)
+	for round := 1; round < n; round++ {
+		index := 0
+		for i := 0; i < n/2; i++ {
+			a, b := players[i], players[n-1-i]
+			if a == "" || b == "" {
+				continue
+			}
+			index++
+			t.Matches = append(t.Matches, &Match{
+				ID:      fmt.Sprintf("R%d.%d", round, index),
+				Round:   round,
+				Players: [2]string{a, b},
+				Status:  MatchPending,
+			})
+		}
+		// Keep the first player fixed and rotate everyone else.
+		players = append(players[:1], append([]string{players[n-1]}, players[1:n-1]...)...)
+	}
+}
+
+// resolveRoundRobin opens the next round once the current one is over and
+// keeps the standings up to date.
+func (t *Tournament) resolveRoundRobin() {
+	current := 0
+	for _, m := range t.Matches {
+		if !m.decided() && (current == 0 || m.Round < current) {
+			current = m.Round
+		}
+	}
+	for _, m := range t.Matches {
+		if m.Round == current && m.Status == MatchPending {
+			m.Status = MatchReady
+		}
+	}
+
+	table := make(map[string]*Standing)
+	for _, name := range t.Roster {
+		table[name] = &Standing{Name: name}
+	}
+	for _, m := range t.Matches {
+		if m.Status != MatchDone {
+			continue
+		}
+		for _, p := range m.Players {
+			s := table[p]
+			s.Played++
+			switch {
+			case m.Draw:
+				s.Draws++
+				s.Points += 0.5
+			case m.Winner == p:
+				s.Wins++
+				s.Points++
+			default:
+				s.Losses++
+			}
+		}
+	}
+	t.Standings = t.Standings[:0]
+	for _, name := range t.Roster {
+		t.Standings = append(t.Standings, *table[name])
+	}
+	sort.SliceStable(t.Standings, func(i, j int) bool {
+		a, b := t.Standings[i], t.Standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Wins > b.Wins
+	})
+
+	if current == 0 {
+		t.Status = StatusCompleted
+		t.Champion = t.Standings[0].Name
+	}
+}
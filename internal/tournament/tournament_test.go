@@ -0,0 +1,251 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+func TestNewRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		title  string
+		format Format
+		roster []string
+	}{
+		{"no name", " ", SingleElimination, []string{"a", "b"}},
+		{"one player", "Cup", SingleElimination, []string{"a"}},
+		{"too many players", "Cup", RoundRobin, roster(MaxPlayers + 1)},
+		{"blank player", "Cup", SingleElimination, []string{"a", " "}},
+		{"same player twice", "Cup", DoubleElimination, []string{"Alice", "alice"}},
+		{"unknown format", "Cup", "swiss", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.title, "org", tt.format, tt.roster, game.Rules{}); err == nil {
+				t.Errorf("New(%q, %s, %v) succeeded; want an error", tt.title, tt.format, tt.roster)
+			}
+		})
+	}
+}
+
+func TestNewBracket(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		players int
+		matches int
+		// ready lists the matches that can be played straight away, and
+		// byes how many were settled by a bye.
+		ready []string
+		byes  int
+	}{
+		{"single, two players", SingleElimination, 2, 1, []string{"W1.1"}, 0},
+		{"single, bye for the top seed", SingleElimination, 3, 3, []string{"W1.2"}, 1},
+		{"single, byes meet in round two", SingleElimination, 5, 7, []string{"W1.2", "W2.2"}, 3},
+		{"single, full bracket", SingleElimination, 8, 7, []string{"W1.1", "W1.2", "W1.3", "W1.4"}, 0},
+		{"double, two players", DoubleElimination, 2, 3, []string{"W1.1"}, 0},
+		{"double, bye for the top seed", DoubleElimination, 3, 7, []string{"W1.2"}, 1},
+		{"double, four players", DoubleElimination, 4, 7, []string{"W1.1", "W1.2"}, 0},
+		{"double, eight players", DoubleElimination, 8, 15, []string{"W1.1", "W1.2", "W1.3", "W1.4"}, 0},
+		{"round robin, even", RoundRobin, 4, 6, []string{"R1.1", "R1.2"}, 0},
+		{"round robin, odd sits one out", RoundRobin, 5, 10, []string{"R1.1", "R1.2"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := mustNew(t, tt.format, tt.players)
+			if len(tr.Matches) != tt.matches {
+				t.Errorf("%d matches; want %d", len(tr.Matches), tt.matches)
+			}
+			var ready []string
+			for _, m := range tr.Playable() {
+				ready = append(ready, m.ID)
+			}
+			if !reflect.DeepEqual(ready, tt.ready) {
+				t.Errorf("ready matches = %v; want %v", ready, tt.ready)
+			}
+			byes := 0
+			for _, m := range tr.Matches {
+				if m.Status == MatchDone {
+					byes++
+				}
+			}
+			if byes != tt.byes {
+				t.Errorf("%d byes; want %d", byes, tt.byes)
+			}
+		})
+	}
+}
+
+func TestPlayThrough(t *testing.T) {
+	// resetThenFavourite has the losers' champion take the grand final, so
+	// that it has to be replayed, and the favourite win everything else.
+	resetThenFavourite := func(m *Match) string {
+		if m.ID == grandFinal {
+			return m.Players[1]
+		}
+		return favourite(m)
+	}
+	// dominantP4 wins every match it plays and every other one is drawn.
+	dominantP4 := func(m *Match) string {
+		if m.Players[0] == "P4" || m.Players[1] == "P4" {
+			return "P4"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name     string
+		format   Format
+		players  int
+		pick     func(m *Match) string
+		champion string
+		played   int
+		reset    bool
+	}{
+		{"single, favourites", SingleElimination, 8, favourite, "P1", 7, false},
+		{"single, underdogs", SingleElimination, 8, underdog, "P8", 7, false},
+		{"single, with byes", SingleElimination, 5, favourite, "P1", 4, false},
+		{"double, favourites", DoubleElimination, 4, favourite, "P1", 6, false},
+		{"double, with a bye", DoubleElimination, 3, favourite, "P1", 4, false},
+		{"double, eight players", DoubleElimination, 8, favourite, "P1", 14, false},
+		{"double, winners' champion loses once", DoubleElimination, 2, underdog, "P2", 2, false},
+		{"double, grand final reset", DoubleElimination, 4, resetThenFavourite, "P1", 7, true},
+		{"double, reset with two players", DoubleElimination, 2, resetThenFavourite, "P1", 3, true},
+		{"round robin, favourites", RoundRobin, 4, favourite, "P1", 6, false},
+		{"round robin, odd", RoundRobin, 5, underdog, "P5", 10, false},
+		{"round robin, draws", RoundRobin, 4, dominantP4, "P4", 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := mustNew(t, tt.format, tt.players)
+			played := play(t, tr, tt.pick)
+			if tr.Status != StatusCompleted {
+				t.Fatalf("status %q; want %q", tr.Status, StatusCompleted)
+			}
+			if tr.Champion != tt.champion {
+				t.Errorf("champion %q; want %q", tr.Champion, tt.champion)
+			}
+			if played != tt.played {
+				t.Errorf("%d matches played; want %d", played, tt.played)
+			}
+			if reset := tr.Match(resetMatch); reset != nil {
+				if got := reset.Status == MatchDone; got != tt.reset {
+					t.Errorf("reset played = %v; want %v", got, tt.reset)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundRobinStandings(t *testing.T) {
+	tr := mustNew(t, RoundRobin, 4)
+	play(t, tr, func(m *Match) string {
+		if m.Players[0] == "P4" || m.Players[1] == "P4" {
+			return "P4"
+		}
+		return ""
+	})
+	want := []Standing{
+		{Name: "P4", Played: 3, Wins: 3, Points: 3},
+		{Name: "P1", Played: 3, Losses: 1, Draws: 2, Points: 1},
+		{Name: "P2", Played: 3, Losses: 1, Draws: 2, Points: 1},
+		{Name: "P3", Played: 3, Losses: 1, Draws: 2, Points: 1},
+	}
+	if !reflect.DeepEqual(tr.Standings, want) {
+		t.Errorf("standings = %+v; want %+v", tr.Standings, want)
+	}
+}
+
+func TestReport(t *testing.T) {
+	tr := mustNew(t, SingleElimination, 4)
+	steps := []struct {
+		name    string
+		match   string
+		winner  string
+		wantErr error
+	}{
+		{"unknown match", "W9.9", "P1", ErrUnknownMatch},
+		{"final not decided yet", "W2.1", "P1", ErrNotPlayable},
+		{"winner not in the match", "W1.1", "P2", ErrNotInMatch},
+		{"elimination needs a winner", "W1.1", "", ErrNeedsWinner},
+		{"winner named in any case", "W1.1", "p1", nil},
+		{"reported twice", "W1.1", "P1", ErrNotPlayable},
+		// The hub reports a forfeit as a win for the player left standing.
+		{"forfeit sends the winner on", "W1.2", "P3", nil},
+	}
+	for _, s := range steps {
+		if err := tr.Report(s.match, s.winner); !errors.Is(err, s.wantErr) {
+			t.Errorf("%s: Report(%q, %q) = %v; want %v", s.name, s.match, s.winner, err, s.wantErr)
+		}
+	}
+
+	if m := tr.Match("W1.1"); m.Winner != "P1" || m.Loser != "P4" {
+		t.Errorf("W1.1 winner %q, loser %q; want P1, P4", m.Winner, m.Loser)
+	}
+	final := tr.Match("W2.1")
+	if final.Players != [2]string{"P1", "P3"} || final.Status != MatchReady {
+		t.Errorf("final %v %s; want [P1 P3] %s", final.Players, final.Status, MatchReady)
+	}
+}
+
+func roster(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("P%d", i+1)
+	}
+	return names
+}
+
+func mustNew(t *testing.T, format Format, players int) *Tournament {
+	t.Helper()
+	tr, err := New("Cup", "org", format, roster(players), game.Rules{})
+	if err != nil {
+		t.Fatalf("New(%s, %d players): %v", format, players, err)
+	}
+	return tr
+}
+
+// play reports every match as it becomes ready, with pick naming the
+// winner, until the tournament is over, and returns how many were played.
+func play(t *testing.T, tr *Tournament, pick func(m *Match) string) int {
+	t.Helper()
+	played := 0
+	for tr.Status == StatusRunning {
+		ready := tr.Playable()
+		if len(ready) == 0 {
+			t.Fatalf("tournament stuck after %d matches", played)
+		}
+		for _, m := range ready {
+			if err := tr.Report(m.ID, pick(m)); err != nil {
+				t.Fatalf("Report(%s): %v", m.ID, err)
+			}
+			played++
+		}
+	}
+	return played
+}
+
+// favourite picks the better seed of a match, underdog the worse.
+func favourite(m *Match) string {
+	if seedOf(m.Players[0]) < seedOf(m.Players[1]) {
+		return m.Players[0]
+	}
+	return m.Players[1]
+}
+
+func underdog(m *Match) string {
+	if seedOf(m.Players[0]) > seedOf(m.Players[1]) {
+		return m.Players[0]
+	}
+	return m.Players[1]
+}
+
+func seedOf(name string) int {
+	n, _ := strconv.Atoi(name[1:])
+	return n
+}
@@ -0,0 +1,198 @@
+package websocket
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// VisibilityPublic rooms are listed in the lobby.
+	VisibilityPublic = "public"
+	// VisibilityUnlisted rooms are open to anyone who has the code.
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPrivate rooms also need the password or an invite.
+	VisibilityPrivate = "private"
+
+	maxPasswordLength = 72
+	defaultInviteTTL  = 24 * time.Hour
+	maxInviteTTL      = 7 * 24 * time.Hour
+)
+
+const (
+	msgPasswordRequired = "This room needs a password."
+	msgWrongPassword    = "Wrong password."
+	msgInviteExpired    = "This invite is invalid or has expired."
+)
+
+type InvitePayload struct {
+	// TTLMinutes is how long the invite stays valid, a day if unset.
+	TTLMinutes int `json:"ttlMinutes"`
+}
+
+// access decides who may enter a room. passwordHash is a bcrypt hash of a
+// private room's password.
+type access struct {
+	visibility   string
+	passwordHash []byte
+}
+
+// passwordCheck is how a password fared against a room, worked out off the
+// hub goroutine since bcrypt is too slow to run on it.
+type passwordCheck struct {
+	room *Room
+	ok   bool
+}
+
+// newAccess checks a new room's settings. A private room's password must
+// already have been hashed by hashPassword.
+func newAccess(visibility, password string, hash []byte) (access, error) {
+	switch visibility {
+	case "":
+		visibility = VisibilityPublic
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+	default:
+		return access{}, fmt.Errorf("unknown visibility %q", visibility)
+	}
+	if visibility != VisibilityPrivate {
+		return access{visibility: visibility}, nil
+	}
+	if password == "" {
+		return access{}, errors.New("a private room needs a password")
+	}
+	if len(password) > maxPasswordLength {
+		return access{}, fmt.Errorf("the password can be at most %d characters", maxPasswordLength)
+	}
+	return access{visibility: visibility, passwordHash: hash}, nil
+}
+
+// hashPassword hashes a private room's password in the background, then
+// hands the action back to the hub to create the room.
+func (h *Hub) hashPassword(action *RoomAction) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(action.Password), bcrypt.DefaultCost)
+	if err != nil {
+		slog.Error("failed to hash room password", "error", err)
+		sendError(action.Client, "Could not set the room password.")
+		return
+	}
+	action.passwordHash = hash
+	h.createRoom <- action
+}
+
+// Listed reports whether the room shows up in the lobby.
+func (r *Room) Listed() bool {
+	return r.access.visibility == VisibilityPublic
+}
+
+func (r *Room) Visibility() string {
+	return r.access.visibility
+}
+
+// admit checks a newcomer's password or invite against the room, turning
+// them away if neither lets them in. An invite lets its holder skip the
+// password. A password is checked in the background, admit turning the
+// newcomer away for now and the action coming back on retry once the check
+// is done. The caller must hold room.Mutex.
+func (h *Hub) admit(room *Room, action *RoomAction, retry chan<- *RoomAction) bool {
+	if room.access.visibility != VisibilityPrivate {
+		return true
+	}
+	c := action.Client
+	if action.Invite != "" {
+		if h.validInvite(room, action.Invite) {
+			return true
+		}
+		sendError(c, msgInviteExpired)
+		return false
+	}
+
+	msg := msgPasswordRequired
+	if action.Password != "" {
+		if action.checked == nil || action.checked.room != room {
+			hash := room.access.passwordHash
+			go func() {
+				action.checked = &passwordCheck{room: room, ok: passwordMatches(hash, action.Password)}
+				retry <- action
+			}()
+			return false
+		}
+		if action.checked.ok {
+			return true
+		}
+		msg = msgWrongPassword
+	}
+	sendError(c, msg)
+	h.sendEvent(c, "password_required", room.GameState.RoomCode)
+	return false
+}
+
+// passwordMatches checks password against a room's hash. It is slow, and
+// best kept off the hub goroutine.
+func passwordMatches(hash []byte, password string) bool {
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// createInvite hands a seated player a signed invite to the room that
+// expires after ttl. The caller must hold room.Mutex.
+func (h *Hub) createInvite(room *Room, c *Client, ttl time.Duration) {
+	if c.role == "spectator" {
+		sendError(c, "Only players can invite people.")
+		return
+	}
+	if ttl <= 0 {
+		ttl = defaultInviteTTL
+	}
+	ttl = min(ttl, maxInviteTTL)
+	expires := time.Now().Add(ttl).Unix()
+	token := strconv.FormatInt(expires, 10) + "." + h.signInvite(room, expires)
+	h.sendEvent(c, "invite", map[string]interface{}{
+		"roomCode":  room.GameState.RoomCode,
+		"token":     token,
+		"expiresAt": expires * 1000,
+	})
+}
+
+func (h *Hub) validInvite(room *Room, token string) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(h.signInvite(room, expires)))
+}
+
+// signInvite binds an invite to this room, not just its code, so an invite
+// to a closed room does not open a later one that reuses the code.
+func (h *Hub) signInvite(room *Room, expires int64) string {
+	mac := hmac.New(sha256.New, h.inviteKey)
+	fmt.Fprintf(mac, "%s.%d.%d", room.GameState.RoomCode, room.CreatedAt.UnixNano(), expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// UseInviteSecret derives the key invites are signed with from secret, so
+// that they survive a restart and work on every replica sharing the secret.
+// It must be called before the hub starts serving; without it the key is
+// random and invites only work on this process.
+func (h *Hub) UseInviteSecret(secret []byte) {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("room invites"))
+	h.inviteKey = mac.Sum(nil)
+}
+
+func newInviteKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/solver"
+)
+
+// analyzeGame runs the solver over a finished game off the hub goroutine and
+// sends the result to everyone still in the room.
+func (h *Hub) analyzeGame(room *Room, snapshot *game.GameState) {
+	analysis, err := solver.Analyze(snapshot)
+	if err != nil {
+		slog.Error("failed to analyse game", "room", snapshot.RoomCode, "error", err)
+		return
+	}
+
+	payload, err := json.Marshal(analysis)
+	if err != nil {
+		slog.Error("error marshalling analysis", "error", err)
+		return
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":    "analysis",
+		"payload": json.RawMessage(payload),
+	})
+
+	room.Mutex.Lock()
+	defer room.Mutex.Unlock()
+
+	// A rematch may have started, or even finished, while the solver ran.
+	if room.closed || room.GameState.GameID != snapshot.GameID {
+		return
+	}
+	room.Analysis = analysis
+	for client := range room.Clients {
+		client.trySend(msg)
+	}
+}
+
+var (
+	ErrNoAnalysis = errors.New("no completed game to analyse")
+	ErrRoomLocked = errors.New("this room needs its password or an invite")
+)
+
+// RoomAnalysis returns the analysis of the last completed game in a room.
+// A private room's is only given out with the password or an invite, as
+// anyone joining it would need.
+func (h *Hub) RoomAnalysis(code, password, invite string) (*solver.Analysis, error) {
+	room := h.lockRoom(code)
+	if room == nil {
+		return nil, ErrNoAnalysis
+	}
+	analysis := room.Analysis
+	locked := room.access.visibility == VisibilityPrivate &&
+		!(invite != "" && h.validInvite(room, invite))
+	hash := room.access.passwordHash
+	room.Mutex.Unlock()
+
+	// The password is checked without holding up the room.
+	if locked && (password == "" || !passwordMatches(hash, password)) {
+		return nil, ErrRoomLocked
+	}
+	if analysis == nil {
+		return nil, ErrNoAnalysis
+	}
+	return analysis, nil
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+const botPlayer = game.Player2
+
+// scheduleBot starts the bot's think timer if the bot can guess and is not
+// already thinking. The caller must hold room.Mutex.
+func (h *Hub) scheduleBot(room *Room) {
+	if room.Bot == nil || room.botPending || !room.GameState.CanGuess(botPlayer) {
+		return
+	}
+	room.botPending = true
+	moves := room.GameState.GuessCount()
+	time.AfterFunc(room.Bot.ThinkDelay, func() {
+		h.playBotTurn(room, moves)
+	})
+}
+
+// playBotTurn works out the bot's guess without holding the room lock, then
+// applies it only if nothing has moved on in the meantime.
+func (h *Hub) playBotTurn(room *Room, moves int) {
+	room.Mutex.Lock()
+	if !botCanMove(room, moves) {
+		room.botPending = false
+		room.Mutex.Unlock()
+		return
+	}
+	rules := room.GameState.Rules
+	history := append([]game.Guess{}, room.GameState.Player(botPlayer).Guesses...)
+	room.Mutex.Unlock()
+
+	guess := room.Bot.NextGuess(rules, history)
+
+	room.Mutex.Lock()
+	defer room.Mutex.Unlock()
+	room.botPending = false
+	if !botCanMove(room, moves) {
+		return
+	}
+
+	// The bot is held to the same rules as everyone else. A guess they
+	// turn down is thought over again.
+	if msg := guessError(room, botPlayer, game.Player1, guess); msg != "" {
+		slog.Warn("bot guess rejected", "room", room.GameState.RoomCode, "guess", guess, "reason", msg)
+		h.scheduleBot(room)
+		return
+	}
+
+	room.LastActivityAt = time.Now()
+	lastRound := room.GameState.LastRound
+	room.GameState.MakeGuess(botPlayer, game.Player1, guess)
+	h.announceRound(room, lastRound)
+	h.afterMove(room)
+	h.syncRoom(room)
+}
+
+func botCanMove(room *Room, moves int) bool {
+	return !room.closed && room.GameState.CanGuess(botPlayer) &&
+		room.GameState.GuessCount() == moves
+}
@@ -0,0 +1,225 @@
+package websocket
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	ChannelPlayers    = "players"
+	ChannelSpectators = "spectators"
+
+	maxChatLength = 280
+	// chatHistorySize is how many recent messages a room keeps to show
+	// people as they arrive.
+	chatHistorySize = 50
+)
+
+// newChatLimiter allows a short burst of messages, then one every two
+// seconds.
+func newChatLimiter() *rate.Limiter {
+	return rate.NewLimiter(rate.Every(2*time.Second), 4)
+}
+
+type ChatPayload struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
+type MutePayload struct {
+	Name string `json:"name"`
+}
+
+type ChatMessage struct {
+	Channel   string `json:"channel"`
+	From      string `json:"from"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// defaultBlocklist holds the words masked in every room. CHAT_BLOCKLIST adds
+// more as a comma separated list.
+var defaultBlocklist = []string{
+	"fuck", "fucking", "shit", "bitch", "cunt", "asshole", "bastard", "dick", "slut", "whore",
+}
+
+var (
+	blocklistOnce sync.Once
+	blocklist     *regexp.Regexp
+)
+
+// filterChat masks every blocked word in text with asterisks.
+func filterChat(text string) string {
+	blocklistOnce.Do(func() {
+		words := defaultBlocklist
+		for _, w := range strings.Split(os.Getenv("CHAT_BLOCKLIST"), ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, w)
+			}
+		}
+		quoted := make([]string, len(words))
+		for i, w := range words {
+			quoted[i] = regexp.QuoteMeta(w)
+		}
+		blocklist = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	})
+	return blocklist.ReplaceAllStringFunc(text, func(w string) string {
+		return strings.Repeat("*", len([]rune(w)))
+	})
+}
+
+// chatName is who a client speaks as: its seat's name for players and a
+// numbered label, or the account name, for spectators.
+func chatName(room *Room, c *Client) string {
+	if c.role == "spectator" {
+		return c.spectatorName
+	}
+	return memberName(room, c)
+}
+
+// checkChat trims and filters a message, or tells the sender why it cannot
+// be sent. The caller must hold room.Mutex.
+func (h *Hub) checkChat(room *Room, from *Client, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+	if room.isMuted(from) {
+		sendError(from, "You have been muted in this room.")
+		return "", false
+	}
+	if !from.chatLimiter.Allow() {
+		sendError(from, "You are sending messages too quickly.")
+		return "", false
+	}
+	if len([]rune(text)) > maxChatLength {
+		text = string([]rune(text)[:maxChatLength])
+	}
+	return filterChat(text), true
+}
+
+// sendChat posts a message to one of the room's channels. Everyone in the
+// room reads the players' channel, but only spectators read their own, so
+// they cannot pass hints to the players. The caller must hold room.Mutex.
+func (h *Hub) sendChat(room *Room, from *Client, channel, text string) {
+	if channel == "" {
+		channel = ChannelPlayers
+		if from.role == "spectator" {
+			channel = ChannelSpectators
+		}
+	}
+	switch {
+	case channel != ChannelPlayers && channel != ChannelSpectators:
+		sendError(from, "Unknown chat channel.")
+		return
+	case channel == ChannelPlayers && from.role == "spectator":
+		sendError(from, "Spectators can only chat with other spectators.")
+		return
+	case channel == ChannelSpectators && from.role != "spectator":
+		sendError(from, "Only spectators can use the spectator chat.")
+		return
+	}
+	text, ok := h.checkChat(room, from, text)
+	if !ok {
+		return
+	}
+
+	msg := ChatMessage{
+		Channel:   channel,
+		From:      chatName(room, from),
+		Text:      text,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	room.chat = append(room.chat, msg)
+	if len(room.chat) > chatHistorySize {
+		room.chat = room.chat[len(room.chat)-chatHistorySize:]
+	}
+	h.saveRoom(room)
+	for c := range room.Clients {
+		if canRead(c, channel) {
+			h.sendEvent(c, "chat", msg)
+		}
+	}
+}
+
+func canRead(c *Client, channel string) bool {
+	return channel == ChannelPlayers || c.role == "spectator"
+}
+
+// sendChatHistory catches a newcomer up on the messages they can read. The
+// caller must hold room.Mutex.
+func (h *Hub) sendChatHistory(room *Room, c *Client) {
+	visible := make([]ChatMessage, 0, len(room.chat))
+	for _, msg := range room.chat {
+		if canRead(c, msg.Channel) {
+			visible = append(visible, msg)
+		}
+	}
+	h.sendEvent(c, "chat_history", visible)
+}
+
+// setMuted lets the room owner silence, or unsilence, anyone in the room by
+// the name they chat under. The mute follows them as a ban would, so a
+// spectator cannot shake it off by coming back under a new label. The
+// caller must hold room.Mutex.
+func (h *Hub) setMuted(room *Room, by *Client, name string, muted bool) {
+	if !isOwner(room, by) {
+		sendError(by, "Only the room owner can mute people.")
+		return
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, chatName(room, by)) {
+		return
+	}
+	targets := findByName(room, name)
+	if len(targets) == 0 && muted {
+		sendError(by, fmt.Sprintf("There is no one called %s in the room.", name))
+		return
+	}
+
+	// A player who has left can still be unmuted by the name they played
+	// under.
+	keys := []string{"name:" + strings.ToLower(name)}
+	if muted {
+		keys = nil
+	}
+	for _, c := range targets {
+		keys = append(keys, moderationKeys(room, c)...)
+	}
+	changed := false
+	for _, key := range keys {
+		if room.muted[key] != muted {
+			changed = true
+		}
+		if muted {
+			room.muted[key] = true
+		} else {
+			delete(room.muted, key)
+		}
+	}
+	if !changed {
+		return
+	}
+	if muted {
+		h.broadcastNotification(room, fmt.Sprintf("%s has been muted.", name))
+	} else {
+		h.broadcastNotification(room, fmt.Sprintf("%s can chat again.", name))
+	}
+}
+
+// isMuted reports whether c has been muted in the room. The caller must
+// hold room.Mutex.
+func (r *Room) isMuted(c *Client) bool {
+	for _, key := range moderationKeys(r, c) {
+		if r.muted[key] {
+			return true
+		}
+	}
+	return false
+}
@@ -3,17 +3,23 @@ package websocket
 import (
 	"encoding/json"
 	"log/slog"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/adimail/colosseum/internal/bot"
+	"github.com/adimail/colosseum/internal/game"
 	"github.com/gorilla/websocket"
 	"golang.org/x/time/rate"
 )
 
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+	// maxMessageSize leaves room for a chat message of maxChatLength
+	// characters however many bytes each one takes to encode.
+	maxMessageSize = 4096
 )
 
 type Client struct {
@@ -22,8 +28,33 @@ type Client struct {
 	send     chan []byte
 	roomCode string
 	playerID string
-	role     string
-	limiter  *rate.Limiter
+	// member is the client's place in its team in team games.
+	member  int
+	role    string
+	limiter *rate.Limiter
+	replay  *replay
+	// userID and username are set when the connection was opened with a
+	// valid session cookie; guests have neither.
+	userID   string
+	username string
+	// spectatorName is what the client chats as while spectating.
+	spectatorName string
+	chatLimiter   *rate.Limiter
+	// guestID comes from a long-lived cookie and follows the browser across
+	// connections.
+	guestID string
+	// remote is the node holding the client's room when another node does,
+	// and relayID names the client to it. A proxy standing in for a client
+	// of another node has origin set to that node instead.
+	remote  string
+	relayID string
+	origin  string
+
+	// sendMu guards closed, which is set once send has been closed so that
+	// nothing is sent on it afterwards. It is never held while taking
+	// another lock.
+	sendMu sync.Mutex
+	closed bool
 }
 
 type Message struct {
@@ -32,16 +63,34 @@ type Message struct {
 }
 
 type JoinPayload struct {
-	Name string `json:"name"`
-	Code string `json:"code"`
+	Name     string `json:"name"`
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"`
+	Invite   string `json:"invite,omitempty"`
 }
 
 type CreatePayload struct {
-	Name string `json:"name"`
+	Name       string      `json:"name"`
+	Rules      *game.Rules `json:"rules"`
+	Bot        *BotOptions `json:"bot"`
+	Visibility string      `json:"visibility,omitempty"`
+	Password   string      `json:"password,omitempty"`
+}
+
+type BotOptions struct {
+	Difficulty bot.Difficulty `json:"difficulty"`
+	ThinkMs    int            `json:"thinkMs"`
 }
 
 type GameActionPayload struct {
 	Data string `json:"data"`
+	// Target picks whose code a guess attacks in a free-for-all.
+	Target string `json:"target,omitempty"`
+}
+
+type ResumePayload struct {
+	RoomCode string `json:"roomCode"`
+	Token    string `json:"token"`
 }
 
 type LeavePayload struct {
@@ -50,6 +99,7 @@ type LeavePayload struct {
 
 func (c *Client) readPump() {
 	defer func() {
+		c.stopReplay()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -77,24 +127,44 @@ func (c *Client) handleMessage(msg []byte) {
 	if err := json.Unmarshal(msg, &m); err != nil {
 		return
 	}
+	if c.hub.relay(c, m, msg) {
+		return
+	}
 
 	switch m.Type {
 	case "create_room":
 		var p CreatePayload
 		json.Unmarshal(m.Payload, &p)
-		c.hub.createRoom <- &RoomAction{Client: c, Name: p.Name}
+		rules := game.DefaultRules()
+		if p.Rules != nil {
+			rules = *p.Rules
+		}
+		if p.Bot != nil && p.Bot.ThinkMs == 0 {
+			p.Bot.ThinkMs = int(bot.DefaultThinkDelay / time.Millisecond)
+		}
+		c.hub.createRoom <- &RoomAction{Client: c, Name: p.Name, Rules: rules, Bot: p.Bot, Visibility: p.Visibility, Password: p.Password}
 	case "join_room":
 		var p JoinPayload
 		json.Unmarshal(m.Payload, &p)
-		c.hub.joinRoom <- &RoomAction{Client: c, Name: p.Name, Code: p.Code}
+		c.hub.joinRoom <- &RoomAction{Client: c, Name: p.Name, Code: p.Code, Password: p.Password, Invite: p.Invite}
 	case "spectate":
 		var p JoinPayload
 		json.Unmarshal(m.Payload, &p)
-		c.hub.spectateRoom <- &RoomAction{Client: c, Code: p.Code}
+		c.hub.spectateRoom <- &RoomAction{Client: c, Code: p.Code, Password: p.Password, Invite: p.Invite}
 	case "leave_room":
 		var p LeavePayload
 		json.Unmarshal(m.Payload, &p)
 		c.hub.leaveRoom <- &RoomAction{Client: c, Code: p.RoomID}
+	case "join_queue":
+		var p JoinPayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.joinQueue <- &RoomAction{Client: c, Name: p.Name}
+	case "leave_queue":
+		c.hub.leaveQueue <- &RoomAction{Client: c}
+	case "resume":
+		var p ResumePayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.resumeRoom <- &RoomAction{Client: c, Code: p.RoomCode, Token: p.Token}
 	case "secret":
 		var p GameActionPayload
 		json.Unmarshal(m.Payload, &p)
@@ -102,19 +172,112 @@ func (c *Client) handleMessage(msg []byte) {
 	case "submit_guess":
 		var p GameActionPayload
 		json.Unmarshal(m.Payload, &p)
-		c.hub.gameAction <- &GameAction{Client: c, Type: "guess", Data: p.Data}
+		c.hub.gameAction <- &GameAction{Client: c, Type: "guess", Data: p.Data, Target: p.Target}
 	case "restart":
 		c.hub.gameAction <- &GameAction{Client: c, Type: "restart"}
 	case "poke":
 		c.hub.gameAction <- &GameAction{Client: c, Type: "poke"}
+	case "team_chat":
+		var p GameActionPayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: "team_chat", Data: p.Data}
+	case "chat":
+		var p ChatPayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: "chat", Data: p.Text, Channel: p.Channel}
+	case "create_invite":
+		var p InvitePayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: "invite", Data: strconv.Itoa(p.TTLMinutes)}
+	case "kick", "ban", "transfer_ownership":
+		var p ModeratePayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: m.Type, Data: p.Name}
+	case "lock_spectators":
+		var p LockPayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: m.Type, Data: strconv.FormatBool(p.Locked)}
+	case "mute", "unmute":
+		var p MutePayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.gameAction <- &GameAction{Client: c, Type: m.Type, Data: p.Name}
+	case "subscribe_tournament":
+		var p TournamentPayload
+		json.Unmarshal(m.Payload, &p)
+		c.hub.watchTournament(c, p.ID)
+	case "unsubscribe_tournament":
+		c.hub.unwatchTournaments(c)
+	case "subscribe_lobby":
+		c.hub.watchLobby(c)
+	case "unsubscribe_lobby":
+		c.hub.unwatchLobby(c)
+	case "replay":
+		var p ReplayPayload
+		json.Unmarshal(m.Payload, &p)
+		c.startReplay(p.GameID, p.Speed)
+	case "replay_speed":
+		var p ReplayPayload
+		json.Unmarshal(m.Payload, &p)
+		c.setReplaySpeed(p.Speed)
+	case "stop_replay":
+		c.stopReplay()
 	}
 }
 
+// trySend queues msg for the client, dropping it if the client is not keeping
+// up. It reports false once the client has been closed.
+func (c *Client) trySend(msg []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- msg:
+	default:
+	}
+	return true
+}
+
+// sendWithin queues msg for the client, waiting up to d for it to make room,
+// and reports false if it did not. A closed client drops msg.
+func (c *Client) sendWithin(msg []byte, d time.Duration) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return true
+	}
+	select {
+	case c.send <- msg:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// closeSend closes the client's send channel, after which its writer flushes
+// what is queued and closes the connection. It may be called more than once.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.closed
+}
+
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.writers.Done()
 	}()
 	for {
 		select {
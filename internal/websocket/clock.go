@@ -0,0 +1,91 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// scheduleClock arms a timer for the current turn's deadline so timeouts
+// are enforced even if nobody sends anything. The caller must hold
+// room.Mutex.
+func (h *Hub) scheduleClock(room *Room) {
+	if room.clockTimer != nil {
+		room.clockTimer.Stop()
+		room.clockTimer = nil
+	}
+
+	deadline := room.GameState.TurnDeadline
+	if room.GameState.Status != "active" || deadline == 0 || room.GameState.PausedAt != 0 {
+		return
+	}
+	room.clockTimer = time.AfterFunc(time.Until(time.UnixMilli(deadline)), func() {
+		h.expireTurn(room, deadline)
+	})
+}
+
+// stopTimers cancels any pending clock timeout and held seats before the
+// room is dropped. The caller must hold room.Mutex.
+func (r *Room) stopTimers() {
+	if r.clockTimer != nil {
+		r.clockTimer.Stop()
+		r.clockTimer = nil
+	}
+	for token, s := range r.sessions {
+		if s.expiry != nil {
+			s.expiry.Stop()
+		}
+		delete(r.sessions, token)
+	}
+}
+
+func (h *Hub) expireTurn(room *Room, deadline int64) {
+	room.Mutex.Lock()
+	defer room.Mutex.Unlock()
+
+	lastRound, mover := room.GameState.LastRound, room.GameState.Turn
+	if room.closed || room.GameState.TurnDeadline != deadline || !room.GameState.ExpireTurn(time.Now()) {
+		return
+	}
+	h.announceTimeout(room, mover, lastRound)
+	h.syncRoom(room)
+}
+
+// announceTimeout tells the room who ran out of time and records the game
+// if that ended it. mover is whoever was to move before the timeout. The
+// caller must hold room.Mutex.
+func (h *Hub) announceTimeout(room *Room, mover game.PlayerID, lastRound *game.RoundResult) {
+	h.announceRound(room, lastRound)
+
+	gs := room.GameState
+	switch {
+	case gs.Status == "completed" && gs.Winner == game.Draw:
+		h.broadcastNotification(room, "Both players ran out of time. The game is drawn.")
+	case gs.Status == "completed" && gs.Rules.TurnMode == game.TurnSimultaneous:
+		loser := gs.Players[0]
+		if gs.Winner == string(loser.ID) {
+			loser = gs.Players[1]
+		}
+		h.broadcastNotification(room, fmt.Sprintf("%s ran out of time and forfeits the game.", loser.Name))
+	case gs.Status == "completed" && !gs.IsFreeForAll():
+		h.broadcastNotification(room, fmt.Sprintf("%s ran out of time and forfeits the game.", gs.Player(mover).Name))
+	case gs.Player(mover).Eliminated:
+		h.broadcastNotification(room, fmt.Sprintf("%s ran out of time and is out of the game.", gs.Player(mover).Name))
+	case gs.Rules.TurnMode == game.TurnSimultaneous:
+		var missed []string
+		if gs.LastRound.P1 == nil {
+			missed = append(missed, gs.Players[0].Name)
+		}
+		if gs.LastRound.P2 == nil {
+			missed = append(missed, gs.Players[1].Name)
+		}
+		h.broadcastNotification(room, fmt.Sprintf("Time's up! %s missed round %d.", strings.Join(missed, " and "), gs.LastRound.Round))
+	default:
+		h.broadcastNotification(room, fmt.Sprintf("Time's up! %s to move.", gs.Player(gs.Turn).Name))
+	}
+	if gs.Status == "completed" {
+		h.afterMove(room)
+	}
+}
@@ -0,0 +1,23 @@
+package websocket
+
+import (
+	"log/slog"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+func (h *Hub) recordGame(snapshot *game.GameState) {
+	if err := h.history.RecordGame(snapshot); err != nil {
+		slog.Error("failed to record game", "room", snapshot.RoomCode, "error", err)
+		return
+	}
+	slog.Info("recorded game", "room", snapshot.RoomCode)
+}
+
+func (h *Hub) recordSeries(snapshot *game.GameState) {
+	if err := h.history.RecordSeries(snapshot); err != nil {
+		slog.Error("failed to record series", "room", snapshot.RoomCode, "error", err)
+		return
+	}
+	slog.Info("recorded series", "room", snapshot.RoomCode)
+}
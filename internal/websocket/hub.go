@@ -5,59 +5,128 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/adimail/colosseum/internal/auth"
+	"github.com/adimail/colosseum/internal/backplane"
+	"github.com/adimail/colosseum/internal/bot"
 	"github.com/adimail/colosseum/internal/game"
-	"github.com/adimail/colosseum/internal/sheets"
+	"github.com/adimail/colosseum/internal/history"
+	"github.com/adimail/colosseum/internal/rating"
+	"github.com/adimail/colosseum/internal/roomstore"
+	"github.com/adimail/colosseum/internal/solver"
+	"github.com/adimail/colosseum/internal/stats"
 	"github.com/gorilla/websocket"
 	"golang.org/x/time/rate"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
 type Room struct {
 	GameState      *game.GameState
 	Clients        map[*Client]bool
 	Mutex          sync.Mutex
 	CreatedAt      time.Time
 	LastActivityAt time.Time
+	Bot            *bot.Bot
+	Analysis       *solver.Analysis
+	clockTimer     *time.Timer
+	botPending     bool
+	sessions       map[string]*session
+	match          *matchRoom
+	chat           []ChatMessage
+	muted          map[string]bool
+	spectatorSeq   int
+	access         access
+	banned         map[string]bool
+	// spectatorsLocked keeps new spectators out.
+	spectatorsLocked bool
+	// closed is set once the room has been deleted, for anyone who looked
+	// it up before then.
+	closed bool
 }
 
 type RoomAction struct {
-	Client *Client
-	Name   string
-	Code   string
+	Client     *Client
+	Name       string
+	Code       string
+	Rules      game.Rules
+	Bot        *BotOptions
+	Token      string
+	Visibility string
+	Password   string
+	Invite     string
+	// passwordHash and checked carry the outcome of hashing or checking
+	// Password in the background when the action comes back to the hub.
+	passwordHash []byte
+	checked      *passwordCheck
 }
 
 type GameAction struct {
-	Client *Client
-	Type   string
-	Data   string
+	Client  *Client
+	Type    string
+	Data    string
+	Target  string
+	Channel string
 }
 
 type Hub struct {
-	clients       map[*Client]bool
-	Rooms         map[string]*Room
-	register      chan *Client
-	unregister    chan *Client
-	createRoom    chan *RoomAction
-	joinRoom      chan *RoomAction
-	spectateRoom  chan *RoomAction
-	leaveRoom     chan *RoomAction
-	gameAction    chan *GameAction
-	sheetsService *sheets.Service
-	Mutex         sync.Mutex
-}
-
-func NewHub(sheetsService *sheets.Service) *Hub {
+	clients      map[*Client]bool
+	Rooms        map[string]*Room
+	register     chan *Client
+	unregister   chan *Client
+	createRoom   chan *RoomAction
+	joinRoom     chan *RoomAction
+	spectateRoom chan *RoomAction
+	leaveRoom    chan *RoomAction
+	resumeRoom   chan *RoomAction
+	joinQueue    chan *RoomAction
+	leaveQueue   chan *RoomAction
+	gameAction   chan *GameAction
+	history      history.Store
+	ratings      *rating.Store
+	accounts     *auth.Accounts
+	stats        *stats.Store
+	queue        []*queueEntry
+	tournaments  map[string]*tournamentEntry
+	tournamentMu sync.Mutex
+	inviteKey    []byte
+	// upgrader only lets in pages served from this host or one of
+	// allowedOrigins.
+	upgrader       websocket.Upgrader
+	allowedOrigins map[string]bool
+	// lobby is what the lobby was last told about each open public room
+	// here, and remoteLobby about those on other nodes. lobbyOut queues
+	// this node's changes for the backplane.
+	lobby         map[string]RoomSummary
+	remoteLobby   map[string]remoteRoom
+	lobbyWatchers map[*Client]bool
+	lobbyMu       sync.Mutex
+	lobbyOut      chan lobbyNews
+	// backplane is set when the hub shares rooms with other servers, on
+	// which it goes by node.
+	backplane backplane.Backplane
+	node      string
+	// relayed are this node's clients in rooms held elsewhere, by relay ID;
+	// proxies stand in for other nodes' clients in rooms held here.
+	relayed     map[string]*Client
+	proxies     map[string]*Client
+	infoWaiters map[string]chan json.RawMessage
+	relayMu     sync.Mutex
+	// snapshots is where rooms are saved to outlive a restart. Once closing
+	// is set the server is going down and rooms are no longer saved.
+	snapshots *roomstore.Store
+	closing   atomic.Bool
+	writers   sync.WaitGroup
+	// Mutex guards clients and Rooms. It is taken before a room's Mutex,
+	// never while holding one.
+	Mutex sync.Mutex
+}
+
+func NewHub(store history.Store, ratings *rating.Store, accounts *auth.Accounts, results *stats.Store, snapshots *roomstore.Store) *Hub {
 	hub := &Hub{
 		register:      make(chan *Client),
 		unregister:    make(chan *Client),
@@ -65,16 +134,39 @@ func NewHub(sheetsService *sheets.Service) *Hub {
 		joinRoom:      make(chan *RoomAction),
 		spectateRoom:  make(chan *RoomAction),
 		leaveRoom:     make(chan *RoomAction),
+		resumeRoom:    make(chan *RoomAction),
+		joinQueue:     make(chan *RoomAction),
+		leaveQueue:    make(chan *RoomAction),
 		gameAction:    make(chan *GameAction),
 		clients:       make(map[*Client]bool),
 		Rooms:         make(map[string]*Room),
-		sheetsService: sheetsService,
+		history:       store,
+		ratings:       ratings,
+		accounts:      accounts,
+		stats:         results,
+		tournaments:   make(map[string]*tournamentEntry),
+		inviteKey:     newInviteKey(),
+		lobby:         make(map[string]RoomSummary),
+		remoteLobby:   make(map[string]remoteRoom),
+		lobbyWatchers: make(map[*Client]bool),
+		relayed:       make(map[string]*Client),
+		proxies:       make(map[string]*Client),
+		infoWaiters:   make(map[string]chan json.RawMessage),
+		snapshots:     snapshots,
+	}
+	hub.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     hub.checkOrigin,
 	}
+	hub.restoreRooms()
 	go hub.cleanupStaleRooms()
 	return hub
 }
 
 func (h *Hub) Run() {
+	queueTicker := time.NewTicker(queueTick)
+	defer queueTicker.Stop()
 	for {
 		select {
 		case client := <-h.register:
@@ -97,6 +189,18 @@ func (h *Hub) Run() {
 		case action := <-h.leaveRoom:
 			h.handleLeaveRoom(action)
 
+		case action := <-h.resumeRoom:
+			h.handleResume(action)
+
+		case action := <-h.joinQueue:
+			h.handleJoinQueue(action)
+
+		case action := <-h.leaveQueue:
+			h.handleLeaveQueue(action)
+
+		case now := <-queueTicker.C:
+			h.matchQueue(now)
+
 		case action := <-h.gameAction:
 			h.handleGameAction(action)
 		}
@@ -104,10 +208,21 @@ func (h *Hub) Run() {
 }
 
 func (h *Hub) handleUnregister(client *Client) {
+	h.dequeue(client)
+	h.unwatchTournaments(client)
+	h.unwatchLobby(client)
+	h.detach(client)
+	h.removeClient(client, true)
+}
+
+// removeClient takes a client out of the hub and its room. A player whose
+// connection dropped has their seat held so they can resume; otherwise, or
+// when hold is false, the seat is given up straight away.
+func (h *Hub) removeClient(client *Client, hold bool) {
 	h.Mutex.Lock()
 	if _, ok := h.clients[client]; ok {
 		delete(h.clients, client)
-		close(client.send)
+		client.closeSend()
 	}
 
 	roomCode := client.roomCode
@@ -116,85 +231,118 @@ func (h *Hub) handleUnregister(client *Client) {
 		return
 	}
 
-	room, roomExists := h.Rooms[roomCode]
 	h.Mutex.Unlock()
 
-	if !roomExists {
+	room := h.lockRoom(roomCode)
+	if room == nil {
 		return
 	}
-
-	room.Mutex.Lock()
 	defer room.Mutex.Unlock()
 
-	delete(room.Clients, client)
-
-	if len(room.Clients) == 0 {
-		h.Mutex.Lock()
-		delete(h.Rooms, roomCode)
-		h.Mutex.Unlock()
+	if !room.Clients[client] {
 		return
 	}
+	delete(room.Clients, client)
 
-	if client.role == "spectator" {
-		if room.GameState.Spectators > 0 {
-			room.GameState.Spectators--
-		}
-	} else {
-		pid := game.PlayerID(client.playerID)
-		var leavingPlayerName string
-		if pid == game.Player1 {
-			leavingPlayerName = room.GameState.P1.Name
-		} else {
-			leavingPlayerName = room.GameState.P2.Name
+	if client.role != "spectator" {
+		if hold && h.holdSeat(room, client) {
+			h.syncRoom(room)
+			return
 		}
-
-		if leavingPlayerName != "" {
-			h.broadcastNotification(room, fmt.Sprintf("%s has left the game.", leavingPlayerName))
+		room.dropSession(client)
+		reason := game.EndForfeit
+		if hold {
+			reason = game.EndDisconnect
 		}
+		h.releaseSeat(room, client.playerID, client.member, reason)
+		return
+	}
 
-		if pid == game.Player1 {
-			if room.GameState.P2.Name != "" {
-				var p2Client *Client
-				for c := range room.Clients {
-					if c.playerID == string(game.Player2) {
-						p2Client = c
-						break
-					}
-				}
-
-				if p2Client != nil {
-					p2Client.playerID = string(game.Player1)
-				}
+	if len(room.Clients) == 0 && !room.holdingSeats() && !room.reserved() {
+		h.deleteRoom(room, roomCode)
+		return
+	}
+	if room.GameState.Spectators > 0 {
+		room.GameState.Spectators--
+	}
+	h.syncRoom(room)
+}
 
-				room.GameState.P1.Name = room.GameState.P2.Name
-				room.GameState.OwnerID = game.Player1
+// releaseSeat gives up a player's seat for good, conceding any game in
+// progress for reason. The caller must hold room.Mutex.
+func (h *Hub) releaseSeat(room *Room, playerID string, member int, reason string) {
+	roomCode := room.GameState.RoomCode
+	if room.GameState.Forfeit(game.PlayerID(playerID), reason) {
+		h.afterMove(room)
+	}
+	if room.Bot != nil {
+		h.closeRoom(room, roomCode)
+		return
+	}
 
-				room.GameState.P1.Secret = ""
-				room.GameState.P1.Guesses = []game.Guess{}
-				room.GameState.P1.IsWinner = false
-				room.GameState.P1.IsReady = false
+	if len(room.Clients) == 0 && !room.holdingSeats() && !room.reserved() {
+		h.deleteRoom(room, roomCode)
+		return
+	}
 
-				room.GameState.P2 = &game.PlayerState{ID: game.Player2, Guesses: []game.Guess{}}
+	pid := game.PlayerID(playerID)
+	if name := seatName(room, playerID, member); name != "" {
+		h.broadcastNotification(room, fmt.Sprintf("%s has left the game.", name))
+	}
 
-				room.GameState.Status = "waiting"
-				room.GameState.Turn = game.Player1
-				room.GameState.Winner = ""
+	if p := room.GameState.Player(pid); room.GameState.IsTeamGame() && p != nil && len(p.Members) > 1 {
+		room.GameState.RemoveMember(pid, member)
+		room.forEachSeat(func(seat *string, m *int) {
+			if *seat == playerID && *m > member {
+				*m--
+			}
+		})
+	} else if room.GameState.Vacate(pid) {
+		// Everyone seated after the leaver moved up one seat.
+		gone := game.SeatIndex(pid)
+		room.forEachSeat(func(seat *string, _ *int) {
+			if i := game.SeatIndex(game.PlayerID(*seat)); i > gone {
+				*seat = string(game.SeatID(i - 1))
 			}
-		} else if pid == game.Player2 {
-			room.GameState.P2 = &game.PlayerState{ID: game.Player2, Guesses: []game.Guess{}}
+		})
+	}
 
-			room.GameState.P1.Secret = ""
-			room.GameState.P1.Guesses = []game.Guess{}
-			room.GameState.P1.IsWinner = false
-			room.GameState.P1.IsReady = false
+	h.syncRoom(room)
+}
 
-			room.GameState.Status = "waiting"
-			room.GameState.Turn = game.Player1
-			room.GameState.Winner = ""
-		}
+// deleteRoom drops a room nobody is in any more. The caller must hold
+// room.Mutex, so the room is taken out of h.Rooms once it is let go.
+func (h *Hub) deleteRoom(room *Room, roomCode string) {
+	room.closed = true
+	room.stopTimers()
+	h.unpublishRoom(roomCode)
+	h.dropSnapshot(roomCode)
+	go h.forgetRoom(room, roomCode)
+}
+
+func (h *Hub) forgetRoom(room *Room, roomCode string) {
+	h.Mutex.Lock()
+	if h.Rooms[roomCode] == room {
+		delete(h.Rooms, roomCode)
 	}
+	h.Mutex.Unlock()
+	h.releaseRoom(roomCode)
+}
 
-	h.broadcastState(room)
+// lockRoom looks up a room and locks it, or returns nil if there is no such
+// room or it has been deleted. The caller must unlock it.
+func (h *Hub) lockRoom(code string) *Room {
+	h.Mutex.Lock()
+	room := h.Rooms[code]
+	if room != nil {
+		room.Mutex.Lock()
+	}
+	h.Mutex.Unlock()
+	if room != nil && room.closed {
+		room.Mutex.Unlock()
+		return nil
+	}
+	return room
 }
 
 func sanitizeName(name string) string {
@@ -210,111 +358,194 @@ func sanitizeName(name string) string {
 	}, name)
 }
 
+// playerName is the name client plays under: their username once logged in,
+// otherwise the name they asked for, provided no account has claimed it.
+func (h *Hub) playerName(client *Client, requested string) (string, bool) {
+	if client.username != "" {
+		return client.username, true
+	}
+	name := sanitizeName(requested)
+	if h.accounts != nil && h.accounts.Registered(name) {
+		sendError(client, "That name belongs to a registered player. Log in to play as them.")
+		return "", false
+	}
+	return name, true
+}
+
 func (h *Hub) generateUniqueRoomCode() string {
 	for {
 		code := game.GenerateRoomCode()
 		h.Mutex.Lock()
 		_, exists := h.Rooms[code]
 		h.Mutex.Unlock()
-		if !exists {
+		if !exists && h.claimRoom(code) {
 			return code
 		}
 	}
 }
 
 func (h *Hub) handleCreateRoom(action *RoomAction) {
-	code := h.generateUniqueRoomCode()
-	now := time.Now()
-	room := &Room{
-		GameState:      game.NewGame(code),
-		Clients:        make(map[*Client]bool),
-		CreatedAt:      now,
-		LastActivityAt: now,
+	rules, err := action.Rules.Resolve()
+	if err != nil {
+		sendError(action.Client, "Invalid rules: "+err.Error())
+		return
 	}
 
-	action.Client.roomCode = code
-	action.Client.playerID = string(game.Player1)
-	action.Client.role = "player"
+	var roomBot *bot.Bot
+	if action.Bot != nil && (rules.Players > 2 || rules.TeamSize > 1) {
+		sendError(action.Client, "Bots only play one-on-one rooms.")
+		return
+	}
+	if action.Bot != nil {
+		roomBot, err = bot.New(action.Bot.Difficulty, time.Duration(action.Bot.ThinkMs)*time.Millisecond)
+		if err != nil {
+			sendError(action.Client, "Invalid bot: "+err.Error())
+			return
+		}
+	}
 
-	room.Mutex.Lock()
-	defer room.Mutex.Unlock()
+	roomAccess, err := newAccess(action.Visibility, action.Password, action.passwordHash)
+	if err != nil {
+		sendError(action.Client, "Invalid room settings: "+err.Error())
+		return
+	}
+	if roomAccess.visibility == VisibilityPrivate && roomAccess.passwordHash == nil {
+		go h.hashPassword(action)
+		return
+	}
 
-	room.Clients[action.Client] = true
-	room.GameState.P1.Name = sanitizeName(action.Name)
+	name, ok := h.playerName(action.Client, action.Name)
+	if !ok {
+		return
+	}
+
+	room := h.newRoom(h.generateUniqueRoomCode(), rules, roomBot)
+	room.access = roomAccess
+	code := room.GameState.RoomCode
 
 	h.Mutex.Lock()
 	h.Rooms[code] = room
+	room.Mutex.Lock()
 	h.Mutex.Unlock()
+	defer room.Mutex.Unlock()
+
+	pid, member, _ := room.GameState.Join(name)
+	action.Client.roomCode = code
+	action.Client.playerID = string(pid)
+	action.Client.member = member
+	action.Client.role = "player"
+	room.Clients[action.Client] = true
+	h.issueSession(room, action.Client)
+
+	if roomBot != nil {
+		room.GameState.Join(roomBot.Name())
+		room.GameState.SetSecret(botPlayer, roomBot.ChooseSecret(rules))
+	}
 
 	h.broadcastState(room)
+	h.sendChatHistory(room, action.Client)
 }
 
-func (h *Hub) handleJoinRoom(action *RoomAction) {
-	h.Mutex.Lock()
-	room, ok := h.Rooms[action.Code]
-	if ok {
-		room.Mutex.Lock()
+// newRoom sets up an empty room under code. It is not reachable until the
+// caller adds it to h.Rooms.
+func (h *Hub) newRoom(code string, rules game.Rules, roomBot *bot.Bot) *Room {
+	now := time.Now()
+	return &Room{
+		GameState:      game.NewGame(code, rules),
+		Clients:        make(map[*Client]bool),
+		CreatedAt:      now,
+		LastActivityAt: now,
+		Bot:            roomBot,
+		sessions:       make(map[string]*session),
+		muted:          make(map[string]bool),
+		access:         access{visibility: VisibilityPublic},
+		banned:         make(map[string]bool),
 	}
-	h.Mutex.Unlock()
+}
 
-	if !ok {
-		action.Client.send <- []byte(`{"type":"error","payload":"Room not found"}`)
+func (h *Hub) handleJoinRoom(action *RoomAction) {
+	room := h.lockRoom(action.Code)
+	if room == nil {
+		action.Client.trySend([]byte(`{"type":"error","payload":"Room not found"}`))
 		return
 	}
 	defer room.Mutex.Unlock()
 
-	if room.GameState.P2.Name != "" {
-		action.Client.send <- []byte(`{"type":"redirect","payload":"/spectate/` + action.Code + `"}`)
+	if !h.admit(room, action, h.joinRoom) {
+		return
+	}
+	name, ok := h.playerName(action.Client, action.Name)
+	if !ok {
+		return
+	}
+	if room.isBanned(action.Client, name) {
+		sendError(action.Client, "You have been banned from this room.")
+		return
+	}
+	if !room.seatedInMatch(name) {
+		sendError(action.Client, "This room is reserved for a tournament match.")
+		action.Client.trySend([]byte(`{"type":"redirect","payload":"/spectate/` + action.Code + `"}`))
+		return
+	}
+	pid, member, seated := room.GameState.Join(name)
+	if !seated {
+		action.Client.trySend([]byte(`{"type":"redirect","payload":"/spectate/` + action.Code + `"}`))
 		return
 	}
 
 	action.Client.roomCode = action.Code
-	action.Client.playerID = string(game.Player2)
+	action.Client.playerID = string(pid)
+	action.Client.member = member
 	action.Client.role = "player"
 	room.Clients[action.Client] = true
-	room.GameState.P2.Name = sanitizeName(action.Name)
 	room.LastActivityAt = time.Now()
-	room.GameState.Status = "setup"
+	h.issueSession(room, action.Client)
 
-	h.broadcastNotification(room, fmt.Sprintf("%s has joined the game!", room.GameState.P2.Name))
+	h.broadcastNotification(room, fmt.Sprintf("%s has joined the game!", name))
 	h.broadcastState(room)
+	h.sendChatHistory(room, action.Client)
 }
 
 func (h *Hub) handleSpectateRoom(action *RoomAction) {
-	h.Mutex.Lock()
-	room, ok := h.Rooms[action.Code]
-	if ok {
-		room.Mutex.Lock()
-	}
-	h.Mutex.Unlock()
-
-	if !ok {
-		action.Client.send <- []byte(`{"type":"error","payload":"Room not found"}`)
+	room := h.lockRoom(action.Code)
+	if room == nil {
+		action.Client.trySend([]byte(`{"type":"error","payload":"Room not found"}`))
 		return
 	}
 	defer room.Mutex.Unlock()
 
+	if room.isBanned(action.Client, action.Client.username) {
+		sendError(action.Client, "You have been banned from this room.")
+		return
+	}
+	if room.spectatorsLocked {
+		sendError(action.Client, "The stands are closed.")
+		return
+	}
+	if !h.admit(room, action, h.spectateRoom) {
+		return
+	}
 	action.Client.roomCode = action.Code
 	action.Client.role = "spectator"
+	room.spectatorSeq++
+	action.Client.spectatorName = action.Client.username
+	if action.Client.spectatorName == "" {
+		action.Client.spectatorName = fmt.Sprintf("Spectator %d", room.spectatorSeq)
+	}
 	room.Clients[action.Client] = true
 	room.GameState.Spectators++
 
 	h.broadcastState(room)
+	h.sendChatHistory(room, action.Client)
 }
 
 func (h *Hub) handleLeaveRoom(action *RoomAction) {
-	h.handleUnregister(action.Client)
+	h.removeClient(action.Client, false)
 }
 
 func (h *Hub) handleGameAction(action *GameAction) {
-	h.Mutex.Lock()
-	room, ok := h.Rooms[action.Client.roomCode]
-	if ok {
-		room.Mutex.Lock()
-	}
-	h.Mutex.Unlock()
-
-	if !ok {
+	room := h.lockRoom(action.Client.roomCode)
+	if room == nil {
 		return
 	}
 	defer room.Mutex.Unlock()
@@ -323,96 +554,224 @@ func (h *Hub) handleGameAction(action *GameAction) {
 	room.LastActivityAt = time.Now()
 	stateChanged := false
 
+	rules := room.GameState.Rules
+
 	switch action.Type {
 	case "secret":
 		if room.GameState.Status == "waiting" || room.GameState.Status == "setup" {
-			if game.IsValidSecret(action.Data) {
-				room.GameState.SetSecret(pid, action.Data)
+			secret := rules.Normalize(action.Data)
+			if rules.IsValidSecret(secret) {
+				room.GameState.SetSecret(pid, secret)
 				stateChanged = true
 			} else {
-				errorMsg := `{"type":"error","payload":"Invalid code. Must be 4 unique digits."}`
-				select {
-				case action.Client.send <- []byte(errorMsg):
-				default:
-				}
+				sendError(action.Client, fmt.Sprintf("Invalid code. Must be %s.", rules.Describe()))
 			}
 		}
 	case "guess":
-		if room.GameState.Status == "active" && room.GameState.Turn == pid {
-			if game.IsValidSecret(action.Data) {
-				room.GameState.MakeGuess(pid, action.Data)
-				stateChanged = true
-				if room.GameState.Status == "completed" && h.sheetsService != nil {
-					go h.sheetsService.RecordGame(room.GameState)
-				}
-			} else {
-				errorMsg := `{"type":"error","payload":"Invalid guess. Must be 4 unique digits."}`
-				select {
-				case action.Client.send <- []byte(errorMsg):
-				default:
-				}
+		lastRound, mover := room.GameState.LastRound, room.GameState.Turn
+		if room.GameState.ExpireTurn(time.Now()) {
+			h.announceTimeout(room, mover, lastRound)
+			sendError(action.Client, "Too late, the clock ran out.")
+			stateChanged = true
+			break
+		}
+		if room.GameState.CanGuess(pid) && !room.GameState.CanGuessAs(pid, action.Client.member) {
+			sendError(action.Client, fmt.Sprintf("It's %s's turn to guess for your team.", room.GameState.Player(pid).GuesserName()))
+			break
+		}
+		if room.GameState.CanGuess(pid) {
+			target, ok := room.GameState.TargetFor(pid, game.PlayerID(action.Target))
+			if !ok {
+				sendError(action.Client, "Pick an opponent who is still in the game.")
+				break
+			}
+			guess := rules.Normalize(action.Data)
+			if msg := guessError(room, pid, target, guess); msg != "" {
+				sendError(action.Client, msg)
+				break
+			}
+			lastRound := room.GameState.LastRound
+			room.GameState.MakeGuess(pid, target, guess)
+			stateChanged = true
+			h.announceRound(room, lastRound)
+			if victim := room.GameState.Player(target); room.GameState.IsFreeForAll() && victim.Eliminated {
+				h.broadcastNotification(room, fmt.Sprintf("%s cracked %s's code!", room.GameState.Player(pid).Name, victim.Name))
 			}
+			h.afterMove(room)
 		}
 	case "restart":
 		if room.GameState.Status != "completed" {
 			return
 		}
-		if pid == game.Player1 {
-			room.GameState.P1.IsReady = true
-		} else if pid == game.Player2 {
-			room.GameState.P2.IsReady = true
+		if p := room.GameState.Player(pid); p != nil {
+			p.IsReady = true
+		}
+		if room.Bot != nil {
+			room.GameState.Player(botPlayer).IsReady = true
 		}
 		stateChanged = true
 
-		if room.GameState.P1.IsReady && room.GameState.P2.IsReady {
+		if room.GameState.AllReady() {
 			room.GameState.Reset()
+			if room.Bot != nil {
+				room.GameState.SetSecret(botPlayer, room.Bot.ChooseSecret(rules))
+			}
 		}
+	case "team_chat":
+		h.sendTeamChat(room, action.Client, action.Data)
+	case "chat":
+		h.sendChat(room, action.Client, action.Channel, action.Data)
+	case "invite":
+		minutes, _ := strconv.Atoi(action.Data)
+		h.createInvite(room, action.Client, time.Duration(minutes)*time.Minute)
+	case "kick", "ban":
+		h.kick(room, action.Client, action.Data, action.Type == "ban")
+	case "lock_spectators":
+		h.lockSpectators(room, action.Client, action.Data == "true")
+	case "transfer_ownership":
+		h.transferOwnership(room, action.Client, action.Data)
+	case "mute", "unmute":
+		h.setMuted(room, action.Client, action.Data, action.Type == "mute")
 	case "poke":
-		canPoke := false
-		if room.GameState.Status == "active" && room.GameState.Turn != pid {
-			canPoke = true
-		} else if room.GameState.Status == "setup" {
-			if pid == game.Player1 && room.GameState.P1.IsReady && !room.GameState.P2.IsReady {
-				canPoke = true
-			} else if pid == game.Player2 && room.GameState.P2.IsReady && !room.GameState.P1.IsReady {
-				canPoke = true
+		// Poke whoever is holding the game up: the player to move, or once
+		// pid has set a secret, anyone who has not.
+		waitingOn := make(map[string]bool)
+		switch room.GameState.Status {
+		case "active":
+			for _, p := range room.GameState.Players {
+				if p.ID != pid && room.GameState.CanGuess(p.ID) {
+					waitingOn[string(p.ID)] = true
+				}
+			}
+		case "setup":
+			if me := room.GameState.Player(pid); me != nil && me.IsReady {
+				for _, p := range room.GameState.Players {
+					if !p.IsReady {
+						waitingOn[string(p.ID)] = true
+					}
+				}
 			}
 		}
 
-		if canPoke {
-			var opponentClient *Client
-			opponentPID := game.Player1
-			if pid == game.Player1 {
-				opponentPID = game.Player2
+		if len(waitingOn) > 0 {
+			pokePayload := map[string]string{"message": "Hurry up!"}
+			payloadBytes, _ := json.Marshal(pokePayload)
+			pokeMsg := map[string]interface{}{
+				"type":    "poked",
+				"payload": json.RawMessage(payloadBytes),
 			}
+			msgBytes, _ := json.Marshal(pokeMsg)
 
 			for c := range room.Clients {
-				if c.playerID == string(opponentPID) {
-					opponentClient = c
-					break
+				if c.role == "spectator" || !waitingOn[c.playerID] {
+					continue
 				}
+				c.trySend(msgBytes)
 			}
+		}
+	}
 
-			if opponentClient != nil {
-				pokePayload := map[string]string{"message": "Hurry up!"}
-				payloadBytes, _ := json.Marshal(pokePayload)
-				pokeMsg := map[string]interface{}{
-					"type":    "poked",
-					"payload": json.RawMessage(payloadBytes),
-				}
-				msgBytes, _ := json.Marshal(pokeMsg)
+	if stateChanged {
+		h.syncRoom(room)
+	}
+}
 
-				select {
-				case opponentClient.send <- msgBytes:
-				default:
-				}
-			}
+// guessError explains why pid may not play guess against target, or returns
+// "" if it may. The caller must hold room.Mutex.
+func guessError(room *Room, pid, target game.PlayerID, guess string) string {
+	rules := room.GameState.Rules
+	if !rules.IsValidSecret(guess) {
+		return fmt.Sprintf("Invalid guess. Must be %s.", rules.Describe())
+	}
+	if rules.HardMode {
+		if prior, bad := game.Contradiction(rules, guess, room.GameState.Player(pid).GuessesAt(target)); bad {
+			bulls, cows := rules.Score(prior.Code, guess)
+			return fmt.Sprintf(
+				"Hard mode: %s contradicts your guess %s, which scored %s. %s would have scored %s.",
+				guess, prior.Code, formatScore(prior.Bulls, prior.Cows), guess, formatScore(bulls, cows))
 		}
 	}
+	return ""
+}
 
-	if stateChanged {
-		h.broadcastState(room)
+// syncRoom pushes the latest state to the room and re-arms its bot and
+// clock timers. The caller must hold room.Mutex.
+func (h *Hub) syncRoom(room *Room) {
+	room.reconcileHolds(time.Now())
+	h.broadcastState(room)
+	h.scheduleBot(room)
+	h.scheduleClock(room)
+}
+
+// afterMove records, rates and analyses the game once a guess has finished
+// it, records the series too if that game decided it, and reports any
+// tournament result. The caller must hold room.Mutex.
+func (h *Hub) afterMove(room *Room) {
+	if room.GameState.Status != "completed" {
+		return
+	}
+	snapshot := room.GameState.Clone()
+	if h.history != nil {
+		go h.recordGame(snapshot)
+	}
+	h.rateGame(room, snapshot)
+	h.recordResult(room, snapshot)
+	go h.analyzeGame(room, snapshot)
+
+	if series := snapshot.Series; series != nil && series.Over() {
+		if h.history != nil {
+			go h.recordSeries(snapshot)
+		}
+		if series.Winner == game.Draw {
+			h.broadcastNotification(room, fmt.Sprintf("The series is drawn %s.", series.Score()))
+		} else {
+			h.broadcastNotification(room, fmt.Sprintf("%s wins the series %s!",
+				snapshot.Player(game.PlayerID(series.Winner)).Name, series.Score()))
+		}
+	}
+	h.reportMatch(room)
+}
+
+// announceRound sends the outcome of a simultaneous round if one closed since
+// prev was read from the game state. The caller must hold room.Mutex.
+func (h *Hub) announceRound(room *Room, prev *game.RoundResult) {
+	if result := room.GameState.LastRound; result != nil && result != prev {
+		h.broadcastEvent(room, "round_resolved", result)
+	}
+}
+
+// closeRoom removes a room that can no longer continue and sends everyone
+// left in it back to the lobby. The caller must hold room.Mutex.
+func (h *Hub) closeRoom(room *Room, roomCode string) {
+	h.deleteRoom(room, roomCode)
+
+	for c := range room.Clients {
+		c.roomCode = ""
+		c.playerID = ""
+		c.role = ""
+		c.trySend([]byte(`{"type":"redirect","payload":"/"}`))
 	}
+	room.Clients = make(map[*Client]bool)
+}
+
+func formatScore(bulls, cows int) string {
+	return fmt.Sprintf("%d %s, %d %s", bulls, plural(bulls, "bull"), cows, plural(cows, "cow"))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func sendError(client *Client, message string) {
+	payload, _ := json.Marshal(message)
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":    "error",
+		"payload": json.RawMessage(payload),
+	})
+	client.trySend(msg)
 }
 
 func (h *Hub) broadcastNotification(room *Room, message string) {
@@ -430,13 +789,33 @@ func (h *Hub) broadcastNotification(room *Room, message string) {
 	}
 
 	for _, client := range clients {
-		select {
-		case client.send <- msgBytes:
-		default:
-		}
+		client.trySend(msgBytes)
+	}
+}
+
+// broadcastEvent sends a typed message with the same payload to everyone in
+// the room.
+func (h *Hub) broadcastEvent(room *Room, msgType string, payload interface{}) {
+	for client := range room.Clients {
+		h.sendEvent(client, msgType, payload)
 	}
 }
 
+// sendEvent queues a typed message for the client, reporting false once the
+// client has been closed. It takes no locks beyond the client's own.
+func (h *Hub) sendEvent(client *Client, msgType string, payload interface{}) bool {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("error marshalling event", "type", msgType, "error", err)
+		return true
+	}
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type":    msgType,
+		"payload": json.RawMessage(payloadBytes),
+	})
+	return client.trySend(msg)
+}
+
 func (h *Hub) broadcastState(room *Room) {
 	clients := make([]*Client, 0, len(room.Clients))
 	for client := range room.Clients {
@@ -445,16 +824,33 @@ func (h *Hub) broadcastState(room *Room) {
 
 	for _, client := range clients {
 		stateCopy := *room.GameState
-		p1Copy := *room.GameState.P1
-		p2Copy := *room.GameState.P2
-		stateCopy.P1 = &p1Copy
-		stateCopy.P2 = &p2Copy
-
-		if client.role != "spectator" && stateCopy.Status != "completed" {
-			if game.PlayerID(client.playerID) == game.Player1 {
-				stateCopy.P2.Secret = ""
-			} else {
-				stateCopy.P1.Secret = ""
+		stateCopy.Players = make([]*game.PlayerState, len(room.GameState.Players))
+		for i, p := range room.GameState.Players {
+			pCopy := *p
+			stateCopy.Players[i] = &pCopy
+		}
+		stateCopy.StampClock(time.Now())
+
+		// Players only see their own side's secret and locked-in guess until
+		// the game ends, unless they have been knocked out and are watching.
+		// Spectators see everything unless the room hides it from them.
+		viewer := stateCopy.Player(game.PlayerID(client.playerID))
+		masked := stateCopy.Status != "completed"
+		if client.role == "spectator" {
+			viewer = nil
+			masked = masked && stateCopy.Rules.SpectatorView == game.SpectatorsHidden
+		} else if viewer != nil && viewer.Eliminated {
+			masked = false
+		}
+		if masked {
+			for _, p := range stateCopy.Players {
+				if p == viewer {
+					continue
+				}
+				p.Secret = ""
+				if p.Pending != nil {
+					p.Pending = &game.Guess{Timestamp: p.Pending.Timestamp}
+				}
 			}
 		}
 
@@ -465,10 +861,12 @@ func (h *Hub) broadcastState(room *Room) {
 		}
 
 		msg := map[string]interface{}{
-			"type":     "state",
-			"payload":  json.RawMessage(stateJSON),
-			"playerId": client.playerID,
-			"role":     client.role,
+			"type":             "state",
+			"payload":          json.RawMessage(stateJSON),
+			"playerId":         client.playerID,
+			"member":           client.member,
+			"role":             client.role,
+			"spectatorsLocked": room.spectatorsLocked,
 		}
 		bytes, err := json.Marshal(msg)
 		if err != nil {
@@ -476,33 +874,69 @@ func (h *Hub) broadcastState(room *Room) {
 			continue
 		}
 
-		select {
-		case client.send <- bytes:
-		case <-time.After(2 * time.Second):
+		if !client.sendWithin(bytes, 2*time.Second) {
 			slog.Warn("slow client detected, triggering unregister", "room", room.GameState.RoomCode, "player", client.playerID)
 			go func(c *Client) { h.unregister <- c }(client)
 		}
 	}
+	h.publishRoom(room)
+	h.saveRoom(room)
 }
 
 func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	guest, header := guestID(r)
+	conn, err := h.upgrader.Upgrade(w, r, header)
 	if err != nil {
 		slog.Error("failed to upgrade websocket", "error", err)
 		return
 	}
 	client := &Client{
-		hub:     h,
-		conn:    conn,
-		send:    make(chan []byte, 256),
-		limiter: rate.NewLimiter(rate.Every(time.Second/2), 5),
+		hub:         h,
+		conn:        conn,
+		send:        make(chan []byte, 256),
+		limiter:     rate.NewLimiter(rate.Every(time.Second/2), 5),
+		chatLimiter: newChatLimiter(),
+		guestID:     guest,
+	}
+	if h.accounts != nil {
+		if user, ok := h.accounts.SessionUser(r); ok {
+			client.userID = user.ID
+			client.username = user.Username
+		}
 	}
 	client.hub.register <- client
 
+	h.writers.Add(1)
 	go client.writePump()
 	go client.readPump()
 }
 
+// AllowOrigins lets pages served from other origins, such as a frontend dev
+// server, open WebSockets here. Origins are given as scheme://host[:port].
+func (h *Hub) AllowOrigins(origins []string) {
+	h.allowedOrigins = make(map[string]bool, len(origins))
+	for _, o := range origins {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			h.allowedOrigins[strings.ToLower(o)] = true
+		}
+	}
+}
+
+// checkOrigin refuses WebSockets opened by pages on other sites, which would
+// otherwise ride on the player's login and guest cookies. Requests without
+// an Origin do not come from a browser and carry no one else's cookies.
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.allowedOrigins[strings.ToLower(origin)]
+}
+
 func (h *Hub) cleanupStaleRooms() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -512,7 +946,7 @@ func (h *Hub) cleanupStaleRooms() {
 		now := time.Now()
 		for code, room := range h.Rooms {
 			room.Mutex.Lock()
-			if now.Sub(room.LastActivityAt) > 30*time.Minute {
+			if now.Sub(room.LastActivityAt) > 30*time.Minute && !room.reserved() {
 				toDelete = append(toDelete, code)
 			}
 			room.Mutex.Unlock()
@@ -521,8 +955,10 @@ func (h *Hub) cleanupStaleRooms() {
 		for _, code := range toDelete {
 			if room, ok := h.Rooms[code]; ok {
 				room.Mutex.Lock()
+				room.closed = true
+				room.stopTimers()
 				for client := range room.Clients {
-					client.conn.Close()
+					client.disconnect()
 				}
 				room.Mutex.Unlock()
 				delete(h.Rooms, code)
@@ -530,5 +966,11 @@ func (h *Hub) cleanupStaleRooms() {
 			}
 		}
 		h.Mutex.Unlock()
+
+		for _, code := range toDelete {
+			h.unpublishRoom(code)
+			h.releaseRoom(code)
+			h.dropSnapshot(code)
+		}
 	}
 }
@@ -0,0 +1,269 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/adimail/colosseum/internal/backplane"
+)
+
+const (
+	lobbyChannel = "lobby"
+	// lobbyRefresh carries every public room of a node, replacing what the
+	// others knew of its rooms; lobbyHello asks every node for one.
+	lobbyRefresh = "refresh"
+	lobbyHello   = "hello"
+	// remoteRoomTTL is how long another node's room stays listed without
+	// being refreshed, so that the rooms of a node that died drop out.
+	remoteRoomTTL  = backplane.ClaimTTL
+	lobbyOutBuffer = 256
+)
+
+// RoomSummary is how a room appears in the lobby.
+type RoomSummary struct {
+	RoomCode    string    `json:"roomCode"`
+	OwnerName   string    `json:"ownerName"`
+	PlayerCount int       `json:"playerCount"`
+	MaxPlayers  int       `json:"maxPlayers"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// summary describes the room for the lobby. The caller must hold
+// room.Mutex.
+func (r *Room) summary() RoomSummary {
+	var ownerName string
+	if owner := r.GameState.Player(r.GameState.OwnerID); owner != nil {
+		ownerName = owner.Name
+	}
+	return RoomSummary{
+		RoomCode:    r.GameState.RoomCode,
+		OwnerName:   ownerName,
+		PlayerCount: len(r.GameState.Seated()),
+		MaxPlayers:  len(r.GameState.Players),
+		Status:      r.GameState.Status,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// lobbyNews is what a node tells the others about its own public rooms.
+type lobbyNews struct {
+	Node  string        `json:"node"`
+	Kind  string        `json:"kind"`
+	Rooms []RoomSummary `json:"rooms,omitempty"`
+	Code  string        `json:"code,omitempty"`
+}
+
+// remoteRoom is a public room held by another node.
+type remoteRoom struct {
+	node    string
+	summary RoomSummary
+	seen    time.Time
+}
+
+// inLobby reports whether the room should be listed: it is public, still
+// open and its game has not finished. The caller must hold room.Mutex.
+func (r *Room) inLobby() bool {
+	return !r.closed && r.Listed() && r.GameState.Status != "completed"
+}
+
+// LobbyRooms lists the rooms open in the lobby, newest first. It reads the
+// lobby as last published, without touching the rooms themselves.
+func (h *Hub) LobbyRooms() []RoomSummary {
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	return h.lobbySnapshot()
+}
+
+// lobbySnapshot copies the lobby out, every node's rooms included. The
+// caller must hold h.lobbyMu.
+func (h *Hub) lobbySnapshot() []RoomSummary {
+	rooms := make([]RoomSummary, 0, len(h.lobby)+len(h.remoteLobby))
+	for _, s := range h.lobby {
+		rooms = append(rooms, s)
+	}
+	for _, r := range h.remoteLobby {
+		rooms = append(rooms, r.summary)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreatedAt.After(rooms[j].CreatedAt)
+	})
+	return rooms
+}
+
+// publishRoom brings the lobby up to date with the room and tells everyone
+// watching it what changed, if anything did. The caller must hold
+// room.Mutex.
+func (h *Hub) publishRoom(room *Room) {
+	code := room.GameState.RoomCode
+	if !room.inLobby() {
+		h.unpublishRoom(code)
+		return
+	}
+
+	s := room.summary()
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	prev, listed := h.lobby[code]
+	if listed && prev == s {
+		return
+	}
+	h.lobby[code] = s
+	kind := "room_created"
+	if listed {
+		kind = "room_updated"
+	}
+	h.pushLobby(kind, s)
+	h.shareLobby(lobbyNews{Kind: kind, Rooms: []RoomSummary{s}})
+}
+
+// unpublishRoom takes a room out of the lobby.
+func (h *Hub) unpublishRoom(code string) {
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	if _, listed := h.lobby[code]; !listed {
+		return
+	}
+	delete(h.lobby, code)
+	h.pushLobby("room_closed", map[string]string{"roomCode": code})
+	h.shareLobby(lobbyNews{Kind: "room_closed", Code: code})
+}
+
+// watchLobby sends c the open rooms now, then every change to them.
+func (h *Hub) watchLobby(c *Client) {
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	h.lobbyWatchers[c] = true
+	h.sendIfConnected(c, "lobby", h.lobbySnapshot())
+}
+
+func (h *Hub) unwatchLobby(c *Client) {
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	delete(h.lobbyWatchers, c)
+}
+
+// pushLobby sends a lobby change to everyone watching. The caller must hold
+// h.lobbyMu.
+func (h *Hub) pushLobby(msgType string, payload interface{}) {
+	for c := range h.lobbyWatchers {
+		if !h.sendIfConnected(c, msgType, payload) {
+			delete(h.lobbyWatchers, c)
+		}
+	}
+}
+
+// shareLobby passes a change to this node's lobby on to the other nodes. It
+// never blocks: news that cannot be queued is dropped, and the next refresh
+// puts the other nodes right. The caller must hold h.lobbyMu.
+func (h *Hub) shareLobby(news lobbyNews) {
+	if h.lobbyOut == nil {
+		return
+	}
+	news.Node = h.node
+	select {
+	case h.lobbyOut <- news:
+	default:
+		slog.Warn("dropping lobby change for the backplane", "kind", news.Kind)
+	}
+}
+
+// sendLobbyNews publishes this node's lobby changes in the order they were
+// made.
+func (h *Hub) sendLobbyNews() {
+	for news := range h.lobbyOut {
+		msg, err := json.Marshal(news)
+		if err != nil {
+			slog.Error("error marshalling lobby change", "error", err)
+			continue
+		}
+		if err := h.backplane.Publish(lobbyChannel, msg); err != nil {
+			slog.Warn("could not share lobby change", "kind", news.Kind, "error", err)
+		}
+	}
+}
+
+// refreshLobby sends the other nodes every public room of this one, and
+// drops their rooms that have not been refreshed within remoteRoomTTL.
+func (h *Hub) refreshLobby(now time.Time) {
+	h.lobbyMu.Lock()
+	defer h.lobbyMu.Unlock()
+	rooms := make([]RoomSummary, 0, len(h.lobby))
+	for _, s := range h.lobby {
+		rooms = append(rooms, s)
+	}
+	h.shareLobby(lobbyNews{Kind: lobbyRefresh, Rooms: rooms})
+	for code, r := range h.remoteLobby {
+		if now.Sub(r.seen) > remoteRoomTTL {
+			h.dropRemoteRoom(code)
+		}
+	}
+}
+
+// listenLobby takes in what the other nodes say about their public rooms.
+func (h *Hub) listenLobby(inbox <-chan []byte) {
+	for msg := range inbox {
+		var news lobbyNews
+		if err := json.Unmarshal(msg, &news); err != nil {
+			slog.Warn("dropping malformed lobby change", "error", err)
+			continue
+		}
+		if news.Node == h.node {
+			continue
+		}
+		if news.Kind == lobbyHello {
+			h.refreshLobby(time.Now())
+			continue
+		}
+		h.lobbyMu.Lock()
+		h.applyLobbyNews(news, time.Now())
+		h.lobbyMu.Unlock()
+	}
+}
+
+// applyLobbyNews brings the lobby up to date with another node's rooms and
+// tells everyone watching it here. The caller must hold h.lobbyMu.
+func (h *Hub) applyLobbyNews(news lobbyNews, now time.Time) {
+	switch news.Kind {
+	case "room_created", "room_updated":
+		for _, s := range news.Rooms {
+			h.putRemoteRoom(news.Node, s, now)
+		}
+	case "room_closed":
+		if r, ok := h.remoteLobby[news.Code]; ok && r.node == news.Node {
+			h.dropRemoteRoom(news.Code)
+		}
+	case lobbyRefresh:
+		listed := make(map[string]bool, len(news.Rooms))
+		for _, s := range news.Rooms {
+			listed[s.RoomCode] = true
+			h.putRemoteRoom(news.Node, s, now)
+		}
+		for code, r := range h.remoteLobby {
+			if r.node == news.Node && !listed[code] {
+				h.dropRemoteRoom(code)
+			}
+		}
+	}
+}
+
+// putRemoteRoom lists another node's room. The caller must hold h.lobbyMu.
+func (h *Hub) putRemoteRoom(node string, s RoomSummary, now time.Time) {
+	prev, listed := h.remoteLobby[s.RoomCode]
+	h.remoteLobby[s.RoomCode] = remoteRoom{node: node, summary: s, seen: now}
+	switch {
+	case !listed:
+		h.pushLobby("room_created", s)
+	case prev.summary != s:
+		h.pushLobby("room_updated", s)
+	}
+}
+
+// dropRemoteRoom unlists another node's room. The caller must hold
+// h.lobbyMu.
+func (h *Hub) dropRemoteRoom(code string) {
+	delete(h.remoteLobby, code)
+	h.pushLobby("room_closed", map[string]string{"roomCode": code})
+}
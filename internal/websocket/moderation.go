@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// guestCookie identifies a browser across connections, so that a ban
+// outlasts a reconnect even for players without an account.
+const guestCookie = "colosseum_guest"
+
+type ModeratePayload struct {
+	Name string `json:"name"`
+}
+
+type LockPayload struct {
+	Locked bool `json:"locked"`
+}
+
+// isOwner reports whether c holds the room's owning seat.
+func isOwner(room *Room, c *Client) bool {
+	return c.role == "player" && c.playerID == string(room.GameState.OwnerID)
+}
+
+// findByName returns everyone in the room who plays or chats under name.
+func findByName(room *Room, name string) []*Client {
+	var found []*Client
+	for c := range room.Clients {
+		if strings.EqualFold(chatName(room, c), name) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// banKeys are what a ban holds a client to: the name they asked to play
+// under, their account and their browser, so that neither reconnecting nor
+// logging out lets them back in.
+func banKeys(c *Client, name string) []string {
+	var keys []string
+	if name != "" {
+		keys = append(keys, "name:"+strings.ToLower(name))
+	}
+	if c.userID != "" {
+		keys = append(keys, "user:"+c.userID)
+	}
+	if c.guestID != "" {
+		keys = append(keys, "guest:"+c.guestID)
+	}
+	return keys
+}
+
+// moderationKeys are what a ban or mute holds c to. A spectator's label is
+// handed out afresh every visit, so only their account name counts for them.
+func moderationKeys(room *Room, c *Client) []string {
+	name := chatName(room, c)
+	if c.role == "spectator" {
+		name = c.username
+	}
+	return banKeys(c, name)
+}
+
+// isBanned reports whether c, asking to enter under name, has been banned
+// from the room. The caller must hold room.Mutex.
+func (r *Room) isBanned(c *Client, name string) bool {
+	for _, key := range banKeys(c, name) {
+		if r.banned[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// kick removes everyone going by name from the room, and with ban keeps
+// them out for as long as the room lasts. The caller must hold room.Mutex.
+func (h *Hub) kick(room *Room, by *Client, name string, ban bool) {
+	verb := "kick"
+	if ban {
+		verb = "ban"
+	}
+	if !isOwner(room, by) {
+		sendError(by, fmt.Sprintf("Only the room owner can %s people.", verb))
+		return
+	}
+	name = strings.TrimSpace(name)
+	targets := findByName(room, name)
+	if len(targets) == 0 {
+		sendError(by, fmt.Sprintf("There is no one called %s in the room.", name))
+		return
+	}
+
+	for _, c := range targets {
+		if c == by {
+			sendError(by, fmt.Sprintf("You cannot %s yourself.", verb))
+			return
+		}
+	}
+	for _, c := range targets {
+		if ban {
+			for _, key := range moderationKeys(room, c) {
+				room.banned[key] = true
+			}
+		}
+		h.sendEvent(c, "kicked", map[string]interface{}{
+			"roomCode": room.GameState.RoomCode,
+			"banned":   ban,
+		})
+		h.ejectClient(room, c)
+	}
+
+	if ban {
+		h.broadcastNotification(room, fmt.Sprintf("%s has been banned from the room.", name))
+	} else {
+		h.broadcastNotification(room, fmt.Sprintf("%s has been kicked from the room.", name))
+	}
+	h.syncRoom(room)
+}
+
+// ejectClient takes c out of the room, giving up any seat it held, and
+// closes its connection once what has been queued for it is sent. The
+// caller must hold room.Mutex.
+func (h *Hub) ejectClient(room *Room, c *Client) {
+	delete(room.Clients, c)
+	if c.role == "spectator" {
+		if room.GameState.Spectators > 0 {
+			room.GameState.Spectators--
+		}
+	} else {
+		room.dropSession(c)
+		h.releaseSeat(room, c.playerID, c.member, game.EndForfeit)
+	}
+	c.roomCode = ""
+	c.playerID = ""
+	c.role = ""
+
+	// The hub lock is not taken under a room lock; the client is dropped
+	// from the hub when its connection closes.
+	c.closeSend()
+}
+
+// lockSpectators closes the stands to newcomers, or opens them again.
+// Spectators already watching stay. The caller must hold room.Mutex.
+func (h *Hub) lockSpectators(room *Room, by *Client, locked bool) {
+	if !isOwner(room, by) {
+		sendError(by, "Only the room owner can close the stands.")
+		return
+	}
+	if room.spectatorsLocked == locked {
+		return
+	}
+	room.spectatorsLocked = locked
+	if locked {
+		h.broadcastNotification(room, "The stands are closed to new spectators.")
+	} else {
+		h.broadcastNotification(room, "The stands are open again.")
+	}
+	h.syncRoom(room)
+}
+
+// transferOwnership hands the room to another seated player. The caller
+// must hold room.Mutex.
+func (h *Hub) transferOwnership(room *Room, by *Client, name string) {
+	if !isOwner(room, by) {
+		sendError(by, "Only the room owner can hand the room over.")
+		return
+	}
+	name = strings.TrimSpace(name)
+	for _, p := range room.GameState.Seated() {
+		if p.ID == room.GameState.OwnerID || !strings.EqualFold(p.Name, name) {
+			continue
+		}
+		room.GameState.OwnerID = p.ID
+		h.broadcastNotification(room, fmt.Sprintf("%s now owns the room.", p.Name))
+		h.syncRoom(room)
+		return
+	}
+	sendError(by, fmt.Sprintf("%s is not seated in the room.", name))
+}
+
+// guestID returns the browser's guest ID, and the header that sets one on
+// the upgrade response if it did not have one yet.
+func guestID(r *http.Request) (string, http.Header) {
+	if cookie, err := r.Cookie(guestCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	id := newSessionToken()
+	cookie := &http.Cookie{
+		Name:     guestCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  time.Now().Add(365 * 24 * time.Hour),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	return id, http.Header{"Set-Cookie": {cookie.String()}}
+}
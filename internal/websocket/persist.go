@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/adimail/colosseum/internal/bot"
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/solver"
+)
+
+// restoreGrace is how long players have to take their seats back in a room
+// restored after a restart. Snapshots older than that are not restored.
+const restoreGrace = 5 * time.Minute
+
+const msgRestarting = "The server is restarting. Hold on, you will be reconnected to your game."
+
+// roomSnapshot is what is saved of a room so that it can be rebuilt after a
+// restart. Nobody is connected to a rebuilt room, so every seat is held for
+// its player to resume.
+type roomSnapshot struct {
+	SavedAt          time.Time        `json:"savedAt"`
+	State            *game.GameState  `json:"state"`
+	Log              []game.Event     `json:"log,omitempty"`
+	CreatedAt        time.Time        `json:"createdAt"`
+	Bot              *BotOptions      `json:"bot,omitempty"`
+	Analysis         *solver.Analysis `json:"analysis,omitempty"`
+	Seats            []seatSnapshot   `json:"seats"`
+	Chat             []ChatMessage    `json:"chat,omitempty"`
+	Muted            map[string]bool  `json:"muted,omitempty"`
+	SpectatorSeq     int              `json:"spectatorSeq"`
+	Visibility       string           `json:"visibility"`
+	PasswordHash     []byte           `json:"passwordHash,omitempty"`
+	Banned           map[string]bool  `json:"banned,omitempty"`
+	SpectatorsLocked bool             `json:"spectatorsLocked,omitempty"`
+}
+
+type seatSnapshot struct {
+	Token    string `json:"token"`
+	PlayerID string `json:"playerId"`
+	Member   int    `json:"member"`
+}
+
+// snapshot captures the room as of now. The caller must hold room.Mutex.
+func (r *Room) snapshot(now time.Time) roomSnapshot {
+	snap := roomSnapshot{
+		SavedAt:          now,
+		State:            r.GameState,
+		Log:              r.GameState.Log,
+		CreatedAt:        r.CreatedAt,
+		Analysis:         r.Analysis,
+		Chat:             r.chat,
+		Muted:            r.muted,
+		SpectatorSeq:     r.spectatorSeq,
+		Visibility:       r.access.visibility,
+		PasswordHash:     r.access.passwordHash,
+		Banned:           r.banned,
+		SpectatorsLocked: r.spectatorsLocked,
+	}
+	if r.Bot != nil {
+		snap.Bot = &BotOptions{Difficulty: r.Bot.Difficulty, ThinkMs: int(r.Bot.ThinkDelay / time.Millisecond)}
+	}
+	for token, s := range r.sessions {
+		seat := seatSnapshot{Token: token, PlayerID: s.playerID, Member: s.member}
+		if s.client != nil {
+			seat.PlayerID = s.client.playerID
+			seat.Member = s.client.member
+		}
+		snap.Seats = append(snap.Seats, seat)
+	}
+	return snap
+}
+
+// saveRoom snapshots the room to disk. Tournament matches are left out,
+// since their tournament does not survive a restart. The caller must hold
+// room.Mutex.
+func (h *Hub) saveRoom(room *Room) {
+	if h.snapshots == nil || room.match != nil || room.closed || h.closing.Load() {
+		return
+	}
+	h.writeSnapshot(room, time.Now())
+}
+
+func (h *Hub) writeSnapshot(room *Room, now time.Time) {
+	data, err := json.Marshal(room.snapshot(now))
+	if err != nil {
+		slog.Error("error marshalling room snapshot", "room", room.GameState.RoomCode, "error", err)
+		return
+	}
+	h.snapshots.Save(room.GameState.RoomCode, data)
+}
+
+func (h *Hub) dropSnapshot(code string) {
+	if h.snapshots == nil || h.closing.Load() {
+		return
+	}
+	h.snapshots.Delete(code)
+}
+
+// restoreRooms rebuilds the rooms saved before the last shutdown, holding
+// every seat for restoreGrace.
+func (h *Hub) restoreRooms() {
+	if h.snapshots == nil {
+		return
+	}
+	saved, err := h.snapshots.Load()
+	if err != nil {
+		slog.Warn("could not load room snapshots", "error", err)
+		return
+	}
+
+	now := time.Now()
+	restored := 0
+	for code, data := range saved {
+		var snap roomSnapshot
+		if err := json.Unmarshal(data, &snap); err != nil || snap.State == nil || snap.State.RoomCode != code {
+			slog.Warn("discarding unreadable room snapshot", "room", code, "error", err)
+			h.snapshots.Delete(code)
+			continue
+		}
+		if now.Sub(snap.SavedAt) > restoreGrace || len(snap.Seats) == 0 {
+			h.snapshots.Delete(code)
+			continue
+		}
+		room, err := h.restoreRoom(snap, now)
+		if err != nil {
+			slog.Warn("discarding room snapshot", "room", code, "error", err)
+			h.snapshots.Delete(code)
+			continue
+		}
+
+		h.Mutex.Lock()
+		h.Rooms[code] = room
+		h.Mutex.Unlock()
+		room.Mutex.Lock()
+		h.syncRoom(room)
+		room.Mutex.Unlock()
+		restored++
+	}
+	if restored > 0 {
+		slog.Info("restored rooms from snapshots", "count", restored)
+	}
+}
+
+func (h *Hub) restoreRoom(snap roomSnapshot, now time.Time) (*Room, error) {
+	var roomBot *bot.Bot
+	if snap.Bot != nil {
+		var err error
+		roomBot, err = bot.New(snap.Bot.Difficulty, time.Duration(snap.Bot.ThinkMs)*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	code := snap.State.RoomCode
+	room := &Room{
+		GameState:        snap.State,
+		Clients:          make(map[*Client]bool),
+		CreatedAt:        snap.CreatedAt,
+		LastActivityAt:   now,
+		Bot:              roomBot,
+		Analysis:         snap.Analysis,
+		sessions:         make(map[string]*session),
+		chat:             snap.Chat,
+		muted:            snap.Muted,
+		spectatorSeq:     snap.SpectatorSeq,
+		access:           access{visibility: snap.Visibility, passwordHash: snap.PasswordHash},
+		banned:           snap.Banned,
+		spectatorsLocked: snap.SpectatorsLocked,
+	}
+	if room.muted == nil {
+		room.muted = make(map[string]bool)
+	}
+	if room.banned == nil {
+		room.banned = make(map[string]bool)
+	}
+	room.GameState.Log = snap.Log
+	room.GameState.Spectators = 0
+	// The clock stood still while the server was down.
+	room.GameState.Pause(snap.SavedAt)
+
+	for _, seat := range snap.Seats {
+		s := &session{token: seat.Token, playerID: seat.PlayerID, member: seat.Member}
+		s.expiry = time.AfterFunc(restoreGrace, func() {
+			h.expireSession(code, s.token)
+		})
+		room.sessions[s.token] = s
+	}
+	return room, nil
+}
+
+// Shutdown saves every room as it stands and tells everyone connected that
+// the server is restarting, then closes their connections. Nothing that
+// happens to the rooms afterwards, such as their players dropping, is saved.
+// It returns once every connection has been sent the notice, or when ctx
+// is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.closing.Store(true)
+
+	h.Mutex.Lock()
+	rooms := make([]*Room, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room)
+	}
+	h.Mutex.Unlock()
+
+	now := time.Now()
+	for _, room := range rooms {
+		room.Mutex.Lock()
+		if h.snapshots != nil && room.match == nil {
+			h.writeSnapshot(room, now)
+		}
+		room.stopTimers()
+		// Out of the room, nothing more is broadcast to the clients once
+		// their connections are closed below.
+		room.Clients = make(map[*Client]bool)
+		room.Mutex.Unlock()
+	}
+
+	h.Mutex.Lock()
+	for c := range h.clients {
+		// Proxies' players are connected to servers that are staying up.
+		if c.origin == "" {
+			h.sendEvent(c, "notification", map[string]string{"message": msgRestarting})
+		}
+		delete(h.clients, c)
+		c.closeSend()
+	}
+	h.Mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,183 @@
+package websocket
+
+import (
+	"math"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/rating"
+)
+
+const (
+	queueTick = 2 * time.Second
+	// A queued player starts out only matched within queueWindow rating
+	// points, widening by queueWidenBy every queueWidenEvery they wait.
+	queueWindow     = 100
+	queueWidenBy    = 50
+	queueWidenEvery = 10 * time.Second
+	queueMaxWindow  = 800
+)
+
+// queueEntry is a player waiting for a ranked match. The queue belongs to
+// the hub loop and is only touched from it.
+type queueEntry struct {
+	client *Client
+	name   string
+	rating float64
+	joined time.Time
+	// failed holds the players this one could not be seated with, who it
+	// is not matched with again.
+	failed map[*Client]bool
+}
+
+func (e *queueEntry) window(now time.Time) float64 {
+	steps := float64(now.Sub(e.joined) / queueWidenEvery)
+	return math.Min(queueWindow+steps*queueWidenBy, queueMaxWindow)
+}
+
+func (h *Hub) handleJoinQueue(action *RoomAction) {
+	if action.Client.roomCode != "" {
+		sendError(action.Client, "Leave your room before joining the queue.")
+		return
+	}
+	name, ok := h.playerName(action.Client, action.Name)
+	if !ok {
+		return
+	}
+	if name == "" {
+		sendError(action.Client, "Enter a name to join the queue.")
+		return
+	}
+
+	h.dequeue(action.Client)
+	r := rating.Rating{Rating: rating.Initial}
+	if h.ratings != nil {
+		r = h.ratings.Get(rating.Player{UserID: action.Client.userID, Name: name})
+	}
+	now := time.Now()
+	entry := &queueEntry{client: action.Client, name: name, rating: r.Rating, joined: now}
+	h.queue = append(h.queue, entry)
+
+	h.sendEvent(action.Client, "queue", map[string]interface{}{
+		"rating":  r.Rating,
+		"window":  entry.window(now),
+		"waiting": len(h.queue),
+	})
+	h.matchQueue(now)
+}
+
+func (h *Hub) handleLeaveQueue(action *RoomAction) {
+	if h.dequeue(action.Client) {
+		h.sendEvent(action.Client, "queue_left", nil)
+	}
+}
+
+// dequeue takes client out of the queue, reporting whether it was in it.
+func (h *Hub) dequeue(client *Client) bool {
+	for i, e := range h.queue {
+		if e.client == client {
+			h.queue = append(h.queue[:i], h.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// matchQueue pairs off waiting players, longest waiting first, each with the
+// closest rated opponent that both of their windows allow.
+func (h *Hub) matchQueue(now time.Time) {
+	h.pruneQueue()
+	for i := 0; i < len(h.queue); i++ {
+		a := h.queue[i]
+		best := -1
+		bestGap := math.Inf(1)
+		for j := i + 1; j < len(h.queue); j++ {
+			b := h.queue[j]
+			if a.failed[b.client] || b.failed[a.client] {
+				continue
+			}
+			gap := math.Abs(a.rating - b.rating)
+			if gap <= math.Min(a.window(now), b.window(now)) && gap < bestGap {
+				best, bestGap = j, gap
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		b := h.queue[best]
+		h.queue = append(h.queue[:best], h.queue[best+1:]...)
+		h.queue = append(h.queue[:i], h.queue[i+1:]...)
+		i--
+		h.startMatch(a, b)
+	}
+}
+
+// requeue puts e back in the queue in the place its wait earned it.
+func (h *Hub) requeue(e *queueEntry) {
+	i := 0
+	for i < len(h.queue) && !h.queue[i].joined.After(e.joined) {
+		i++
+	}
+	h.queue = append(h.queue[:i], append([]*queueEntry{e}, h.queue[i:]...)...)
+}
+
+// pruneQueue drops players who have disconnected or found a room some other
+// way since they queued.
+func (h *Hub) pruneQueue() {
+	h.Mutex.Lock()
+	defer h.Mutex.Unlock()
+	kept := h.queue[:0]
+	for _, e := range h.queue {
+		if h.clients[e.client] && e.client.roomCode == "" {
+			kept = append(kept, e)
+		}
+	}
+	h.queue = kept
+}
+
+// startMatch puts a matched pair in a fresh room with the default rules, a
+// creating it and b joining as though they had swapped the room code. If b
+// cannot join, the room is closed again and both go back in the queue.
+func (h *Hub) startMatch(a, b *queueEntry) {
+	h.handleCreateRoom(&RoomAction{Client: a.client, Name: a.name, Rules: game.DefaultRules()})
+	code := a.client.roomCode
+	if code == "" {
+		h.requeue(b)
+		return
+	}
+	h.handleJoinRoom(&RoomAction{Client: b.client, Name: b.name, Code: code})
+	if b.client.roomCode != code {
+		h.cancelMatch(a.client, code)
+		for _, e := range []*queueEntry{a, b} {
+			if e.failed == nil {
+				e.failed = make(map[*Client]bool)
+			}
+		}
+		a.failed[b.client] = true
+		b.failed[a.client] = true
+		h.requeue(a)
+		h.requeue(b)
+		return
+	}
+	h.sendEvent(a.client, "match_found", map[string]interface{}{
+		"roomCode": code, "opponent": b.name, "opponentRating": b.rating,
+	})
+	h.sendEvent(b.client, "match_found", map[string]interface{}{
+		"roomCode": code, "opponent": a.name, "opponentRating": a.rating,
+	})
+}
+
+// cancelMatch takes c back out of the room it just opened for a match that
+// fell through, closing the room.
+func (h *Hub) cancelMatch(c *Client, code string) {
+	room := h.lockRoom(code)
+	if room == nil {
+		return
+	}
+	defer room.Mutex.Unlock()
+	delete(room.Clients, c)
+	c.roomCode = ""
+	c.playerID = ""
+	c.role = ""
+	h.deleteRoom(room, code)
+}
@@ -0,0 +1,67 @@
+package websocket
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/rating"
+)
+
+// rateGame updates the ratings of everyone in a finished two-sided game and
+// tells the room how they moved. Bot games and free-for-alls are not rated.
+// The caller must hold room.Mutex.
+func (h *Hub) rateGame(room *Room, gs *game.GameState) {
+	if h.ratings == nil || room.Bot != nil || len(gs.Players) != 2 || gs.Winner == "" {
+		return
+	}
+
+	a, b := gs.Players[0], gs.Players[1]
+	score := 0.5
+	switch gs.Winner {
+	case string(a.ID):
+		score = 1
+	case string(b.ID):
+		score = 0
+	}
+
+	changes := h.ratings.Record(h.ratedSide(a), h.ratedSide(b), score)
+	if len(changes) == 0 {
+		return
+	}
+
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		parts[i] = fmt.Sprintf("%s %d (%+d)", c.Name, int(math.Round(c.After)), int(math.Round(c.Delta())))
+	}
+	h.broadcastEvent(room, "ratings", changes)
+	h.broadcastNotification(room, "Ratings: "+strings.Join(parts, ", "))
+}
+
+// ratedSide names the players on a side for the ratings.
+func (h *Hub) ratedSide(p *game.PlayerState) []rating.Player {
+	names := sideNames(p)
+	players := make([]rating.Player, len(names))
+	for i, name := range names {
+		players[i] = rating.Player{UserID: h.userIDOf(name), Name: name}
+	}
+	return players
+}
+
+func sideNames(p *game.PlayerState) []string {
+	if len(p.Members) > 0 {
+		return p.Members
+	}
+	return []string{p.Name}
+}
+
+// userIDOf returns the ID of the account name belongs to, or "" for a
+// guest. Guests cannot play under an account's name, so a registered name
+// in a game is always its owner.
+func (h *Hub) userIDOf(name string) string {
+	if h.accounts == nil {
+		return ""
+	}
+	return h.accounts.UserID(name)
+}
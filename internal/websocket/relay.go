@@ -0,0 +1,424 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/adimail/colosseum/internal/backplane"
+	"golang.org/x/time/rate"
+)
+
+// What servers sharing a backplane say to each other. A client whose room
+// lives on another node keeps its connection where it is: that node relays
+// what the client sends to the room's owner, where a proxy client stands in
+// for it, and the owner relays back whatever the proxy is sent.
+const (
+	// relayMessage carries a message from a client to the owner of its room.
+	relayMessage = "message"
+	// relayGone tells the owner that a client has disconnected or moved on.
+	relayGone = "gone"
+	// relayOut carries a message from a room to a client on another node.
+	relayOut = "out"
+	// relayClose tells a client's node that the room has let the client go.
+	relayClose = "close"
+	// relayInfo asks the owner of a room what the room page shows about it.
+	relayInfo      = "info"
+	relayInfoReply = "info_reply"
+
+	roomInfoTimeout = 2 * time.Second
+)
+
+// localMessages are handled by the node the client is connected to, even
+// while the client's room lives elsewhere.
+var localMessages = map[string]bool{
+	"create_room":            true,
+	"join_queue":             true,
+	"leave_queue":            true,
+	"subscribe_tournament":   true,
+	"unsubscribe_tournament": true,
+	"subscribe_lobby":        true,
+	"unsubscribe_lobby":      true,
+	"replay":                 true,
+	"replay_speed":           true,
+	"stop_replay":            true,
+}
+
+type relayEnvelope struct {
+	Kind string `json:"kind"`
+	From string `json:"from"`
+	// Client names the client on its own node, or the request in an info
+	// exchange.
+	Client   string          `json:"client"`
+	UserID   string          `json:"userId,omitempty"`
+	Username string          `json:"username,omitempty"`
+	GuestID  string          `json:"guestId,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// RoomInfo is what the room page shows before its visitor is in the room.
+type RoomInfo struct {
+	RoomCode   string `json:"roomCode"`
+	OwnerName  string `json:"ownerName"`
+	Visibility string `json:"visibility"`
+}
+
+// UseBackplane lets the hub share rooms with the other servers on bp, where
+// it goes by node. Call it before the hub serves any clients.
+func (h *Hub) UseBackplane(bp backplane.Backplane, node string) error {
+	inbox, err := bp.Subscribe(nodeChannel(node))
+	if err != nil {
+		return err
+	}
+	lobby, err := bp.Subscribe(lobbyChannel)
+	if err != nil {
+		return err
+	}
+	h.backplane = bp
+	h.node = node
+	h.lobbyOut = make(chan lobbyNews, lobbyOutBuffer)
+	// Rooms restored from snapshots were opened before the backplane was
+	// there to claim them.
+	h.claimRooms()
+	go h.listenRelay(inbox)
+	go h.listenLobby(lobby)
+	go h.sendLobbyNews()
+	go h.renewClaims()
+	// Tell the other nodes about this one's rooms, and ask about theirs.
+	h.refreshLobby(time.Now())
+	h.lobbyMu.Lock()
+	h.shareLobby(lobbyNews{Kind: lobbyHello})
+	h.lobbyMu.Unlock()
+	slog.Info("sharing rooms over the backplane", "node", node)
+	return nil
+}
+
+func nodeChannel(node string) string {
+	return "node:" + node
+}
+
+// claimRoom reserves a new room code across every node, so that two nodes
+// never open rooms under the same code. Without the backplane, or if it
+// cannot be reached, the room is only checked against this node's.
+func (h *Hub) claimRoom(code string) bool {
+	if h.backplane == nil {
+		return true
+	}
+	ok, err := h.backplane.Claim(code, h.node)
+	if err != nil {
+		slog.Warn("could not claim room on the backplane", "code", code, "error", err)
+		return true
+	}
+	return ok
+}
+
+func (h *Hub) releaseRoom(code string) {
+	if h.backplane == nil {
+		return
+	}
+	if err := h.backplane.Release(code, h.node); err != nil {
+		slog.Warn("could not release room on the backplane", "code", code, "error", err)
+	}
+}
+
+// renewClaims keeps the claims on this node's rooms from expiring for as
+// long as the rooms are open, and the other nodes' lobbies listing them.
+func (h *Hub) renewClaims() {
+	ticker := time.NewTicker(backplane.ClaimTTL / 3)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		h.claimRooms()
+		h.refreshLobby(now)
+	}
+}
+
+func (h *Hub) claimRooms() {
+	h.Mutex.Lock()
+	codes := make([]string, 0, len(h.Rooms))
+	for code := range h.Rooms {
+		codes = append(codes, code)
+	}
+	h.Mutex.Unlock()
+
+	for _, code := range codes {
+		if ok, err := h.backplane.Claim(code, h.node); err != nil || !ok {
+			slog.Warn("lost the claim on a room", "code", code, "error", err)
+		}
+	}
+}
+
+// roomOwner returns the node that holds the room if it is not this one.
+func (h *Hub) roomOwner(code string) string {
+	if h.backplane == nil || code == "" {
+		return ""
+	}
+	h.Mutex.Lock()
+	_, local := h.Rooms[code]
+	h.Mutex.Unlock()
+	if local {
+		return ""
+	}
+	owner, err := h.backplane.Owner(code)
+	if err != nil {
+		slog.Warn("could not look up room owner", "code", code, "error", err)
+		return ""
+	}
+	if owner == h.node {
+		return ""
+	}
+	return owner
+}
+
+// relay sends a client's message on to the node that owns its room, and
+// reports whether it did. Joining a room held elsewhere attaches the client
+// to that node until it joins another room or disconnects.
+func (h *Hub) relay(c *Client, m Message, raw []byte) bool {
+	if h.backplane == nil || c.origin != "" || localMessages[m.Type] {
+		return false
+	}
+
+	switch m.Type {
+	case "join_room", "spectate", "resume":
+		var p struct {
+			Code     string `json:"code"`
+			RoomCode string `json:"roomCode"`
+		}
+		json.Unmarshal(m.Payload, &p)
+		code := p.Code
+		if m.Type == "resume" {
+			code = p.RoomCode
+		}
+		h.attach(c, h.roomOwner(code))
+	}
+
+	h.relayMu.Lock()
+	node := c.remote
+	h.relayMu.Unlock()
+	if node == "" {
+		return false
+	}
+	h.publishRelay(node, relayEnvelope{
+		Kind:     relayMessage,
+		Client:   c.relayID,
+		UserID:   c.userID,
+		Username: c.username,
+		GuestID:  c.guestID,
+		Data:     raw,
+	})
+	return true
+}
+
+// attach points the client at the node that owns its room, "" meaning this
+// one, letting the node it was attached to before know it has gone.
+func (h *Hub) attach(c *Client, node string) {
+	h.relayMu.Lock()
+	prev := c.remote
+	c.remote = node
+	if node != "" {
+		if c.relayID == "" {
+			c.relayID = newSessionToken()
+		}
+		h.relayed[c.relayID] = c
+	} else if c.relayID != "" {
+		delete(h.relayed, c.relayID)
+	}
+	h.relayMu.Unlock()
+
+	if prev != "" && prev != node {
+		h.publishRelay(prev, relayEnvelope{Kind: relayGone, Client: c.relayID})
+	}
+}
+
+// detach lets the node holding the client's room know the client is gone.
+func (h *Hub) detach(c *Client) {
+	if h.backplane != nil && c.origin == "" {
+		h.attach(c, "")
+	}
+}
+
+func (h *Hub) publishRelay(node string, env relayEnvelope) {
+	env.From = h.node
+	msg, err := json.Marshal(env)
+	if err != nil {
+		slog.Error("error marshalling relay message", "error", err)
+		return
+	}
+	if err := h.backplane.Publish(nodeChannel(node), msg); err != nil {
+		slog.Warn("could not relay message", "node", node, "kind", env.Kind, "error", err)
+	}
+}
+
+// listenRelay handles what other nodes send this one, one message at a
+// time so that each client's messages are handled in the order it sent
+// them.
+func (h *Hub) listenRelay(inbox <-chan []byte) {
+	for msg := range inbox {
+		var env relayEnvelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			slog.Warn("dropping malformed relay message", "error", err)
+			continue
+		}
+		switch env.Kind {
+		case relayMessage:
+			h.proxyFor(env).handleMessage(env.Data)
+		case relayGone:
+			key := env.From + "/" + env.Client
+			h.relayMu.Lock()
+			proxy := h.proxies[key]
+			delete(h.proxies, key)
+			h.relayMu.Unlock()
+			if proxy != nil {
+				h.unregister <- proxy
+			}
+		case relayOut:
+			h.relayMu.Lock()
+			c := h.relayed[env.Client]
+			h.relayMu.Unlock()
+			if c != nil {
+				h.sendRaw(c, env.Data)
+			}
+		case relayClose:
+			h.relayMu.Lock()
+			c := h.relayed[env.Client]
+			if c != nil && c.remote == env.From {
+				delete(h.relayed, env.Client)
+				c.remote = ""
+			} else {
+				c = nil
+			}
+			h.relayMu.Unlock()
+			if c != nil {
+				h.unregister <- c
+			}
+		case relayInfo:
+			var code string
+			json.Unmarshal(env.Data, &code)
+			info, _ := h.localRoomInfo(code)
+			data, _ := json.Marshal(info)
+			h.publishRelay(env.From, relayEnvelope{Kind: relayInfoReply, Client: env.Client, Data: data})
+		case relayInfoReply:
+			h.relayMu.Lock()
+			reply := h.infoWaiters[env.Client]
+			delete(h.infoWaiters, env.Client)
+			h.relayMu.Unlock()
+			if reply != nil {
+				reply <- env.Data
+			}
+		}
+	}
+}
+
+// proxyFor returns the stand-in for a client of another node, setting up
+// a new one if the client has not been heard from since it last left a
+// room here.
+func (h *Hub) proxyFor(env relayEnvelope) *Client {
+	key := env.From + "/" + env.Client
+	h.relayMu.Lock()
+	defer h.relayMu.Unlock()
+	if proxy := h.proxies[key]; proxy != nil && !proxy.isClosed() {
+		return proxy
+	}
+
+	proxy := &Client{
+		hub:  h,
+		send: make(chan []byte, 256),
+		// The client's own node has already rate limited it.
+		limiter:     rate.NewLimiter(rate.Inf, 0),
+		chatLimiter: newChatLimiter(),
+		userID:      env.UserID,
+		username:    env.Username,
+		guestID:     env.GuestID,
+		origin:      env.From,
+		relayID:     env.Client,
+	}
+	h.proxies[key] = proxy
+	h.Mutex.Lock()
+	h.clients[proxy] = true
+	h.Mutex.Unlock()
+	go h.forwardProxy(key, proxy)
+	return proxy
+}
+
+// forwardProxy relays what the room sends a proxy to the real client, and
+// once the room lets the proxy go, lets the client's node know.
+func (h *Hub) forwardProxy(key string, proxy *Client) {
+	for msg := range proxy.send {
+		h.publishRelay(proxy.origin, relayEnvelope{Kind: relayOut, Client: proxy.relayID, Data: msg})
+	}
+	h.relayMu.Lock()
+	if h.proxies[key] == proxy {
+		delete(h.proxies, key)
+	}
+	h.relayMu.Unlock()
+	// A proxy ejected from its room is closed without being unregistered.
+	h.Mutex.Lock()
+	delete(h.clients, proxy)
+	h.Mutex.Unlock()
+	h.publishRelay(proxy.origin, relayEnvelope{Kind: relayClose, Client: proxy.relayID})
+}
+
+// sendRaw queues an already encoded message for c if it is still
+// connected.
+func (h *Hub) sendRaw(c *Client, msg []byte) {
+	c.trySend(msg)
+}
+
+// disconnect closes the client's connection. A proxy has none of its own,
+// so it is unregistered instead, which lets the real client's node know.
+func (c *Client) disconnect() {
+	if c.conn != nil {
+		c.conn.Close()
+		return
+	}
+	go func() { c.hub.unregister <- c }()
+}
+
+// RoomInfo describes a room for the room page, asking the node that owns
+// it if that is not this one.
+func (h *Hub) RoomInfo(code string) (RoomInfo, bool) {
+	if info, ok := h.localRoomInfo(code); ok {
+		return info, true
+	}
+	owner := h.roomOwner(code)
+	if owner == "" {
+		return RoomInfo{}, false
+	}
+
+	id := newSessionToken()
+	reply := make(chan json.RawMessage, 1)
+	h.relayMu.Lock()
+	h.infoWaiters[id] = reply
+	h.relayMu.Unlock()
+	defer func() {
+		h.relayMu.Lock()
+		delete(h.infoWaiters, id)
+		h.relayMu.Unlock()
+	}()
+
+	data, _ := json.Marshal(code)
+	h.publishRelay(owner, relayEnvelope{Kind: relayInfo, Client: id, Data: data})
+	select {
+	case data := <-reply:
+		var info RoomInfo
+		if json.Unmarshal(data, &info) != nil || info.RoomCode == "" {
+			return RoomInfo{}, false
+		}
+		return info, true
+	case <-time.After(roomInfoTimeout):
+		slog.Warn("room owner did not answer", "code", code, "node", owner)
+		return RoomInfo{}, false
+	}
+}
+
+func (h *Hub) localRoomInfo(code string) (RoomInfo, bool) {
+	room := h.lockRoom(code)
+	if room == nil {
+		return RoomInfo{}, false
+	}
+	defer room.Mutex.Unlock()
+	var ownerName string
+	if owner := room.GameState.Player(room.GameState.OwnerID); owner != nil {
+		ownerName = owner.Name
+	}
+	return RoomInfo{RoomCode: code, OwnerName: ownerName, Visibility: room.Visibility()}, true
+}
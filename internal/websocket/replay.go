@@ -0,0 +1,116 @@
+package websocket
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/adimail/colosseum/internal/history"
+)
+
+const (
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 16
+	// maxReplayGap caps the wait between two events, before speed-up, so a
+	// long think does not stall the replay.
+	maxReplayGap = 5 * time.Second
+)
+
+type ReplayPayload struct {
+	GameID string  `json:"gameId"`
+	Speed  float64 `json:"speed"`
+}
+
+// replay is a finished game being played back to one client. The client's
+// read loop owns it; the playback goroutine only reads speed.
+type replay struct {
+	speed atomic.Uint64
+	stop  chan struct{}
+}
+
+func (r *replay) setSpeed(speed float64) {
+	if speed == 0 {
+		speed = 1
+	}
+	speed = min(max(speed, minReplaySpeed), maxReplaySpeed)
+	r.speed.Store(math.Float64bits(speed))
+}
+
+func (r *replay) getSpeed() float64 {
+	return math.Float64frombits(r.speed.Load())
+}
+
+func (c *Client) startReplay(gameID string, speed float64) {
+	c.stopReplay()
+	r := &replay{stop: make(chan struct{})}
+	r.setSpeed(speed)
+	c.replay = r
+	go c.hub.playReplay(c, r, gameID)
+}
+
+func (c *Client) setReplaySpeed(speed float64) {
+	if c.replay != nil {
+		c.replay.setSpeed(speed)
+	}
+}
+
+func (c *Client) stopReplay() {
+	if c.replay != nil {
+		close(c.replay.stop)
+		c.replay = nil
+	}
+}
+
+// playReplay sends a recorded game's events to c with the gaps between them
+// as played, divided by the replay speed.
+func (h *Hub) playReplay(c *Client, r *replay, gameID string) {
+	if h.history == nil {
+		h.sendIfConnected(c, "error", "Game history service is not available")
+		return
+	}
+	t, err := h.history.GetGame(gameID)
+	if errors.Is(err, history.ErrNotFound) {
+		h.sendIfConnected(c, "error", "Game not found")
+		return
+	}
+	if err != nil {
+		h.sendIfConnected(c, "error", "Failed to load game")
+		return
+	}
+
+	events := t.Events
+	t.Events = nil
+	if !h.sendIfConnected(c, "replay_start", map[string]interface{}{
+		"game":   t,
+		"events": len(events),
+		"speed":  r.getSpeed(),
+	}) {
+		return
+	}
+
+	var prev int64
+	for i, e := range events {
+		if i > 0 {
+			gap := min(max(time.Duration(e.At-prev)*time.Millisecond, 0), maxReplayGap)
+			timer := time.NewTimer(time.Duration(float64(gap) / r.getSpeed()))
+			select {
+			case <-timer.C:
+			case <-r.stop:
+				timer.Stop()
+				return
+			}
+		}
+		prev = e.At
+		if !h.sendIfConnected(c, "replay_event", e) {
+			return
+		}
+	}
+	h.sendIfConnected(c, "replay_end", map[string]string{"gameId": gameID})
+}
+
+// sendIfConnected sends to c from outside the hub loop, reporting false once
+// c has gone away. It takes no hub lock, so it is safe under a room lock.
+func (h *Hub) sendIfConnected(c *Client, msgType string, payload interface{}) bool {
+	return h.sendEvent(c, msgType, payload)
+}
@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// reconnectGrace is how long a dropped player's seat is held for them.
+const reconnectGrace = 60 * time.Second
+
+// session lets a player take their seat back from a new connection. While
+// client is nil the seat is being held and expiry will give it up.
+type session struct {
+	token    string
+	client   *Client
+	playerID string
+	member   int
+	expiry   *time.Timer
+}
+
+func newSessionToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// issueSession hands a newly seated client the token it can resume with.
+// The caller must hold room.Mutex.
+func (h *Hub) issueSession(room *Room, client *Client) {
+	s := &session{token: newSessionToken(), client: client}
+	room.sessions[s.token] = s
+	h.sendSession(room, client, s)
+}
+
+func (h *Hub) sendSession(room *Room, client *Client, s *session) {
+	h.sendEvent(client, "session", map[string]interface{}{
+		"roomCode": room.GameState.RoomCode,
+		"token":    s.token,
+		"graceMs":  reconnectGrace.Milliseconds(),
+	})
+}
+
+func (r *Room) sessionOf(client *Client) *session {
+	for _, s := range r.sessions {
+		if s.client == client {
+			return s
+		}
+	}
+	return nil
+}
+
+// dropSession forgets client's session so its token can no longer resume.
+func (r *Room) dropSession(client *Client) {
+	if s := r.sessionOf(client); s != nil {
+		delete(r.sessions, s.token)
+	}
+}
+
+// holdingSeats reports whether any seat is waiting for its player to return.
+func (r *Room) holdingSeats() bool {
+	for _, s := range r.sessions {
+		if s.client == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachSeat visits the seat of every player in the room, connected or
+// held, so seat changes can be applied to both.
+func (r *Room) forEachSeat(fn func(playerID *string, member *int)) {
+	for c := range r.Clients {
+		if c.role == "player" {
+			fn(&c.playerID, &c.member)
+		}
+	}
+	for _, s := range r.sessions {
+		if s.client == nil {
+			fn(&s.playerID, &s.member)
+		}
+	}
+}
+
+// reconcileHolds marks the sides with a missing player and pauses the game
+// while any seat is held.
+func (r *Room) reconcileHolds(now time.Time) {
+	gs := r.GameState
+	for _, p := range gs.Players {
+		p.Disconnected = false
+	}
+	held := false
+	for _, s := range r.sessions {
+		if s.client != nil {
+			continue
+		}
+		held = true
+		if p := gs.Player(game.PlayerID(s.playerID)); p != nil {
+			p.Disconnected = true
+		}
+	}
+	if held {
+		gs.Pause(now)
+	} else {
+		gs.Unpause(now)
+	}
+}
+
+// holdSeat keeps a dropped player's seat for reconnectGrace. It reports false
+// if the client has no session to resume. The caller must hold room.Mutex.
+func (h *Hub) holdSeat(room *Room, client *Client) bool {
+	if room.Bot != nil {
+		return false
+	}
+	s := room.sessionOf(client)
+	if s == nil {
+		return false
+	}
+	s.client = nil
+	s.playerID = client.playerID
+	s.member = client.member
+	s.expiry = time.AfterFunc(reconnectGrace, func() {
+		h.expireSession(room.GameState.RoomCode, s.token)
+	})
+
+	h.broadcastNotification(room, fmt.Sprintf("%s lost connection. Holding their seat for %d seconds.",
+		memberName(room, client), int(reconnectGrace.Seconds())))
+	return true
+}
+
+// expireSession gives up a held seat whose player did not come back in time.
+func (h *Hub) expireSession(roomCode, token string) {
+	room := h.lockRoom(roomCode)
+	if room == nil {
+		return
+	}
+	defer room.Mutex.Unlock()
+
+	s, ok := room.sessions[token]
+	if !ok || s.client != nil {
+		return
+	}
+	delete(room.sessions, token)
+	h.releaseSeat(room, s.playerID, s.member, game.EndDisconnect)
+}
+
+// handleResume puts a reconnecting player back in their seat. A connection
+// still bound to the session is replaced by the new one.
+func (h *Hub) handleResume(action *RoomAction) {
+	room := h.lockRoom(action.Code)
+	if room == nil {
+		h.sendEvent(action.Client, "session_expired", action.Code)
+		return
+	}
+	defer room.Mutex.Unlock()
+
+	s, ok := room.sessions[action.Token]
+	if !ok {
+		h.sendEvent(action.Client, "session_expired", action.Code)
+		return
+	}
+
+	if old := s.client; old != nil && old != action.Client {
+		s.playerID = old.playerID
+		s.member = old.member
+		delete(room.Clients, old)
+		old.roomCode = ""
+		old.playerID = ""
+		old.role = ""
+		old.trySend([]byte(`{"type":"redirect","payload":"/"}`))
+	}
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	s.client = action.Client
+	action.Client.roomCode = action.Code
+	action.Client.playerID = s.playerID
+	action.Client.member = s.member
+	action.Client.role = "player"
+	room.Clients[action.Client] = true
+	room.LastActivityAt = time.Now()
+
+	h.sendSession(room, action.Client, s)
+	h.broadcastNotification(room, fmt.Sprintf("%s is back.", memberName(room, action.Client)))
+	h.syncRoom(room)
+	h.sendChatHistory(room, action.Client)
+}
@@ -0,0 +1,15 @@
+package websocket
+
+import (
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/stats"
+)
+
+// recordResult adds a finished game to the local statistics. Bot games are
+// left out so the bot never appears on the leaderboard.
+func (h *Hub) recordResult(room *Room, gs *game.GameState) {
+	if h.stats == nil || room.Bot != nil {
+		return
+	}
+	h.stats.Record(stats.NewResult(gs, h.userIDOf))
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+)
+
+// sendTeamChat relays a message to the sender's teammates only. The caller
+// must hold room.Mutex.
+func (h *Hub) sendTeamChat(room *Room, from *Client, text string) {
+	if !room.GameState.IsTeamGame() || from.role == "spectator" {
+		return
+	}
+	text, ok := h.checkChat(room, from, text)
+	if !ok {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"from":      memberName(room, from),
+		"text":      text,
+		"timestamp": time.Now().UnixMilli(),
+	}
+	for c := range room.Clients {
+		if c.role != "spectator" && c.playerID == from.playerID {
+			h.sendEvent(c, "team_chat", payload)
+		}
+	}
+}
+
+// memberName is the name a seated client plays under.
+func memberName(room *Room, c *Client) string {
+	return seatName(room, c.playerID, c.member)
+}
+
+// seatName is the name of whoever sits in a seat: the member's own name in a
+// team, or the seat's name otherwise.
+func seatName(room *Room, playerID string, member int) string {
+	p := room.GameState.Player(game.PlayerID(playerID))
+	if p == nil {
+		return ""
+	}
+	if member < len(p.Members) {
+		return p.Members[member]
+	}
+	return p.Name
+}
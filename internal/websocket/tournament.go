@@ -0,0 +1,239 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adimail/colosseum/internal/game"
+	"github.com/adimail/colosseum/internal/tournament"
+)
+
+type TournamentPayload struct {
+	ID string `json:"id"`
+}
+
+// matchRoom ties a room to the tournament match played in it. done is set
+// once the result has been reported, after which the room is an ordinary one.
+type matchRoom struct {
+	tournamentID string
+	matchID      string
+	players      [2]string
+	done         bool
+}
+
+type tournamentEntry struct {
+	t        *tournament.Tournament
+	watchers map[*Client]bool
+}
+
+// TournamentSummary is a tournament without its bracket.
+type TournamentSummary struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Organizer string            `json:"organizer"`
+	Format    tournament.Format `json:"format"`
+	Players   int               `json:"players"`
+	Status    string            `json:"status"`
+	Champion  string            `json:"champion,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// CreateTournament draws up a tournament and opens rooms for its first
+// matches.
+func (h *Hub) CreateTournament(name, organizer string, format tournament.Format, roster []string, rules game.Rules) (*tournament.Tournament, error) {
+	rules, err := rules.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules: %v", err)
+	}
+	if rules.Players > 2 || rules.TeamSize > 1 {
+		return nil, errors.New("tournament matches are one-on-one")
+	}
+	for i, p := range roster {
+		roster[i] = sanitizeName(p)
+	}
+	t, err := tournament.New(name, organizer, format, roster, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	h.tournamentMu.Lock()
+	h.tournaments[t.ID] = &tournamentEntry{t: t, watchers: make(map[*Client]bool)}
+	h.tournamentMu.Unlock()
+	h.openMatchRooms(t.ID)
+	slog.Info("tournament created", "id", t.ID, "format", format, "players", len(roster))
+
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	return t.Clone(), nil
+}
+
+func (h *Hub) Tournament(id string) (*tournament.Tournament, bool) {
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	e, ok := h.tournaments[id]
+	if !ok {
+		return nil, false
+	}
+	return e.t.Clone(), true
+}
+
+// Tournaments lists every tournament, newest first.
+func (h *Hub) Tournaments() []TournamentSummary {
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	list := make([]TournamentSummary, 0, len(h.tournaments))
+	for _, e := range h.tournaments {
+		list = append(list, TournamentSummary{
+			ID:        e.t.ID,
+			Name:      e.t.Name,
+			Organizer: e.t.Organizer,
+			Format:    e.t.Format,
+			Players:   len(e.t.Roster),
+			Status:    e.t.Status,
+			Champion:  e.t.Champion,
+			CreatedAt: e.t.CreatedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// openMatchRooms creates an empty room for every match of the tournament
+// that is ready to be played. Only the two players drawn for a match can
+// take a seat in it. Watchers are then sent the bracket. It takes h.Mutex,
+// so the caller must hold neither a room lock nor h.tournamentMu.
+func (h *Hub) openMatchRooms(id string) {
+	for {
+		h.tournamentMu.Lock()
+		e, ok := h.tournaments[id]
+		if !ok || len(e.t.Playable()) == 0 {
+			if ok {
+				h.pushTournament(e)
+			}
+			h.tournamentMu.Unlock()
+			return
+		}
+		h.tournamentMu.Unlock()
+
+		// Claiming a code can mean a round trip to the backplane, so it is
+		// done without h.tournamentMu held, and the match looked up again.
+		code := h.generateUniqueRoomCode()
+		h.tournamentMu.Lock()
+		playable := e.t.Playable()
+		if len(playable) == 0 {
+			h.tournamentMu.Unlock()
+			h.releaseRoom(code)
+			continue
+		}
+		m := playable[0]
+		m.RoomCode = code
+		room := h.newRoom(code, e.t.Rules, nil)
+		room.match = &matchRoom{tournamentID: id, matchID: m.ID, players: m.Players}
+		h.tournamentMu.Unlock()
+
+		h.Mutex.Lock()
+		h.Rooms[code] = room
+		room.Mutex.Lock()
+		h.Mutex.Unlock()
+		h.publishRoom(room)
+		room.Mutex.Unlock()
+	}
+}
+
+// reportMatch passes a finished game in a tournament room on to the
+// bracket. A drawn elimination match is left for the players to replay.
+// The caller must hold room.Mutex.
+func (h *Hub) reportMatch(room *Room) {
+	gs := room.GameState
+	if room.match == nil || room.match.done {
+		return
+	}
+	winner := gs.Winner
+	if gs.Series != nil {
+		if !gs.Series.Over() {
+			return
+		}
+		winner = gs.Series.Winner
+	}
+	var name string
+	if p := gs.Player(game.PlayerID(winner)); p != nil && winner != game.Draw {
+		name = p.Name
+	}
+
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	e, ok := h.tournaments[room.match.tournamentID]
+	if !ok {
+		return
+	}
+	err := e.t.Report(room.match.matchID, name)
+	if errors.Is(err, tournament.ErrNeedsWinner) {
+		h.broadcastNotification(room, "Tournament matches need a winner. Play again to settle it.")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to report tournament match", "tournament", e.t.ID, "match", room.match.matchID, "error", err)
+		return
+	}
+	room.match.done = true
+
+	if name != "" {
+		h.broadcastNotification(room, fmt.Sprintf("%s wins the tournament match!", name))
+	}
+	if e.t.Status == tournament.StatusCompleted {
+		h.broadcastNotification(room, fmt.Sprintf("%s is the champion of %s!", e.t.Champion, e.t.Name))
+	}
+	// The bracket goes out once the next matches have their rooms.
+	go h.openMatchRooms(e.t.ID)
+}
+
+// seatedInMatch reports whether name was drawn to play in room's match.
+func (r *Room) seatedInMatch(name string) bool {
+	if r.match == nil || r.match.done {
+		return true
+	}
+	return strings.EqualFold(name, r.match.players[0]) || strings.EqualFold(name, r.match.players[1])
+}
+
+// reserved reports whether the room must stay open, even empty, because a
+// tournament match is still to be played in it.
+func (r *Room) reserved() bool {
+	return r.match != nil && !r.match.done
+}
+
+// watchTournament sends c the live bracket now and whenever it changes.
+func (h *Hub) watchTournament(c *Client, id string) {
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	e, ok := h.tournaments[id]
+	if !ok {
+		h.sendIfConnected(c, "error", "Tournament not found")
+		return
+	}
+	e.watchers[c] = true
+	h.sendIfConnected(c, "tournament", e.t)
+}
+
+func (h *Hub) unwatchTournaments(c *Client) {
+	h.tournamentMu.Lock()
+	defer h.tournamentMu.Unlock()
+	for _, e := range h.tournaments {
+		delete(e.watchers, c)
+	}
+}
+
+// pushTournament sends the bracket to everyone watching it. The caller must
+// hold h.tournamentMu.
+func (h *Hub) pushTournament(e *tournamentEntry) {
+	for c := range e.watchers {
+		if !h.sendIfConnected(c, "tournament", e.t) {
+			delete(e.watchers, c)
+		}
+	}
+}
@@ -14,7 +14,11 @@ cd "$APP_DIR" || {
 
 echo "--> Stopping any existing process for '$APP_NAME'..."
 pkill -f "$APP_DIR/$APP_NAME" || echo "No old process was running."
-sleep 2
+# Give the old process time to save its rooms and close its connections.
+for _ in $(seq 1 10); do
+	pgrep -f "$APP_DIR/$APP_NAME" >/dev/null || break
+	sleep 1
+done
 
 echo "--> Setting execute permissions on new binary..."
 chmod +x "$APP_DIR/$APP_NAME"